@@ -3,28 +3,50 @@ package main
 import (
 	"antigravity-rotator-v2/internal/config"
 	"antigravity-rotator-v2/internal/engine"
+	"antigravity-rotator-v2/internal/fsutil"
+	"antigravity-rotator-v2/internal/google"
+	"antigravity-rotator-v2/internal/importer"
+	"antigravity-rotator-v2/internal/instance"
 	"antigravity-rotator-v2/internal/scanner"
+	"antigravity-rotator-v2/internal/usage"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // App struct
 type App struct {
-	ctx    context.Context
-	cfg    *config.AppConfig
-	engine *engine.RotatorEngine
+	ctx     context.Context
+	engine  *engine.RotatorEngine
+	loadErr error
+
+	// Single-instance coordination: either leader or remote is set.
+	instMu   sync.Mutex
+	leader   *instance.Leader
+	remote   *instance.Client
+	stopPoll chan struct{}
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
-	cfg, _ := config.LoadConfig()
+	cfg, err := config.LoadConfig()
+	if cfg == nil {
+		cfg = config.DefaultConfig()
+	}
+	if err != nil {
+		fmt.Printf("Config load warning: %v\n", err)
+	}
 	return &App{
-		cfg:    cfg,
-		engine: engine.NewRotatorEngine(cfg),
+		engine:  engine.NewRotatorEngine(cfg),
+		loadErr: err,
 	}
 }
 
@@ -33,14 +55,126 @@ func NewApp() *App {
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 	a.engine.SetContext(ctx)
-	// Start auto-rotation on startup if enabled
-	a.engine.StartAutoLoop()
+	if a.loadErr != nil {
+		runtime.EventsEmit(ctx, "config_load_error", a.loadErr.Error())
+	}
+	// Start auto-rotation on startup if enabled, unless another instance
+	// already leads
+	a.acquireInstance()
+	if err := a.engine.StartWatcher(); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// shutdown stops background work before the app exits
+func (a *App) shutdown(ctx context.Context) {
+	a.engine.StopWatcher()
+	a.engine.StopAutoLoop()
+	a.instMu.Lock()
+	defer a.instMu.Unlock()
+	if a.stopPoll != nil {
+		close(a.stopPoll)
+		a.stopPoll = nil
+	}
+	if a.leader != nil {
+		a.leader.Release()
+		a.leader = nil
+	}
+}
+
+// acquireInstance becomes the leader for the OpenClaw home, or attaches to
+// the running leader (a daemon or another GUI) and keeps the local auto loop
+// in standby until that leader goes away.
+func (a *App) acquireInstance() {
+	leader, remote, err := instance.Acquire(config.DefaultOpenClawHome(), "gui")
+	if err != nil {
+		fmt.Println(err)
+		a.engine.StartAutoLoop()
+		return
+	}
+
+	a.instMu.Lock()
+	defer a.instMu.Unlock()
+	a.leader, a.remote = leader, remote
+	if leader != nil {
+		leader.Serve(instance.EngineAPI{RotatorEngine: a.engine})
+		a.engine.SetStandby(false)
+	} else {
+		a.engine.SetStandby(true)
+		if a.stopPoll == nil {
+			a.stopPoll = make(chan struct{})
+			go a.watchLeader(a.stopPoll)
+		}
+	}
+	runtime.EventsEmit(a.ctx, "instance_role", a.instanceRole())
+}
+
+// watchLeader promotes this instance once the leader stops answering.
+func (a *App) watchLeader(stop chan struct{}) {
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		a.instMu.Lock()
+		remote := a.remote
+		a.instMu.Unlock()
+		if remote == nil || remote.Ping() == nil {
+			continue
+		}
+
+		a.acquireInstance()
+		a.instMu.Lock()
+		promoted := a.leader != nil
+		if promoted && a.stopPoll == stop {
+			a.stopPoll = nil
+		}
+		a.instMu.Unlock()
+		if promoted {
+			return
+		}
+	}
+}
+
+// instanceRole describes this instance for the UI, e.g. "leader" or
+// "client of daemon (pid 1234)".
+func (a *App) instanceRole() string {
+	if a.remote != nil {
+		return fmt.Sprintf("client of %s (pid %d)", a.remote.Role, a.remote.PID)
+	}
+	return "leader"
+}
+
+// GetInstanceRole reports whether this window runs the auto loop itself or
+// defers to another instance.
+func (a *App) GetInstanceRole() string {
+	a.instMu.Lock()
+	defer a.instMu.Unlock()
+	return a.instanceRole()
+}
+
+// leaderClient returns the running leader when this instance is a client.
+func (a *App) leaderClient() *instance.Client {
+	a.instMu.Lock()
+	defer a.instMu.Unlock()
+	return a.remote
+}
+
+// notifyLeader asks the leader to pick up a config change made here.
+func (a *App) notifyLeader() {
+	if remote := a.leaderClient(); remote != nil {
+		if err := remote.ReloadConfig(); err != nil {
+			fmt.Println(err)
+		}
+	}
 }
 
 // GetWorkspaces returns all detected OpenClaw workspaces
 func (a *App) GetWorkspaces() []scanner.WorkspaceInfo {
-	ws, _ := scanner.ScanWorkspaces()
-	return ws
+	return a.engine.GetWorkspaces()
 }
 
 // GetAgents returns the list of configured agents and their models
@@ -50,55 +184,191 @@ func (a *App) GetAgents() []engine.AgentInfo {
 
 // GetConfig returns the current application configuration
 func (a *App) GetConfig() config.AppConfig {
-	return *a.cfg
+	return *a.engine.Config()
 }
 
-// SaveConfig saves the configuration
-func (a *App) SaveConfig(cfg config.AppConfig) string {
-	a.cfg = &cfg
-	err := config.SaveConfig(a.cfg)
-	if err != nil {
-		return fmt.Sprintf("Error: %v", err)
+// GetConfigLoadError reports why the config file could not be loaded cleanly
+// at startup (corrupt file backed up, validation failures, or settings a
+// migration changed), if at all.
+func (a *App) GetConfigLoadError() string {
+	if a.loadErr == nil {
+		return ""
 	}
-	// Restart loop if config changed
-	a.engine.StartAutoLoop()
-	return "Success"
+	return a.loadErr.Error()
+}
+
+// SaveConfig validates, saves and applies the configuration. It returns the
+// field-level errors, or an empty list on success.
+func (a *App) SaveConfig(cfg config.AppConfig) []config.FieldError {
+	return fieldErrors(a.applyConfig(&cfg))
+}
+
+// fieldErrors flattens err into field errors for the frontend.
+func fieldErrors(err error) []config.FieldError {
+	if err == nil {
+		return []config.FieldError{}
+	}
+	var verrs config.ValidationErrors
+	if errors.As(err, &verrs) {
+		return verrs
+	}
+	return []config.FieldError{{Message: err.Error()}}
+}
+
+// applyConfig persists cfg and hands it to the engine.
+func (a *App) applyConfig(cfg *config.AppConfig) error {
+	if err := config.SaveConfig(cfg); err != nil {
+		return err
+	}
+	_, err := a.engine.ApplyConfig(cfg)
+	a.notifyLeader()
+	return err
+}
+
+// updateConfig edits the config through the engine and tells the leader.
+func (a *App) updateConfig(fn func(cfg *config.AppConfig)) error {
+	err := a.engine.UpdateConfig(fn)
+	a.notifyLeader()
+	return err
 }
 
 // RunRotation triggers a rotation cycle manually
 func (a *App) RunRotation() string {
+	if remote := a.leaderClient(); remote != nil {
+		msg, err := remote.RunCycle()
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		return msg
+	}
 	return a.engine.RunCycle()
 }
 
 // GetAccountStatus returns the current quota status from the engine
 func (a *App) GetAccountStatus() map[string]int {
+	if remote := a.leaderClient(); remote != nil {
+		if status, err := remote.Status(true); err == nil {
+			return status
+		}
+	}
 	// 触发配额刷新
 	_ = a.engine.RefreshStatus()
-	return a.engine.Status
+	return a.engine.StatusSnapshot()
 }
 
-// ImportAccounts imports a batch of accounts from a JSON string
-func (a *App) ImportAccounts(jsonStr string) string {
-	var input []struct {
-		Email        string `json:"email"`
-		RefreshToken string `json:"refresh_token"`
+// GetActivePolicy returns the rotation settings in effect now and the
+// schedule that set them, if any
+func (a *App) GetActivePolicy() config.Policy {
+	return a.engine.CurrentPolicy()
+}
+
+// GetPollSchedule returns the polling tier and next poll time per account
+func (a *App) GetPollSchedule() []engine.AccountPoll {
+	if remote := a.leaderClient(); remote != nil {
+		if polls, err := remote.PollSchedule(); err == nil {
+			return polls
+		}
 	}
-	err := json.Unmarshal([]byte(jsonStr), &input)
-	if err != nil {
-		return fmt.Sprintf("Error: 解析 JSON 失败: %v", err)
+	return a.engine.PollSchedule()
+}
+
+// GetModelCatalogs returns the last model catalog per account: every
+// upstream model with its limits and quota, and which one limits each
+// display model
+func (a *App) GetModelCatalogs() map[string]*google.Catalog {
+	if remote := a.leaderClient(); remote != nil {
+		if cats, err := remote.ModelCatalogs(); err == nil {
+			return cats
+		}
 	}
+	return a.engine.ModelCatalogs()
+}
+
+// GetAccountTiers returns the Code Assist tier, onboarding state and
+// ineligibility reasons per account
+func (a *App) GetAccountTiers() []engine.AccountTier {
+	if remote := a.leaderClient(); remote != nil {
+		if tiers, err := remote.AccountTiers(); err == nil {
+			return tiers
+		}
+	}
+	return a.engine.AccountTiers()
+}
 
-	err = a.engine.ImportAccounts(input)
+// ImportAccounts imports accounts from pasted text in any supported format
+// (JSON list, auth-profiles.json, credential file, CSV or email:token
+// lines), validating the tokens first
+func (a *App) ImportAccounts(text string) (engine.ImportReport, error) {
+	entries, problems, err := importer.Parse("pasted", []byte(text))
 	if err != nil {
-		return fmt.Sprintf("Error: %v", err)
+		return engine.ImportReport{}, err
 	}
+	return a.importCredentials(entries, problems)
+}
 
-	return "Success"
+// ImportFromFiles lets the user pick credential files or zips and imports
+// them, returning the per-row report
+func (a *App) ImportFromFiles() (engine.ImportReport, error) {
+	selection, err := runtime.OpenMultipleFilesDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "Select Account Files",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "Credential Files", Pattern: "*.json;*.csv;*.txt;*.zip"},
+			{DisplayName: "All Files", Pattern: "*"},
+		},
+	})
+	if err != nil || len(selection) == 0 {
+		return engine.ImportReport{Rows: []engine.ImportRow{}}, err
+	}
+
+	var entries []importer.Entry
+	var problems []importer.Problem
+	for _, path := range selection {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			problems = append(problems, importer.Problem{Source: path, Reason: err.Error()})
+			continue
+		}
+		e, p, err := importer.Parse(filepath.Base(path), data)
+		if err != nil {
+			problems = append(problems, importer.Problem{Source: path, Reason: err.Error()})
+			continue
+		}
+		entries = append(entries, e...)
+		problems = append(problems, p...)
+	}
+	return a.importCredentials(entries, problems)
+}
+
+// LoginAccount adds an account by signing in with Google in the browser.
+// The consent URL is also sent as a login_url event in case the browser
+// does not open
+func (a *App) LoginAccount() (engine.ImportReport, error) {
+	report, err := a.engine.LoginAccount(a.ctx, func(url string) {
+		runtime.EventsEmit(a.ctx, "login_url", url)
+		runtime.BrowserOpenURL(a.ctx, url)
+	})
+	if err == nil && report.Added+report.Updated > 0 {
+		a.notifyLeader()
+	}
+	return report, err
+}
+
+func (a *App) importCredentials(entries []importer.Entry, problems []importer.Problem) (engine.ImportReport, error) {
+	report, err := a.engine.ImportCredentials(entries, problems, engine.ImportOptions{Validate: true})
+	if err == nil && report.Added+report.Updated > 0 {
+		a.notifyLeader()
+	}
+	return report, err
 }
 
 // SwitchModel manually switches the active model
 func (a *App) SwitchModel(modelID string) string {
-	err := a.engine.SwitchModel(modelID)
+	var err error
+	if remote := a.leaderClient(); remote != nil {
+		err = remote.SwitchModel(modelID)
+	} else {
+		err = a.engine.SwitchModel(modelID)
+	}
 	if err != nil {
 		return fmt.Sprintf("Error: %v", err)
 	}
@@ -107,17 +377,179 @@ func (a *App) SwitchModel(modelID string) string {
 
 // SwitchAccount manually switches the active account
 func (a *App) SwitchAccount(email string) string {
-	err := a.engine.SwitchAccount(email)
+	var err error
+	if remote := a.leaderClient(); remote != nil {
+		err = remote.SwitchAccount(email)
+	} else {
+		err = a.engine.SwitchAccount(email)
+	}
 	if err != nil {
 		return fmt.Sprintf("Error: %v", err)
 	}
 	return "Success"
 }
 
+// PreviewModelSwitch shows which agents switching to modelID would touch
+func (a *App) PreviewModelSwitch(modelID string) []engine.AgentModelChange {
+	changes, err := a.engine.PreviewModelSwitch(modelID)
+	if err != nil {
+		fmt.Println(err)
+		return []engine.AgentModelChange{}
+	}
+	return changes
+}
+
 func (a *App) GetVipEmail() string {
 	return a.engine.GetVipEmail()
 }
 
+// TestProxy checks reachability and latency of the Google endpoints through
+// the given proxy settings, so they can be verified before saving.
+func (a *App) TestProxy(proxy config.ProxyConfig) []google.ProbeResult {
+	client := &google.Client{Proxy: proxy}
+	return client.ProbeEndpoints()
+}
+
+// GetConsistencyReport returns the accounts × workspaces credential matrix
+func (a *App) GetConsistencyReport() scanner.ConsistencyReport {
+	return scanner.BuildConsistencyReport(a.engine.GetWorkspaces())
+}
+
+// FixDrift re-syncs one workspace (or all, for an empty path) from the
+// primary credentials
+func (a *App) FixDrift(path string) []engine.WorkspaceSyncPlan {
+	return syncPlans(a.engine.FixDrift(path))
+}
+
+// PreviewSync shows, per workspace, which profiles a credential sync would
+// add, update or keep
+func (a *App) PreviewSync() []engine.WorkspaceSyncPlan {
+	return syncPlans(a.engine.PreviewSync())
+}
+
+// SyncWorkspaces merges the primary credentials into all agent workspaces
+func (a *App) SyncWorkspaces() []engine.WorkspaceSyncPlan {
+	return syncPlans(a.engine.SyncAuth())
+}
+
+func syncPlans(plans []engine.WorkspaceSyncPlan, err error) []engine.WorkspaceSyncPlan {
+	if err != nil {
+		return []engine.WorkspaceSyncPlan{{Error: err.Error()}}
+	}
+	return plans
+}
+
+// ListBackups returns the timestamped backups of the auth and config files
+func (a *App) ListBackups() []fsutil.BackupInfo {
+	return a.engine.ListBackups()
+}
+
+// RestoreBackup restores a file from one of its backups
+func (a *App) RestoreBackup(backupPath string) string {
+	if err := a.engine.RestoreBackup(backupPath); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	return "Success"
+}
+
+// ExportBundle asks where to save a backup of the config, credentials,
+// account states and rotation history, encrypted when passphrase is set.
+// It returns the saved path, or "" when cancelled
+func (a *App) ExportBundle(passphrase string) (string, error) {
+	path, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		Title:           "Export Backup",
+		DefaultFilename: "antigravity-rotator-" + time.Now().Format("20060102") + ".json",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "Backup Files", Pattern: "*.json"},
+		},
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+	data, err := a.engine.ExportBundle(passphrase)
+	if err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// SelectBundle opens a file dialog to pick a backup and returns its path
+func (a *App) SelectBundle() (string, error) {
+	return runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "Select Backup",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "Backup Files", Pattern: "*.json"},
+		},
+	})
+}
+
+// PreviewRestore reports what restoring the backup at path would change
+func (a *App) PreviewRestore(path, passphrase string) (engine.RestorePlan, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return engine.RestorePlan{}, err
+	}
+	return a.engine.PreviewRestore(data, passphrase)
+}
+
+// RestoreBundle restores the backup at path
+func (a *App) RestoreBundle(path, passphrase string) (engine.RestorePlan, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return engine.RestorePlan{}, err
+	}
+	plan, err := a.engine.RestoreBundle(data, passphrase)
+	if err == nil {
+		a.notifyLeader()
+	}
+	return plan, err
+}
+
+// GetUsageReport reads new usage from the session transcripts and gateway
+// log, then reports it by account, agent or model per day or week
+func (a *App) GetUsageReport(by, period string) (usage.Report, error) {
+	if _, err := a.engine.IngestUsage(nil); err != nil {
+		return usage.Report{}, err
+	}
+	return a.engine.UsageReport(engine.UsageQuery{By: by, Period: period})
+}
+
+// ExportUsage asks where to save a usage report as CSV or JSON
+func (a *App) ExportUsage(by, period, format string) (string, error) {
+	report, err := a.engine.UsageReport(engine.UsageQuery{By: by, Period: period})
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if format == "csv" {
+		err = usage.WriteCSV(&buf, report)
+	} else {
+		format = "json"
+		err = json.NewEncoder(&buf).Encode(report)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	path, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		Title:           "Export Usage",
+		DefaultFilename: fmt.Sprintf("usage-%s-%s-%s.%s", by, period, time.Now().Format("20060102"), format),
+		Filters: []runtime.FileFilter{
+			{DisplayName: "Usage Reports", Pattern: "*." + format},
+		},
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
 // SelectFile opens a file dialog to select a JSON file and returns its content
 func (a *App) SelectFile() (string, error) {
 	selection, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
@@ -142,15 +574,15 @@ func (a *App) SelectFile() (string, error) {
 
 // StartAutoRotation enables and starts the auto-rotation loop
 func (a *App) StartAutoRotation(interval int) {
-	a.cfg.Rotator.AutoRotate = true
-	a.cfg.Rotator.RotateInterval = interval
-	a.engine.StartAutoLoop()
-	config.SaveConfig(a.cfg)
+	a.updateConfig(func(cfg *config.AppConfig) {
+		cfg.Rotator.AutoRotate = true
+		cfg.Rotator.RotateInterval = interval
+	})
 }
 
 // StopAutoRotation disables the auto-rotation loop
 func (a *App) StopAutoRotation() {
-	a.cfg.Rotator.AutoRotate = false
-	a.engine.StopAutoLoop()
-	config.SaveConfig(a.cfg)
+	a.updateConfig(func(cfg *config.AppConfig) {
+		cfg.Rotator.AutoRotate = false
+	})
 }
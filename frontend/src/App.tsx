@@ -1,7 +1,7 @@
 import {useState, useEffect, useRef} from 'react';
 import './index.css';
-import {GetWorkspaces, GetConfig, SaveConfig, RunRotation, GetAccountStatus, ImportAccounts, SwitchModel, SwitchAccount, GetVipEmail, GetAgents, SelectFile, StartAutoRotation, StopAutoRotation} from "../wailsjs/go/main/App";
-import {scanner, config, engine} from "../wailsjs/go/models";
+import {GetWorkspaces, GetActivePolicy, GetConfig, GetConfigLoadError, GetInstanceRole, SaveConfig, RunRotation, GetAccountStatus, ImportFromFiles, LoginAccount, SwitchModel, SwitchAccount, GetVipEmail, GetAgents, GetPollSchedule, GetModelCatalogs, GetAccountTiers, PreviewModelSwitch, ExportBundle, SelectBundle, PreviewRestore, RestoreBundle, GetUsageReport, ExportUsage, TestProxy, StartAutoRotation, StopAutoRotation} from "../wailsjs/go/main/App";
+import {scanner, config, engine, google, usage} from "../wailsjs/go/models";
 
 function App() {
     const [workspaces, setWorkspaces] = useState<scanner.WorkspaceInfo[]>([]);
@@ -16,6 +16,23 @@ function App() {
     const [currentTime, setCurrentTime] = useState(new Date());
     const [activeTab, setActiveTab] = useState("overview");
     const [theme, setTheme] = useState<'dark' | 'light'>('dark');
+    const [instanceRole, setInstanceRole] = useState("leader");
+    const [policy, setPolicy] = useState<config.Policy | null>(null);
+    const [importReport, setImportReport] = useState<engine.ImportReport | null>(null);
+    const [showBackup, setShowBackup] = useState(false);
+    const [passphrase, setPassphrase] = useState("");
+    const [restorePath, setRestorePath] = useState("");
+    const [restorePlan, setRestorePlan] = useState<engine.RestorePlan | null>(null);
+    const [showUsage, setShowUsage] = useState(false);
+    const [usageBy, setUsageBy] = useState("account");
+    const [usagePeriod, setUsagePeriod] = useState("day");
+    const [usageReport, setUsageReport] = useState<usage.Report | null>(null);
+    const [proxyDraft, setProxyDraft] = useState<config.ProxyConfig | null>(null);
+    const [proxyResults, setProxyResults] = useState<google.ProbeResult[] | null>(null);
+    const [proxyTesting, setProxyTesting] = useState(false);
+    const [tiers, setTiers] = useState<engine.AccountTier[]>([]);
+    const [catalogs, setCatalogs] = useState<{[email: string]: google.Catalog}>({});
+    const [pollSchedule, setPollSchedule] = useState<engine.AccountPoll[]>([]);
     const menuRef = useRef<HTMLDivElement>(null);
 
     // Theme definitions
@@ -50,6 +67,8 @@ function App() {
             if (stats && Object.keys(stats).length > 0) {
                 setAccountStatus(stats);
             }
+            setCatalogs(await GetModelCatalogs() || {});
+            setTiers(await GetAccountTiers() || []);
             await fetchData();
             setStatus("同步完成");
         } catch (e) {
@@ -58,9 +77,23 @@ function App() {
         setRefreshing(false);
     };
 
+    const saveConfig = async (newCfg: config.AppConfig): Promise<boolean> => {
+        const errs = await SaveConfig(newCfg);
+        if (errs && errs.length > 0) {
+            setStatus("配置无效: " + errs.map(e => e.field ? `${e.field} ${e.message}` : e.message).join("; "));
+            setCfg(await GetConfig());
+            return false;
+        }
+        return true;
+    };
+
     useEffect(() => {
         fetchData();
         refreshData();
+        GetConfigLoadError().then(err => { if (err) setStatus(err); });
+        GetInstanceRole().then(setInstanceRole);
+        GetPollSchedule().then(p => setPollSchedule(p || []));
+        GetActivePolicy().then(setPolicy);
         
         // 监听后端推送的实时状态
         const unsub = (window as any).runtime.EventsOn("status_updated", (stats: any) => {
@@ -68,6 +101,50 @@ function App() {
             if (stats) setAccountStatus(stats);
         });
 
+        // 工作区目录变化时刷新列表
+        const unsubWs = (window as any).runtime.EventsOn("workspaces_changed", (ws: scanner.WorkspaceInfo[]) => {
+            setWorkspaces(ws || []);
+        });
+
+        // 配置热更新后同步最新配置
+        const unsubCfg = (window as any).runtime.EventsOn("config_applied", async () => {
+            setCfg(await GetConfig());
+            setPolicy(await GetActivePolicy());
+        });
+
+        // 生效的轮换计划变化
+        const unsubSched = (window as any).runtime.EventsOn("schedule_changed", (p: config.Policy) => {
+            setPolicy(p);
+        });
+
+        // 分级轮询计划更新
+        const unsubPoll = (window as any).runtime.EventsOn("poll_schedule", (p: engine.AccountPoll[]) => {
+            setPollSchedule(p || []);
+        });
+
+        // 网关报错等事件触发的轮换
+        const unsubTrig = (window as any).runtime.EventsOn("rotation_completed", (ev: {reasons: string[], result: string}) => {
+            setStatus(`事件触发轮换: ${ev.result}`);
+            refreshData();
+        });
+        const unsubTrigErr = (window as any).runtime.EventsOn("trigger_error", (msg: string) => {
+            setStatus(msg);
+        });
+
+        // 其他实例接管/释放自动轮换时更新角色
+        const unsubRole = (window as any).runtime.EventsOn("instance_role", (role: string) => {
+            setInstanceRole(role);
+        });
+
+        const unsubOnboard = (window as any).runtime.EventsOn("account_onboarded", (ev: {email: string, projectId: string}) => {
+            setStatus(`账号 ${ev.email} 已自动开通，项目 ${ev.projectId}`);
+        });
+
+        // 浏览器未自动打开时可手动复制登录地址
+        const unsubLogin = (window as any).runtime.EventsOn("login_url", (url: string) => {
+            setStatus(`请在浏览器中完成 Google 登录: ${url}`);
+        });
+
         const timer = setInterval(() => setCurrentTime(new Date()), 1000);
 
         const handleClickOutside = (event: MouseEvent) => {
@@ -78,6 +155,15 @@ function App() {
         document.addEventListener("mousedown", handleClickOutside);
         return () => {
             unsub();
+            unsubCfg();
+            unsubWs();
+            unsubRole();
+            unsubLogin();
+            unsubOnboard();
+            unsubTrig();
+            unsubTrigErr();
+            unsubPoll();
+            unsubSched();
             clearInterval(timer);
             document.removeEventListener("mousedown", handleClickOutside);
         };
@@ -97,22 +183,112 @@ function App() {
 
     const handleImport = async () => {
         try {
-            const jsonStr = await SelectFile();
-            if (!jsonStr) return; // Cancelled
-
-            setStatus("正在导入凭据...");
-            const result = await ImportAccounts(jsonStr);
-            if (result === "Success") {
-                setStatus("导入成功");
+            setStatus("正在导入并校验凭据...");
+            const report = await ImportFromFiles();
+            if (!report.rows || report.rows.length === 0) {
+                setStatus("系统就绪");
+                return; // Cancelled
+            }
+            setImportReport(report);
+            setStatus(`导入完成: 新增 ${report.added}, 更新 ${report.updated}, 跳过 ${report.skipped}, 重复 ${report.duplicates}`);
+            if (report.added + report.updated > 0) {
                 await refreshData();
-            } else {
-                setStatus(result);
             }
         } catch (e) {
             setStatus("导入失败: " + e);
         }
     };
 
+    const handleLogin = async () => {
+        try {
+            setStatus("正在打开 Google 登录...");
+            const report = await LoginAccount();
+            setImportReport(report);
+            setStatus(report.added + report.updated > 0 ? "账号已通过 Google 登录添加" : "登录完成，未添加新账号");
+            if (report.added + report.updated > 0) {
+                await refreshData();
+            }
+        } catch (e) {
+            setStatus("Google 登录失败: " + e);
+        }
+    };
+
+    const handleExport = async () => {
+        try {
+            const path = await ExportBundle(passphrase);
+            if (path) setStatus(`备份已保存: ${path}${passphrase ? " (已加密)" : ""}`);
+        } catch (e) {
+            setStatus("备份失败: " + e);
+        }
+    };
+
+    const handlePreviewRestore = async () => {
+        try {
+            const path = restorePath || await SelectBundle();
+            if (!path) return;
+            setRestorePath(path);
+            setRestorePlan(await PreviewRestore(path, passphrase));
+        } catch (e) {
+            setRestorePlan(null);
+            setStatus("读取备份失败: " + e);
+        }
+    };
+
+    const handleRestore = async () => {
+        try {
+            await RestoreBundle(restorePath, passphrase);
+            setStatus("备份已恢复");
+            setRestorePath("");
+            setRestorePlan(null);
+            setShowBackup(false);
+            await refreshData();
+        } catch (e) {
+            setStatus("恢复失败: " + e);
+        }
+    };
+
+    const loadUsage = async (by: string, period: string) => {
+        setUsageBy(by);
+        setUsagePeriod(period);
+        try {
+            setUsageReport(await GetUsageReport(by, period));
+        } catch (e) {
+            setUsageReport(null);
+            setStatus("读取用量失败: " + e);
+        }
+    };
+
+    const handleExportUsage = async (format: string) => {
+        try {
+            const path = await ExportUsage(usageBy, usagePeriod, format);
+            if (path) setStatus(`用量报告已保存: ${path}`);
+        } catch (e) {
+            setStatus("导出用量失败: " + e);
+        }
+    };
+
+    const toggleProxy = () => {
+        setProxyResults(null);
+        const saved = cfg?.rotator.proxy;
+        setProxyDraft(proxyDraft ? null : {...saved, mode: saved?.mode || "system", url: saved?.url || ""});
+    };
+
+    const handleTestProxy = async () => {
+        if (!proxyDraft) return;
+        setProxyTesting(true);
+        setProxyResults(await TestProxy(proxyDraft) || []);
+        setProxyTesting(false);
+    };
+
+    const handleSaveProxy = async () => {
+        if (!cfg || !proxyDraft) return;
+        const newCfg = {...cfg, rotator: {...cfg.rotator, proxy: proxyDraft}};
+        setCfg(newCfg as any);
+        if (await saveConfig(newCfg as any)) {
+            setStatus("代理设置已保存");
+        }
+    };
+
     const handleSwitchAccount = async (email: string) => {
         if (email === vipEmail) return;
         setStatus(`正在调度节点: ${email}...`);
@@ -127,9 +303,13 @@ function App() {
 
     const handleSwitchModel = async (modelID: string) => {
         setStatus(`正在配置核心模型: ${modelID}...`);
+        const plan = await PreviewModelSwitch(modelID);
+        const touched = (plan || []).filter(c => c.action === "patch");
         const result = await SwitchModel(modelID);
         if (result === "Success") {
-            setStatus("模型重配置成功");
+            setStatus(touched.length > 0
+                ? `模型重配置成功，已调整智能体: ${touched.map(c => `${c.name || c.agentId} → ${c.to}`).join(", ")}`
+                : "模型重配置成功");
             setShowModelMenu(false);
             await refreshData();
         } else {
@@ -153,8 +333,9 @@ function App() {
         };
         
         setCfg(newCfg as any);
-        setStatus(`已提升优先级: ${modelId.split('/').pop()}`);
-        await SaveConfig(newCfg as any);
+        if (await saveConfig(newCfg as any)) {
+            setStatus(`已提升优先级: ${modelId.split('/').pop()}`);
+        }
     };
 
     const updateThreshold = async (value: number) => {
@@ -162,8 +343,19 @@ function App() {
         const newCfg = {...cfg};
         newCfg.rotator.quotas.low = value;
         setCfg(newCfg as any);
-        await SaveConfig(newCfg as any);
-        setStatus("阈值已更新");
+        if (await saveConfig(newCfg as any)) {
+            setStatus("阈值已更新");
+        }
+    };
+
+    const updateBalance = async (mode: string) => {
+        if (!cfg) return;
+        const newCfg = {...cfg};
+        newCfg.rotator.balance = {...(newCfg.rotator.balance || {}), mode};
+        setCfg(newCfg as any);
+        if (await saveConfig(newCfg as any)) {
+            setStatus(mode ? `负载均衡: ${mode === 'slice' ? '按份额轮换' : '按剩余配额加权'}` : "负载均衡已关闭");
+        }
     };
 
     const toggleAutoRotate = async () => {
@@ -235,6 +427,12 @@ function App() {
                             <div className="flex items-center gap-4 mt-1 text-xs font-bold text-slate-500 uppercase tracking-wider">
                                 <span className="flex items-center gap-1.5"><span className="w-1.5 h-1.5 bg-emerald-500 rounded-full" /> 网络状态稳定</span>
                                 <span className="flex items-center gap-1.5"><span className="w-1.5 h-1.5 bg-blue-500 rounded-full glow-pulse" /> {status}</span>
+                                {policy?.schedule && (
+                                    <span className="flex items-center gap-1.5 normal-case" title={`阈值 ${policy.low}% · ${policy.autoRotate ? "自动轮换" : "轮换已暂停"} · ${(policy.modelPriority || []).join(" > ")}`}><span className={`w-1.5 h-1.5 rounded-full ${policy.autoRotate ? 'bg-violet-500' : 'bg-rose-500'}`} /> 计划: {policy.schedule}</span>
+                                )}
+                                {instanceRole !== "leader" && (
+                                    <span className="flex items-center gap-1.5 normal-case" title="自动轮换由另一个实例执行"><span className="w-1.5 h-1.5 bg-amber-500 rounded-full" /> {instanceRole}</span>
+                                )}
                             </div>
                         </div>
                     </div>
@@ -266,7 +464,19 @@ function App() {
                              <svg xmlns="http://www.w3.org/2000/svg" className="h-4 w-4" fill="none" viewBox="0 0 24 24" stroke="currentColor">
                                 <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M4 16v1a3 3 0 003 3h10a3 3 0 003-3v-1m-4-8l-4-4m0 0L8 8m4-4v12" />
                               </svg>
-                            导入 JSON
+                            导入账号
+                        </button>
+                        <button onClick={handleLogin} className="btn-modern bg-white/5 hover:bg-white/10 text-blue-400 border border-blue-500/20 px-4 py-2 rounded-lg text-sm font-bold">
+                            Google 登录
+                        </button>
+                        <button onClick={() => setShowBackup(!showBackup)} className="btn-modern bg-white/5 hover:bg-white/10 text-slate-400 border border-white/10 px-4 py-2 rounded-lg text-sm font-bold">
+                            备份/恢复
+                        </button>
+                        <button onClick={() => { if (!showUsage) loadUsage(usageBy, usagePeriod); setShowUsage(!showUsage); }} className="btn-modern bg-white/5 hover:bg-white/10 text-slate-400 border border-white/10 px-4 py-2 rounded-lg text-sm font-bold">
+                            用量统计
+                        </button>
+                        <button onClick={toggleProxy} className="btn-modern bg-white/5 hover:bg-white/10 text-slate-400 border border-white/10 px-4 py-2 rounded-lg text-sm font-bold">
+                            网络代理
                         </button>
                         <button onClick={handleRotate} className="btn-modern bg-blue-600 hover:bg-blue-500 text-white px-6 py-2 rounded-lg text-sm font-bold shadow-[0_0_20px_rgba(37,99,235,0.3)]">
                             强制轮换
@@ -281,6 +491,150 @@ function App() {
             </header>
 
             <main className="p-8 max-w-[1600px] mx-auto min-h-[calc(100vh-120px)]">
+                {showBackup && (
+                    <div className={`mb-8 p-6 rounded-2xl border ${t.card}`}>
+                        <div className="flex justify-between items-center mb-4">
+                            <h2 className="text-base font-bold uppercase tracking-widest text-blue-500">备份与恢复</h2>
+                            <button onClick={() => { setShowBackup(false); setRestorePath(""); setRestorePlan(null); }} className="text-xs font-bold text-slate-500 hover:text-blue-500 uppercase">关闭</button>
+                        </div>
+                        <div className="flex gap-3 items-center mb-4">
+                            <input type="password" value={passphrase} onChange={e => setPassphrase(e.target.value)} placeholder="密码 (可选，用于加密/解密)" className={`flex-1 px-3 py-2 rounded-lg border text-sm ${t.input}`} />
+                            <button onClick={handleExport} className="btn-modern bg-blue-600 hover:bg-blue-500 text-white px-4 py-2 rounded-lg text-sm font-bold">导出备份</button>
+                            <button onClick={handlePreviewRestore} className="btn-modern bg-white/5 hover:bg-white/10 text-emerald-400 border border-emerald-500/20 px-4 py-2 rounded-lg text-sm font-bold">{restorePath ? "重新预览" : "选择备份..."}</button>
+                        </div>
+                        {restorePlan && (
+                            <div className="text-xs font-mono space-y-1.5">
+                                <div className="text-slate-500">{restorePath} · v{restorePlan.version} · {new Date(restorePlan.createdAt).toLocaleString()}{restorePlan.encrypted ? " · 已加密" : ""}</div>
+                                <div>新增账号: <span className="text-emerald-500">{restorePlan.accountsAdded.join(", ") || "--"}</span></div>
+                                <div>移除账号: <span className="text-rose-500">{restorePlan.accountsRemoved.join(", ") || "--"}</span></div>
+                                <div>新增凭据: <span className="text-emerald-500">{restorePlan.profilesAdded.join(", ") || "--"}</span></div>
+                                <div>更新凭据: <span className="text-blue-500">{restorePlan.profilesUpdated.join(", ") || "--"}</span> (未变化 {restorePlan.profilesUnchanged})</div>
+                                <div>配置变更: <span className="text-amber-500">{restorePlan.configChanges.join(", ") || "--"}</span></div>
+                                <div>轮换历史: {restorePlan.historyEntries} 条 (将替换本地历史)</div>
+                                <button onClick={handleRestore} className="btn-modern mt-3 bg-rose-600 hover:bg-rose-500 text-white px-4 py-2 rounded-lg text-sm font-bold">确认恢复</button>
+                            </div>
+                        )}
+                    </div>
+                )}
+
+                {showUsage && (
+                    <div className={`mb-8 p-6 rounded-2xl border ${t.card}`}>
+                        <div className="flex justify-between items-center mb-4">
+                            <h2 className="text-base font-bold uppercase tracking-widest text-blue-500">用量统计</h2>
+                            <button onClick={() => setShowUsage(false)} className="text-xs font-bold text-slate-500 hover:text-blue-500 uppercase">关闭</button>
+                        </div>
+                        <div className="flex gap-3 items-center mb-4">
+                            <select value={usageBy} onChange={e => loadUsage(e.target.value, usagePeriod)} className={`px-3 py-2 rounded-lg border text-sm ${t.input}`}>
+                                <option value="account">按账号</option>
+                                <option value="agent">按 Agent</option>
+                                <option value="model">按模型</option>
+                            </select>
+                            <select value={usagePeriod} onChange={e => loadUsage(usageBy, e.target.value)} className={`px-3 py-2 rounded-lg border text-sm ${t.input}`}>
+                                <option value="day">按天 (近 7 天)</option>
+                                <option value="week">按周 (近 4 周)</option>
+                            </select>
+                            <div className="flex-1" />
+                            <button onClick={() => handleExportUsage("csv")} className="btn-modern bg-white/5 hover:bg-white/10 text-emerald-400 border border-emerald-500/20 px-4 py-2 rounded-lg text-sm font-bold">导出 CSV</button>
+                            <button onClick={() => handleExportUsage("json")} className="btn-modern bg-white/5 hover:bg-white/10 text-emerald-400 border border-emerald-500/20 px-4 py-2 rounded-lg text-sm font-bold">导出 JSON</button>
+                        </div>
+                        {usageReport && (
+                            <table className="w-full text-left text-xs font-mono">
+                                <thead>
+                                    <tr className={`border-b ${t.border} text-slate-500`}>
+                                        <th className="py-1.5 pr-4">周期</th>
+                                        <th className="py-1.5 pr-4">{usageBy === "account" ? "账号" : usageBy === "agent" ? "Agent" : "模型"}</th>
+                                        <th className="py-1.5 pr-4 text-right">请求</th>
+                                        <th className="py-1.5 pr-4 text-right">失败</th>
+                                        <th className="py-1.5 pr-4 text-right">输入</th>
+                                        <th className="py-1.5 pr-4 text-right">输出</th>
+                                        <th className="py-1.5 text-right">总 Token</th>
+                                    </tr>
+                                </thead>
+                                <tbody>
+                                    {[...usageReport.rows.map(r => ({label: new Date(r.period).toLocaleDateString(), r})),
+                                      ...usageReport.totals.map(r => ({label: "合计", r})),
+                                      {label: "合计", r: {...usageReport.total, key: "全部"}}].map(({label, r}, i) => (
+                                        <tr key={i} className={`border-b ${t.border} ${label === "合计" ? "font-bold" : ""}`}>
+                                            <td className="py-1.5 pr-4 text-slate-500">{label}</td>
+                                            <td className="py-1.5 pr-4">{r.key}</td>
+                                            <td className="py-1.5 pr-4 text-right">{r.requests}</td>
+                                            <td className={`py-1.5 pr-4 text-right ${r.errors > 0 ? 'text-rose-500' : ''}`}>{r.errors}</td>
+                                            <td className="py-1.5 pr-4 text-right">{r.inputTokens.toLocaleString()}</td>
+                                            <td className="py-1.5 pr-4 text-right">{r.outputTokens.toLocaleString()}</td>
+                                            <td className="py-1.5 text-right text-blue-500">{r.totalTokens.toLocaleString()}</td>
+                                        </tr>
+                                    ))}
+                                </tbody>
+                            </table>
+                        )}
+                    </div>
+                )}
+
+                {proxyDraft && (
+                    <div className={`mb-8 p-6 rounded-2xl border ${t.card}`}>
+                        <div className="flex justify-between items-center mb-4">
+                            <h2 className="text-base font-bold uppercase tracking-widest text-blue-500">网络代理</h2>
+                            <button onClick={toggleProxy} className="text-xs font-bold text-slate-500 hover:text-blue-500 uppercase">关闭</button>
+                        </div>
+                        <div className="flex gap-3 items-center mb-4">
+                            <select value={proxyDraft.mode} onChange={e => setProxyDraft({...proxyDraft, mode: e.target.value})} className={`px-3 py-2 rounded-lg border text-sm ${t.input}`}>
+                                <option value="system">系统代理 (环境变量)</option>
+                                <option value="none">直连</option>
+                                <option value="http">HTTP</option>
+                                <option value="socks5">SOCKS5</option>
+                            </select>
+                            {(proxyDraft.mode === "http" || proxyDraft.mode === "socks5") && (
+                                <>
+                                    <input value={proxyDraft.url} onChange={e => setProxyDraft({...proxyDraft, url: e.target.value})} placeholder="127.0.0.1:7890" className={`flex-1 px-3 py-2 rounded-lg border text-sm ${t.input}`} />
+                                    <input value={proxyDraft.username || ""} onChange={e => setProxyDraft({...proxyDraft, username: e.target.value})} placeholder="用户名 (可选)" className={`w-36 px-3 py-2 rounded-lg border text-sm ${t.input}`} />
+                                    <input type="password" value={proxyDraft.password || ""} onChange={e => setProxyDraft({...proxyDraft, password: e.target.value})} placeholder="密码 (可选)" className={`w-36 px-3 py-2 rounded-lg border text-sm ${t.input}`} />
+                                </>
+                            )}
+                            <div className="flex-1" />
+                            <button onClick={handleTestProxy} disabled={proxyTesting} className="btn-modern bg-white/5 hover:bg-white/10 text-emerald-400 border border-emerald-500/20 px-4 py-2 rounded-lg text-sm font-bold">{proxyTesting ? "测试中..." : "测试连接"}</button>
+                            <button onClick={handleSaveProxy} className="btn-modern bg-blue-600 hover:bg-blue-500 text-white px-4 py-2 rounded-lg text-sm font-bold">保存</button>
+                        </div>
+                        {Object.keys(cfg?.rotator.accountProxies || {}).length > 0 && (
+                            <div className="text-xs text-slate-500 mb-3">另有 {Object.keys(cfg?.rotator.accountProxies || {}).length} 个账号使用单独代理 (accountProxies)，不受此处影响</div>
+                        )}
+                        {proxyResults && (
+                            <table className="w-full text-left text-xs font-mono">
+                                <tbody>
+                                    {proxyResults.map((r, i) => (
+                                        <tr key={i} className={`border-b ${t.border}`}>
+                                            <td className="py-1.5 pr-4">{r.endpoint}</td>
+                                            <td className="py-1.5 pr-4 text-slate-500">{r.url}</td>
+                                            <td className={`py-1.5 pr-4 font-bold ${r.reachable ? 'text-emerald-500' : 'text-rose-500'}`}>{r.reachable ? `${r.latencyMs} ms` : "不可达"}</td>
+                                            <td className="py-1.5 text-slate-500">{r.error || (r.status ? `HTTP ${r.status}` : "")}</td>
+                                        </tr>
+                                    ))}
+                                </tbody>
+                            </table>
+                        )}
+                    </div>
+                )}
+
+                {importReport && (
+                    <div className={`mb-8 p-6 rounded-2xl border ${t.card}`}>
+                        <div className="flex justify-between items-center mb-4">
+                            <h2 className="text-base font-bold uppercase tracking-widest text-emerald-500">导入报告</h2>
+                            <button onClick={() => setImportReport(null)} className="text-xs font-bold text-slate-500 hover:text-blue-500 uppercase">关闭</button>
+                        </div>
+                        <table className="w-full text-left text-xs font-mono">
+                            <tbody>
+                                {importReport.rows.map((r, i) => (
+                                    <tr key={i} className={`border-b ${t.border}`}>
+                                        <td className="py-1.5 pr-4 text-slate-500">{r.source}</td>
+                                        <td className="py-1.5 pr-4">{r.email || "--"}</td>
+                                        <td className={`py-1.5 pr-4 font-bold ${r.status === 'added' ? 'text-emerald-500' : r.status === 'updated' ? 'text-blue-500' : r.status === 'skipped' ? 'text-rose-500' : 'text-slate-400'}`}>{r.status}</td>
+                                        <td className="py-1.5 text-slate-500">{r.reason}</td>
+                                    </tr>
+                                ))}
+                            </tbody>
+                        </table>
+                    </div>
+                )}
+
                 {activeTab === 'overview' && (
                     <div className="grid grid-cols-1 lg:grid-cols-3 gap-8">
                         {/* Status Panel */}
@@ -417,6 +771,21 @@ function App() {
                                                  </button>
                                              </div>
                                          </div>
+                                         <div className={`flex justify-between items-center p-4 mt-3 rounded-xl ${theme === 'dark' ? 'bg-white/5' : 'bg-slate-100'}`}>
+                                             <div>
+                                                 <h3 className={`text-sm font-bold ${t.heading}`}>负载均衡 (Balance)</h3>
+                                                 <p className="text-xs text-slate-400">Spread usage so accounts degrade together</p>
+                                             </div>
+                                             <select
+                                                value={cfg?.rotator?.balance?.mode || ""}
+                                                onChange={(e) => updateBalance(e.target.value)}
+                                                className={`px-3 py-2 rounded-lg border text-sm ${t.input}`}
+                                             >
+                                                 <option value="">关闭 (最优账号)</option>
+                                                 <option value="slice">按份额轮换 ({cfg?.rotator?.balance?.slice || 10}%)</option>
+                                                 <option value="weighted">按剩余配额加权</option>
+                                             </select>
+                                         </div>
                                     </div>
                                 </div>
                             </div>
@@ -463,7 +832,8 @@ function App() {
                                     <p className="text-xs font-mono text-slate-500 mb-4">{agent.id}</p>
                                     <div className={`pt-4 border-t ${t.border}`}>
                                         <div className="text-xs font-bold text-slate-500 uppercase tracking-wider mb-1">Current Model</div>
-                                        <div className="text-sm font-mono text-emerald-400">{agent.currentModel}</div>
+                                        <div className="text-sm font-mono text-emerald-400">{agent.currentModel || "(defaults)"}</div>
+                                        <div className="text-xs text-slate-500 mt-2">轮换规则: <span className="font-mono">{agent.rule}</span></div>
                                     </div>
                                 </div>
                             ))}
@@ -478,7 +848,12 @@ function App() {
                                 <h2 className="text-base font-bold uppercase tracking-widest text-blue-500">资源配额矩阵 (Resource Matrix)</h2>
                                 <span className="px-2 py-0.5 bg-emerald-500/10 text-emerald-500 text-xs font-bold rounded border border-emerald-500/20">LIVE DATA</span>
                              </div>
-                             <div className="text-xs font-bold text-slate-500">Showing {cfg?.rotator?.accounts?.length || 0} Accounts</div>
+                             <div className="text-xs font-bold text-slate-500">
+                                {cfg?.rotator?.polling?.tiered && (
+                                    <span className="mr-4">轮询: 活跃 {cfg.rotator.polling.hotSeconds}s · 候选 {cfg.rotator.polling.candidates} · 空闲 {cfg.rotator.polling.idleMinutes}m · 耗尽账号等待重置</span>
+                                )}
+                                Showing {cfg?.rotator?.accounts?.length || 0} Accounts
+                             </div>
                         </div>
                         <div className="overflow-x-auto">
                             <table className="w-full text-left border-collapse">
@@ -488,6 +863,7 @@ function App() {
                                         <th className={`p-4 border-b text-center w-32 ${t.border}`}>Pro High</th>
                                         <th className={`p-4 border-b text-center w-32 ${t.border}`}>Flash</th>
                                         <th className={`p-4 border-b text-center w-32 ${t.border}`}>Claude 4.5</th>
+                                        <th className={`p-4 border-b text-center w-40 ${t.border}`}>Polling</th>
                                         <th className={`p-4 border-b text-right w-40 ${t.border}`}>Actions</th>
                                     </tr>
                                 </thead>
@@ -501,11 +877,17 @@ function App() {
                                         const qFlash = getQuota("gemini-3-flash");
                                         const qClaude = getQuota("claude-sonnet-4-5");
 
-                                        const renderCell = (val: number) => {
-                                            if (val === -1) return <span className="text-slate-400 font-bold">--</span>;
-                                            if (val === 0) return <span className="text-rose-500 font-bold">0%</span>;
-                                            if (val < 20) return <span className="text-amber-500 font-bold">{val}%</span>;
-                                            return <span className="text-emerald-500 font-bold">{val}%</span>;
+                                        const catalog = catalogs[acc];
+                                        const available = catalog ? catalog.models.filter(m => m.available) : [];
+
+                                        // 悬停显示限制该展示模型的上游模型
+                                        const renderCell = (val: number, model: string) => {
+                                            const limit = catalog?.display?.[model];
+                                            const title = limit?.limitedBy ? `受限于 ${limit.limitedBy}${limit.resetTime && new Date(limit.resetTime).getFullYear() > 1 ? ` · ${new Date(limit.resetTime).toLocaleString()} 重置` : ""}` : undefined;
+                                            if (val === -1) return <span className="text-slate-400 font-bold" title={catalog ? "该账号无此模型" : undefined}>--</span>;
+                                            if (val === 0) return <span className="text-rose-500 font-bold" title={title}>0%</span>;
+                                            if (val < 20) return <span className="text-amber-500 font-bold" title={title}>{val}%</span>;
+                                            return <span className="text-emerald-500 font-bold" title={title}>{val}%</span>;
                                         };
 
                                         return (
@@ -515,11 +897,33 @@ function App() {
                                                         <div className={`w-2 h-2 rounded-full ${acc === vipEmail ? 'bg-blue-500 animate-pulse' : 'bg-slate-400'}`} />
                                                         <span className={`truncate font-bold ${acc === vipEmail ? 'text-blue-500' : ''}`}>{acc}</span>
                                                         {acc === vipEmail && <span className="text-xs bg-blue-600 text-white px-1.5 py-0.5 rounded font-bold">MAIN</span>}
+                                                        {(() => {
+                                                            // 账号等级与开通状态，未开通的账号不参与轮换
+                                                            const tier = tiers.find(t => t.email === acc);
+                                                            if (!tier) return null;
+                                                            const reasons = (tier.ineligibleTiers || []).map(r => `${r.tierName || r.tierId}: ${r.reasonMessage || r.reasonCode}`);
+                                                            const allowed = (tier.allowedTiers || []).map(t => t.name || t.id).join(", ");
+                                                            const title = [`可用等级: ${allowed || "--"}`, ...reasons].join("\n");
+                                                            if (!tier.onboarded) return <span className="text-xs bg-rose-500/10 text-rose-500 border border-rose-500/20 px-1.5 py-0.5 rounded font-bold" title={title}>未开通</span>;
+                                                            return <span className={`text-xs px-1.5 py-0.5 rounded font-bold border ${tier.rank >= 3 ? 'bg-violet-500/10 text-violet-500 border-violet-500/20' : 'bg-slate-500/10 text-slate-500 border-slate-500/20'}`} title={title}>{tier.tierName || tier.tier}</span>;
+                                                        })()}
                                                     </div>
+                                                    {catalog && (
+                                                        <div className="mt-1 ml-5 text-xs text-slate-500" title={catalog.models.map(m => `${m.available ? "✓" : "✗"} ${m.id}${m.displayName ? ` (${m.displayName})` : ""}${m.available ? ` ${m.percent}%` : ""}`).join("\n")}>
+                                                            {available.length}/{catalog.models.length} 模型可用
+                                                        </div>
+                                                    )}
                                                 </td>
-                                                <td className={`p-4 text-center border-l ${t.border}`}>{renderCell(qPro)}</td>
-                                                <td className={`p-4 text-center border-l ${t.border}`}>{renderCell(qFlash)}</td>
-                                                <td className={`p-4 text-center border-l ${t.border}`}>{renderCell(qClaude)}</td>
+                                                <td className={`p-4 text-center border-l ${t.border}`}>{renderCell(qPro, "google-antigravity/gemini-3-pro-high")}</td>
+                                                <td className={`p-4 text-center border-l ${t.border}`}>{renderCell(qFlash, "google-antigravity/gemini-3-flash")}</td>
+                                                <td className={`p-4 text-center border-l ${t.border}`}>{renderCell(qClaude, "google-antigravity/claude-sonnet-4-5-thinking")}</td>
+                                                <td className={`p-4 text-center border-l text-xs ${t.border}`}>{(() => {
+                                                    const p = pollSchedule.find(p => p.email === acc);
+                                                    if (!p) return <span className="text-slate-400">--</span>;
+                                                    const next = new Date(p.nextPoll);
+                                                    const color = p.tier === 'hot' ? 'text-blue-500' : p.tier === 'exhausted' ? 'text-rose-500' : 'text-slate-400';
+                                                    return <span className={color} title={p.lastPolled}>{p.tier} · {next.getFullYear() > 1 ? next.toLocaleTimeString() : 'now'}</span>;
+                                                })()}</td>
                                                 <td className={`p-4 text-right border-l ${t.border}`}>
                                                     {acc !== vipEmail && (
                                                         <button 
@@ -2,23 +2,66 @@
 // This file is automatically generated. DO NOT EDIT
 import {engine} from '../models';
 import {config} from '../models';
+import {fsutil} from '../models';
+import {google} from '../models';
 import {scanner} from '../models';
+import {usage} from '../models';
+
+export function ExportBundle(arg1:string):Promise<string>;
+
+export function ExportUsage(arg1:string,arg2:string,arg3:string):Promise<string>;
+
+export function FixDrift(arg1:string):Promise<Array<engine.WorkspaceSyncPlan>>;
 
 export function GetAccountStatus():Promise<Record<string, number>>;
 
+export function GetAccountTiers():Promise<Array<engine.AccountTier>>;
+
+export function GetActivePolicy():Promise<config.Policy>;
+
 export function GetAgents():Promise<Array<engine.AgentInfo>>;
 
 export function GetConfig():Promise<config.AppConfig>;
 
+export function GetConfigLoadError():Promise<string>;
+
+export function GetConsistencyReport():Promise<scanner.ConsistencyReport>;
+
+export function GetInstanceRole():Promise<string>;
+
+export function GetModelCatalogs():Promise<Record<string, google.Catalog>>;
+
+export function GetPollSchedule():Promise<Array<engine.AccountPoll>>;
+
+export function GetUsageReport(arg1:string,arg2:string):Promise<usage.Report>;
+
 export function GetVipEmail():Promise<string>;
 
 export function GetWorkspaces():Promise<Array<scanner.WorkspaceInfo>>;
 
-export function ImportAccounts(arg1:string):Promise<string>;
+export function ImportAccounts(arg1:string):Promise<engine.ImportReport>;
+
+export function ImportFromFiles():Promise<engine.ImportReport>;
+
+export function ListBackups():Promise<Array<fsutil.BackupInfo>>;
+
+export function LoginAccount():Promise<engine.ImportReport>;
+
+export function PreviewModelSwitch(arg1:string):Promise<Array<engine.AgentModelChange>>;
+
+export function PreviewRestore(arg1:string,arg2:string):Promise<engine.RestorePlan>;
+
+export function PreviewSync():Promise<Array<engine.WorkspaceSyncPlan>>;
+
+export function RestoreBackup(arg1:string):Promise<string>;
+
+export function RestoreBundle(arg1:string,arg2:string):Promise<engine.RestorePlan>;
 
 export function RunRotation():Promise<string>;
 
-export function SaveConfig(arg1:config.AppConfig):Promise<string>;
+export function SaveConfig(arg1:config.AppConfig):Promise<Array<config.FieldError>>;
+
+export function SelectBundle():Promise<string>;
 
 export function SelectFile():Promise<string>;
 
@@ -29,3 +72,7 @@ export function StopAutoRotation():Promise<void>;
 export function SwitchAccount(arg1:string):Promise<string>;
 
 export function SwitchModel(arg1:string):Promise<string>;
+
+export function SyncWorkspaces():Promise<Array<engine.WorkspaceSyncPlan>>;
+
+export function TestProxy(arg1:config.ProxyConfig):Promise<Array<google.ProbeResult>>;
@@ -2,10 +2,30 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function ExportBundle(arg1) {
+  return window['go']['main']['App']['ExportBundle'](arg1);
+}
+
+export function ExportUsage(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ExportUsage'](arg1, arg2, arg3);
+}
+
+export function FixDrift(arg1) {
+  return window['go']['main']['App']['FixDrift'](arg1);
+}
+
 export function GetAccountStatus() {
   return window['go']['main']['App']['GetAccountStatus']();
 }
 
+export function GetAccountTiers() {
+  return window['go']['main']['App']['GetAccountTiers']();
+}
+
+export function GetActivePolicy() {
+  return window['go']['main']['App']['GetActivePolicy']();
+}
+
 export function GetAgents() {
   return window['go']['main']['App']['GetAgents']();
 }
@@ -14,6 +34,30 @@ export function GetConfig() {
   return window['go']['main']['App']['GetConfig']();
 }
 
+export function GetConfigLoadError() {
+  return window['go']['main']['App']['GetConfigLoadError']();
+}
+
+export function GetConsistencyReport() {
+  return window['go']['main']['App']['GetConsistencyReport']();
+}
+
+export function GetInstanceRole() {
+  return window['go']['main']['App']['GetInstanceRole']();
+}
+
+export function GetModelCatalogs() {
+  return window['go']['main']['App']['GetModelCatalogs']();
+}
+
+export function GetPollSchedule() {
+  return window['go']['main']['App']['GetPollSchedule']();
+}
+
+export function GetUsageReport(arg1, arg2) {
+  return window['go']['main']['App']['GetUsageReport'](arg1, arg2);
+}
+
 export function GetVipEmail() {
   return window['go']['main']['App']['GetVipEmail']();
 }
@@ -26,6 +70,38 @@ export function ImportAccounts(arg1) {
   return window['go']['main']['App']['ImportAccounts'](arg1);
 }
 
+export function ListBackups() {
+  return window['go']['main']['App']['ListBackups']();
+}
+
+export function LoginAccount() {
+  return window['go']['main']['App']['LoginAccount']();
+}
+
+export function PreviewModelSwitch(arg1) {
+  return window['go']['main']['App']['PreviewModelSwitch'](arg1);
+}
+
+export function ImportFromFiles() {
+  return window['go']['main']['App']['ImportFromFiles']();
+}
+
+export function PreviewRestore(arg1,arg2) {
+  return window['go']['main']['App']['PreviewRestore'](arg1,arg2);
+}
+
+export function PreviewSync() {
+  return window['go']['main']['App']['PreviewSync']();
+}
+
+export function RestoreBackup(arg1) {
+  return window['go']['main']['App']['RestoreBackup'](arg1);
+}
+
+export function RestoreBundle(arg1,arg2) {
+  return window['go']['main']['App']['RestoreBundle'](arg1,arg2);
+}
+
 export function RunRotation() {
   return window['go']['main']['App']['RunRotation']();
 }
@@ -34,6 +110,10 @@ export function SaveConfig(arg1) {
   return window['go']['main']['App']['SaveConfig'](arg1);
 }
 
+export function SelectBundle() {
+  return window['go']['main']['App']['SelectBundle']();
+}
+
 export function SelectFile() {
   return window['go']['main']['App']['SelectFile']();
 }
@@ -53,3 +133,11 @@ export function SwitchAccount(arg1) {
 export function SwitchModel(arg1) {
   return window['go']['main']['App']['SwitchModel'](arg1);
 }
+
+export function SyncWorkspaces() {
+  return window['go']['main']['App']['SyncWorkspaces']();
+}
+
+export function TestProxy(arg1) {
+  return window['go']['main']['App']['TestProxy'](arg1);
+}
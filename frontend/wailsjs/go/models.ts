@@ -1,13 +1,83 @@
 export namespace config {
 	
+	export class FieldError {
+	    field: string;
+	    message: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new FieldError(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.field = source["field"];
+	        this.message = source["message"];
+	    }
+	}
+	export class Policy {
+	    schedule: string;
+	    modelPriority: string[];
+	    low: number;
+	    autoRotate: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Policy(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.schedule = source["schedule"];
+	        this.modelPriority = source["modelPriority"];
+	        this.low = source["low"];
+	        this.autoRotate = source["autoRotate"];
+	    }
+	}
+	export class ProxyConfig {
+	    mode: string;
+	    url: string;
+	    username?: string;
+	    password?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProxyConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.mode = source["mode"];
+	        this.url = source["url"];
+	        this.username = source["username"];
+	        this.password = source["password"];
+	    }
+	}
 	export class RotatorConfig {
 	    accounts: string[];
+	    disabledAccounts?: string[];
 	    modelPriority: string[];
 	    // Go type: struct { Low int "json:\"low\"" }
 	    quotas: any;
 	    autoRotate: boolean;
 	    rotateInterval: number;
 	    openclawBin: string;
+	    proxy: ProxyConfig;
+	    accountProxies?: Record<string, ProxyConfig>;
+	    accountTags?: Record<string, string[]>;
+	    syncExclude?: string[];
+	    autoSync: boolean;
+	    openclawHome?: string;
+	    // Go type: config.Installation
+	    installations?: any[];
+	    // Go type: config.AgentRule
+	    agentRules?: Record<string, any>;
+	    // Go type: config.TriggerConfig
+	    triggers?: any;
+	    // Go type: config.PollingConfig
+	    polling?: any;
+	    // Go type: config.BalanceConfig
+	    balance?: any;
+	    recordTrace?: boolean;
+	    // Go type: config.Schedule
+	    schedules?: any[];
 	
 	    static createFrom(source: any = {}) {
 	        return new RotatorConfig(source);
@@ -16,11 +86,25 @@ export namespace config {
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.accounts = source["accounts"];
+	        this.disabledAccounts = source["disabledAccounts"];
 	        this.modelPriority = source["modelPriority"];
 	        this.quotas = this.convertValues(source["quotas"], Object);
 	        this.autoRotate = source["autoRotate"];
 	        this.rotateInterval = source["rotateInterval"];
 	        this.openclawBin = source["openclawBin"];
+	        this.proxy = this.convertValues(source["proxy"], ProxyConfig);
+	        this.accountProxies = this.convertValues(source["accountProxies"], ProxyConfig, true);
+	        this.accountTags = source["accountTags"];
+	        this.syncExclude = source["syncExclude"];
+	        this.autoSync = source["autoSync"];
+	        this.openclawHome = source["openclawHome"];
+	        this.installations = this.convertValues(source["installations"], Object);
+	        this.agentRules = this.convertValues(source["agentRules"], Object, true);
+	        this.triggers = this.convertValues(source["triggers"], Object);
+	        this.polling = this.convertValues(source["polling"], Object);
+	        this.balance = this.convertValues(source["balance"], Object);
+	        this.recordTrace = source["recordTrace"];
+	        this.schedules = this.convertValues(source["schedules"], Object);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -42,6 +126,7 @@ export namespace config {
 		}
 	}
 	export class AppConfig {
+	    version: number;
 	    rotator: RotatorConfig;
 	    lastScan: string[];
 	
@@ -51,6 +136,7 @@ export namespace config {
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.version = source["version"];
 	        this.rotator = this.convertValues(source["rotator"], RotatorConfig);
 	        this.lastScan = source["lastScan"];
 	    }
@@ -83,6 +169,8 @@ export namespace engine {
 	    name: string;
 	    currentModel: string;
 	    emoji: string;
+	    installation?: string;
+	    rule: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new AgentInfo(source);
@@ -94,11 +182,397 @@ export namespace engine {
 	        this.name = source["name"];
 	        this.currentModel = source["currentModel"];
 	        this.emoji = source["emoji"];
+	        this.installation = source["installation"];
+	        this.rule = source["rule"];
+	    }
+	}
+	export class AccountPoll {
+	    email: string;
+	    tier: string;
+	    lastPolled: string;
+	    nextPoll: string;
+	    resetAt: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AccountPoll(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.email = source["email"];
+	        this.tier = source["tier"];
+	        this.lastPolled = source["lastPolled"];
+	        this.nextPoll = source["nextPoll"];
+	        this.resetAt = source["resetAt"];
+	    }
+	}
+	export class AccountTier {
+	    email: string;
+	    projectId?: string;
+	    currentTier?: google.Tier;
+	    paidTier?: google.Tier;
+	    allowedTiers: google.Tier[];
+	    ineligibleTiers: google.IneligibleTier[];
+	    tier: string;
+	    tierName: string;
+	    rank: number;
+	    onboarded: boolean;
+	    checkedAt: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AccountTier(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.email = source["email"];
+	        this.projectId = source["projectId"];
+	        this.currentTier = this.convertValues(source["currentTier"], google.Tier);
+	        this.paidTier = this.convertValues(source["paidTier"], google.Tier);
+	        this.allowedTiers = this.convertValues(source["allowedTiers"], google.Tier);
+	        this.ineligibleTiers = this.convertValues(source["ineligibleTiers"], google.IneligibleTier);
+	        this.tier = source["tier"];
+	        this.tierName = source["tierName"];
+	        this.rank = source["rank"];
+	        this.onboarded = source["onboarded"];
+	        this.checkedAt = source["checkedAt"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RestorePlan {
+	    createdAt: string;
+	    version: number;
+	    encrypted: boolean;
+	    accountsAdded: string[];
+	    accountsRemoved: string[];
+	    profilesAdded: string[];
+	    profilesUpdated: string[];
+	    profilesUnchanged: number;
+	    configChanges: string[];
+	    historyEntries: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new RestorePlan(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.createdAt = source["createdAt"];
+	        this.version = source["version"];
+	        this.encrypted = source["encrypted"];
+	        this.accountsAdded = source["accountsAdded"];
+	        this.accountsRemoved = source["accountsRemoved"];
+	        this.profilesAdded = source["profilesAdded"];
+	        this.profilesUpdated = source["profilesUpdated"];
+	        this.profilesUnchanged = source["profilesUnchanged"];
+	        this.configChanges = source["configChanges"];
+	        this.historyEntries = source["historyEntries"];
+	    }
+	}
+	export class ImportRow {
+	    source: string;
+	    email: string;
+	    status: string;
+	    reason?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportRow(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.source = source["source"];
+	        this.email = source["email"];
+	        this.status = source["status"];
+	        this.reason = source["reason"];
+	    }
+	}
+	export class ImportReport {
+	    rows: ImportRow[];
+	    added: number;
+	    updated: number;
+	    skipped: number;
+	    duplicates: number;
+	    dryRun: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.rows = (source["rows"] || []).map((r: any) => new ImportRow(r));
+	        this.added = source["added"];
+	        this.updated = source["updated"];
+	        this.skipped = source["skipped"];
+	        this.duplicates = source["duplicates"];
+	        this.dryRun = source["dryRun"];
+	    }
+	}
+	export class AgentModelChange {
+	    installation?: string;
+	    agentId: string;
+	    name: string;
+	    mode: string;
+	    action: string;
+	    from: string;
+	    to: string;
+	    fallbacks?: string[];
+	    reason?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AgentModelChange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.installation = source["installation"];
+	        this.agentId = source["agentId"];
+	        this.name = source["name"];
+	        this.mode = source["mode"];
+	        this.action = source["action"];
+	        this.from = source["from"];
+	        this.to = source["to"];
+	        this.fallbacks = source["fallbacks"];
+	        this.reason = source["reason"];
+	    }
+	}
+	export class ProfileChange {
+	    profile: string;
+	    action: string;
+	    reason?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProfileChange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.profile = source["profile"];
+	        this.action = source["action"];
+	        this.reason = source["reason"];
+	    }
+	}
+	export class WorkspaceSyncPlan {
+	    installation: string;
+	    path: string;
+	    excluded: boolean;
+	    changes: ProfileChange[];
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new WorkspaceSyncPlan(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.installation = source["installation"];
+	        this.path = source["path"];
+	        this.excluded = source["excluded"];
+	        this.changes = (source["changes"] || []).map((c: any) => new ProfileChange(c));
+	        this.error = source["error"];
 	    }
 	}
 
 }
 
+export namespace fsutil {
+	
+	export class BackupInfo {
+	    path: string;
+	    original: string;
+	    time: string;
+	    size: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new BackupInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.original = source["original"];
+	        this.time = source["time"];
+	        this.size = source["size"];
+	    }
+	}
+
+}
+
+export namespace google {
+	
+	export class ProbeResult {
+	    endpoint: string;
+	    url: string;
+	    reachable: boolean;
+	    status: number;
+	    latencyMs: number;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProbeResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.endpoint = source["endpoint"];
+	        this.url = source["url"];
+	        this.reachable = source["reachable"];
+	        this.status = source["status"];
+	        this.latencyMs = source["latencyMs"];
+	        this.error = source["error"];
+	    }
+	}
+	export class Tier {
+	    id: string;
+	    name?: string;
+	    description?: string;
+	    isDefault?: boolean;
+	    userDefinedCloudaicompanionProject?: boolean;
+	    hasAcceptedTos?: boolean;
+	    hasOnboardedPreviously?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Tier(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.isDefault = source["isDefault"];
+	        this.userDefinedCloudaicompanionProject = source["userDefinedCloudaicompanionProject"];
+	        this.hasAcceptedTos = source["hasAcceptedTos"];
+	        this.hasOnboardedPreviously = source["hasOnboardedPreviously"];
+	    }
+	}
+	export class IneligibleTier {
+	    tierId: string;
+	    tierName?: string;
+	    reasonCode: string;
+	    reasonMessage?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new IneligibleTier(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.tierId = source["tierId"];
+	        this.tierName = source["tierName"];
+	        this.reasonCode = source["reasonCode"];
+	        this.reasonMessage = source["reasonMessage"];
+	    }
+	}
+	export class ModelQuota {
+	    percent: number;
+	    resetTime: string;
+	    limitedBy?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ModelQuota(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.percent = source["percent"];
+	        this.resetTime = source["resetTime"];
+	        this.limitedBy = source["limitedBy"];
+	    }
+	}
+	export class CatalogModel {
+	    id: string;
+	    displayName: string;
+	    display?: string;
+	    apiProvider?: string;
+	    modelProvider?: string;
+	    maxTokens?: number;
+	    maxOutputTokens?: number;
+	    supportsImages: boolean;
+	    supportsThinking: boolean;
+	    thinkingBudget?: number;
+	    recommended: boolean;
+	    available: boolean;
+	    percent: number;
+	    resetTime: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new CatalogModel(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.displayName = source["displayName"];
+	        this.display = source["display"];
+	        this.apiProvider = source["apiProvider"];
+	        this.modelProvider = source["modelProvider"];
+	        this.maxTokens = source["maxTokens"];
+	        this.maxOutputTokens = source["maxOutputTokens"];
+	        this.supportsImages = source["supportsImages"];
+	        this.supportsThinking = source["supportsThinking"];
+	        this.thinkingBudget = source["thinkingBudget"];
+	        this.recommended = source["recommended"];
+	        this.available = source["available"];
+	        this.percent = source["percent"];
+	        this.resetTime = source["resetTime"];
+	    }
+	}
+	export class Catalog {
+	    fetchedAt: string;
+	    models: CatalogModel[];
+	    display: Record<string, ModelQuota>;
+	
+	    static createFrom(source: any = {}) {
+	        return new Catalog(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.fetchedAt = source["fetchedAt"];
+	        this.models = this.convertValues(source["models"], CatalogModel);
+	        this.display = this.convertValues(source["display"], ModelQuota, true);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
 export namespace scanner {
 	
 	export class WorkspaceInfo {
@@ -117,6 +591,97 @@ export namespace scanner {
 	        this.hasAuthProfiles = source["hasAuthProfiles"];
 	    }
 	}
+	export class ConsistencyReport {
+	    accounts: string[];
+	    workspaces: string[];
+	    cells: Record<string, Record<string, string>>;
+	    inSync: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ConsistencyReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.accounts = source["accounts"];
+	        this.workspaces = source["workspaces"];
+	        this.cells = source["cells"];
+	        this.inSync = source["inSync"];
+	    }
+	}
+
+}
+
+export namespace usage {
+	
+	export class Row {
+	    period: string;
+	    key: string;
+	    requests: number;
+	    errors: number;
+	    inputTokens: number;
+	    outputTokens: number;
+	    cacheTokens: number;
+	    totalTokens: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Row(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.period = source["period"];
+	        this.key = source["key"];
+	        this.requests = source["requests"];
+	        this.errors = source["errors"];
+	        this.inputTokens = source["inputTokens"];
+	        this.outputTokens = source["outputTokens"];
+	        this.cacheTokens = source["cacheTokens"];
+	        this.totalTokens = source["totalTokens"];
+	    }
+	}
+	export class Report {
+	    by: string;
+	    period: string;
+	    from: string;
+	    to: string;
+	    rows: Row[];
+	    totals: Row[];
+	    total: Row;
+	
+	    static createFrom(source: any = {}) {
+	        return new Report(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.by = source["by"];
+	        this.period = source["period"];
+	        this.from = source["from"];
+	        this.to = source["to"];
+	        this.rows = this.convertValues(source["rows"], Row);
+	        this.totals = this.convertValues(source["totals"], Row);
+	        this.total = this.convertValues(source["total"], Row);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 
 }
 
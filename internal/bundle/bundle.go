@@ -0,0 +1,190 @@
+// Package bundle encodes account backups: the rotator config, the
+// google-antigravity credentials, per-account state and the rotation
+// history, optionally encrypted with a passphrase.
+package bundle
+
+import (
+	"antigravity-rotator-v2/internal/history"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Format identifies bundle files.
+const Format = "antigravity-rotator-bundle"
+
+// Version is the bundle version written by this build.
+//
+//	1: config, profiles, accounts and history
+const Version = 1
+
+// upgrades[i] upgrades a raw bundle payload from version i+1 to i+2, so
+// exports made by older builds still restore. The embedded config is
+// migrated separately by config.Parse.
+var upgrades = []func(raw map[string]interface{}) error{}
+
+var (
+	// ErrPassphraseRequired is returned when decoding an encrypted bundle
+	// without a passphrase.
+	ErrPassphraseRequired = errors.New("备份已加密，需要密码")
+	// ErrWrongPassphrase is returned when the passphrase does not decrypt
+	// the bundle (or the file was modified).
+	ErrWrongPassphrase = errors.New("密码错误或备份已损坏")
+)
+
+// Bundle is the decoded content of a backup.
+type Bundle struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Config is the rotator config document as saved, of any config version.
+	Config json.RawMessage `json:"config"`
+	// Profiles are the google-antigravity entries of auth-profiles.json,
+	// keyed like the file.
+	Profiles map[string]interface{} `json:"profiles"`
+	Accounts []Account              `json:"accounts"`
+	History  []history.Entry        `json:"history"`
+}
+
+// Account is the exported state of one managed account.
+type Account struct {
+	Email    string         `json:"email"`
+	Disabled bool           `json:"disabled"`
+	Tags     []string       `json:"tags,omitempty"`
+	Quotas   map[string]int `json:"quotas,omitempty"`
+}
+
+// envelope is the file layout. Exactly one of Data and Ciphertext is set.
+type envelope struct {
+	Format     string          `json:"format"`
+	Version    int             `json:"version"`
+	Encrypted  bool            `json:"encrypted"`
+	KDF        *kdf            `json:"kdf,omitempty"`
+	Nonce      []byte          `json:"nonce,omitempty"`
+	Ciphertext []byte          `json:"ciphertext,omitempty"`
+	Data       json.RawMessage `json:"data,omitempty"`
+}
+
+type kdf struct {
+	Name string `json:"name"` // scrypt
+	Salt []byte `json:"salt"`
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+}
+
+// Encode serializes b; a non-empty passphrase encrypts it with AES-256-GCM
+// under a scrypt-derived key.
+func Encode(b *Bundle, passphrase string) ([]byte, error) {
+	b.Version = Version
+	payload, err := json.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+	env := envelope{Format: Format, Version: Version}
+	if passphrase == "" {
+		env.Data = payload
+		return json.MarshalIndent(env, "", "  ")
+	}
+
+	k := &kdf{Name: "scrypt", Salt: make([]byte, 16), N: 1 << 15, R: 8, P: 1}
+	if _, err := rand.Read(k.Salt); err != nil {
+		return nil, err
+	}
+	aead, err := k.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	env.Encrypted, env.KDF = true, k
+	env.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(env.Nonce); err != nil {
+		return nil, err
+	}
+	env.Ciphertext = aead.Seal(nil, env.Nonce, payload, env.header())
+	return json.MarshalIndent(env, "", "  ")
+}
+
+// Encrypted reports whether data is an encrypted bundle.
+func Encrypted(data []byte) bool {
+	var env envelope
+	return json.Unmarshal(data, &env) == nil && env.Format == Format && env.Encrypted
+}
+
+// Decode reads a bundle of any version up to Version, upgrading it.
+func Decode(data []byte, passphrase string) (*Bundle, error) {
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("解析备份失败: %v", err)
+	}
+	if env.Format != Format {
+		return nil, fmt.Errorf("不是 antigravity-rotator 备份文件")
+	}
+	if env.Version < 1 || env.Version > Version {
+		return nil, fmt.Errorf("备份版本 %d 不受支持，当前支持 1-%d", env.Version, Version)
+	}
+
+	payload := []byte(env.Data)
+	if env.Encrypted {
+		if passphrase == "" {
+			return nil, ErrPassphraseRequired
+		}
+		if env.KDF == nil || env.KDF.Name != "scrypt" {
+			return nil, fmt.Errorf("不支持的密钥派生方式")
+		}
+		aead, err := env.KDF.aead(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		if len(env.Nonce) != aead.NonceSize() {
+			return nil, ErrWrongPassphrase
+		}
+		payload, err = aead.Open(nil, env.Nonce, env.Ciphertext, env.header())
+		if err != nil {
+			return nil, ErrWrongPassphrase
+		}
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(payload, &raw); err != nil || raw == nil {
+		return nil, fmt.Errorf("解析备份内容失败: %v", err)
+	}
+	for v := env.Version; v < Version; v++ {
+		if err := upgrades[v-1](raw); err != nil {
+			return nil, fmt.Errorf("备份升级 v%d -> v%d 失败: %v", v, v+1, err)
+		}
+	}
+	raw["version"] = Version
+
+	upgraded, _ := json.Marshal(raw)
+	var b Bundle
+	if err := json.Unmarshal(upgraded, &b); err != nil {
+		return nil, fmt.Errorf("解析备份内容失败: %v", err)
+	}
+	if b.Profiles == nil {
+		b.Profiles = make(map[string]interface{})
+	}
+	return &b, nil
+}
+
+// header is authenticated with the ciphertext, so the format and version
+// cannot be swapped on an encrypted payload.
+func (env envelope) header() []byte {
+	return []byte(fmt.Sprintf("%s/v%d", env.Format, env.Version))
+}
+
+func (k *kdf) aead(passphrase string) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), k.Salt, k.N, k.R, k.P, 32)
+	if err != nil {
+		return nil, fmt.Errorf("派生密钥失败: %v", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"antigravity-rotator-v2/internal/engine"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envPassphrase supplies the bundle passphrase without putting it on the
+// command line.
+const envPassphrase = "ANTIGRAVITY_ROTATOR_PASSPHRASE"
+
+func passphrase(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	return os.Getenv(envPassphrase)
+}
+
+func runExport(c *cmdContext, args []string) int {
+	fs := c.flags("export")
+	pass := fs.String("passphrase", "", "encrypt the bundle (or set $"+envPassphrase+")")
+	pos, err := parse(fs, args)
+	if err != nil {
+		return ExitUsage
+	}
+	if len(pos) != 1 {
+		return c.usage(commands["export"].usage)
+	}
+	eng, err := c.engine()
+	if err != nil {
+		return c.fail(err)
+	}
+	data, err := eng.ExportBundle(passphrase(*pass))
+	if err != nil {
+		return c.fail(err)
+	}
+	if err := os.WriteFile(pos[0], data, 0600); err != nil {
+		return c.fail(err)
+	}
+	return c.ok("exported to " + pos[0])
+}
+
+func runRestore(c *cmdContext, args []string) int {
+	fs := c.flags("restore")
+	pass := fs.String("passphrase", "", "passphrase of an encrypted bundle (or set $"+envPassphrase+")")
+	dryRun := fs.Bool("dry-run", false, "only show what would change")
+	pos, err := parse(fs, args)
+	if err != nil {
+		return ExitUsage
+	}
+	if len(pos) != 1 {
+		return c.usage(commands["restore"].usage)
+	}
+	data, err := os.ReadFile(pos[0])
+	if err != nil {
+		return c.fail(err)
+	}
+	eng, err := c.engine()
+	if err != nil {
+		return c.fail(err)
+	}
+
+	var plan engine.RestorePlan
+	if *dryRun {
+		plan, err = eng.PreviewRestore(data, passphrase(*pass))
+	} else {
+		plan, err = eng.RestoreBundle(data, passphrase(*pass))
+	}
+	if err != nil {
+		return c.fail(err)
+	}
+	if !*dryRun {
+		c.notifyLeader()
+	}
+
+	if c.json {
+		c.writeJSON(plan)
+		return ExitOK
+	}
+	c.table([]string{"CHANGE", "ITEMS"}, [][]string{
+		{"accounts added", orDash(strings.Join(plan.AccountsAdded, ", "))},
+		{"accounts removed", orDash(strings.Join(plan.AccountsRemoved, ", "))},
+		{"profiles added", orDash(strings.Join(plan.ProfilesAdded, ", "))},
+		{"profiles updated", orDash(strings.Join(plan.ProfilesUpdated, ", "))},
+		{"config", orDash(strings.Join(plan.ConfigChanges, ", "))},
+		{"history", fmt.Sprintf("%d entries", plan.HistoryEntries)},
+	})
+	verb := "restored"
+	if *dryRun {
+		verb = "would restore"
+	}
+	fmt.Fprintf(c.stdout, "\n%s bundle v%d from %s (%d profiles unchanged)\n",
+		verb, plan.Version, plan.CreatedAt.Local().Format("2006-01-02 15:04"), plan.ProfilesUnchanged)
+	return ExitOK
+}
@@ -0,0 +1,199 @@
+// Package cli implements the scriptable command-line interface that shares
+// the binary with the desktop app.
+package cli
+
+import (
+	"antigravity-rotator-v2/internal/config"
+	"antigravity-rotator-v2/internal/engine"
+	"antigravity-rotator-v2/internal/instance"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Exit codes returned by Run.
+const (
+	ExitOK        = 0
+	ExitError     = 1 // the command failed
+	ExitUsage     = 2 // bad arguments
+	ExitNoHealthy = 3 // rotate found no model above the threshold
+)
+
+type command struct {
+	usage   string
+	summary string
+	run     func(c *cmdContext, args []string) int
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"status":         {"status [--json] [--no-refresh]", "Show quota per account and model", runStatus},
+		"models":         {"models [<email>] [--no-refresh]", "List every upstream model per account with limits, quota and availability", runModels},
+		"rotate":         {"rotate [--dry-run] [--json]", "Run one rotation cycle", runRotate},
+		"switch-model":   {"switch-model <model> [--dry-run]", "Make a model the default and apply the agent rules", runSwitchModel},
+		"switch-account": {"switch-account <email>", "Move an account to the front of auth.order", runSwitchAccount},
+		"import":         {"import <file>... [--dry-run] [--no-validate]", "Import accounts from JSON, CSV, email:token text, auth-profiles.json, CLI credential files or zip", runImport},
+		"login":          {"login [--no-browser]", "Add an account by signing in with Google", runLogin},
+		"accounts":       {"accounts list|remove|disable|enable [<email>] [--purge] | accounts tag <email> [<tag>...]", "Manage accounts", runAccounts},
+		"export":         {"export <file> [--passphrase <p>]", "Back up config, credentials, account states and rotation history", runExport},
+		"restore":        {"restore <file> [--passphrase <p>] [--dry-run]", "Restore a backup made by export, showing what changes", runRestore},
+		"usage":          {"usage [--by account|agent|model] [--period day|week] [--since <date>] [--until <date>] [--csv] [--output <file>] | usage ingest [<file>...]", "Report requests and tokens per account, agent or model from session transcripts and gateway logs", runUsage},
+		"workspaces":     {"workspaces [--json]", "List OpenClaw workspaces and credential drift", runWorkspaces},
+		"agents":         {"agents [--json]", "List configured agents and their models", runAgents},
+		"config":         {"config get [<path>] | config set <path> <value>", "Read or change the rotator config", runConfig},
+		"simulate":       {"simulate [<trace>] [--synthetic [--accounts <n>] [--hours <h>] [--reset-hours <h>] [--stagger]] [--window <h>] [--load <%/h>] [--low <n,...>] [--interval <min,...>] [--tiered true|false] [--balance off,slice,weighted] [--slice <%>]", "Replay a quota trace (or a synthetic one) against rotation policies in virtual time", runSimulate},
+		"trigger":        {"trigger [<reason>]", "Ask the running instance for a debounced rotation", runTrigger},
+		"daemon":         {"daemon", "Run the auto-rotation loop headless as the leader instance", runDaemon},
+		"help":           {"help", "Show this help", runHelp},
+	}
+}
+
+// IsCommand reports whether name is a CLI subcommand, i.e. whether the
+// binary should run headless instead of opening the window.
+func IsCommand(name string) bool {
+	_, ok := commands[name]
+	return ok
+}
+
+type cmdContext struct {
+	stdout, stderr io.Writer
+	json           bool
+	eng            *engine.RotatorEngine
+}
+
+// Run executes a CLI command and returns the process exit code.
+func Run(args []string, stdout, stderr io.Writer) int {
+	c := &cmdContext{stdout: stdout, stderr: stderr}
+	if len(args) == 0 {
+		return runHelp(c, nil)
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n", args[0])
+		runHelp(c, nil)
+		return ExitUsage
+	}
+	return cmd.run(c, args[1:])
+}
+
+func runHelp(c *cmdContext, _ []string) int {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(c.stdout, "Usage: antigravity-rotator-v2 [--openclaw-home <dir>] <command> [options]")
+	fmt.Fprintln(c.stdout)
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\t%s\n", commands[name].usage, commands[name].summary)
+	}
+	w.Flush()
+	return ExitOK
+}
+
+// flags returns a flag set with the shared --json option.
+func (c *cmdContext) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.BoolVar(&c.json, "json", false, "machine-readable JSON output")
+	return fs
+}
+
+// parse parses flags anywhere in args and returns the positional arguments.
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// engine loads the config and builds a headless engine on first use.
+func (c *cmdContext) engine() (*engine.RotatorEngine, error) {
+	if c.eng != nil {
+		return c.eng, nil
+	}
+	cfg, err := config.LoadConfig()
+	if cfg == nil {
+		return nil, err
+	}
+	if err != nil {
+		// Corrupt files were replaced by defaults, migrated ones are
+		// usable and invalid ones still are for read-only commands; warn
+		// and carry on.
+		fmt.Fprintf(c.stderr, "warning: %v\n", err)
+	}
+	c.eng = engine.NewRotatorEngine(cfg)
+	return c.eng, nil
+}
+
+// leader returns the running GUI or daemon instance, if any. Commands that
+// change the gateway go through it so that two engines never rotate at once.
+func (c *cmdContext) leader() *instance.Client {
+	client, err := instance.Find(config.DefaultOpenClawHome())
+	if err != nil {
+		return nil
+	}
+	return client
+}
+
+// notifyLeader asks the running instance to reload the config file after
+// this command changed it.
+func (c *cmdContext) notifyLeader() {
+	if l := c.leader(); l != nil {
+		if err := l.ReloadConfig(); err != nil {
+			fmt.Fprintf(c.stderr, "warning: %v\n", err)
+		}
+	}
+}
+
+func (c *cmdContext) fail(err error) int {
+	if c.json {
+		out := map[string]interface{}{"error": err.Error()}
+		var verrs config.ValidationErrors
+		if errors.As(err, &verrs) {
+			out["fields"] = verrs
+		}
+		c.writeJSON(out)
+	} else {
+		fmt.Fprintf(c.stderr, "error: %v\n", err)
+	}
+	return ExitError
+}
+
+func (c *cmdContext) usage(format string, args ...interface{}) int {
+	fmt.Fprintf(c.stderr, "usage: "+format+"\n", args...)
+	return ExitUsage
+}
+
+func (c *cmdContext) writeJSON(v interface{}) {
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// table prints rows as aligned columns.
+func (c *cmdContext) table(header []string, rows [][]string) {
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+}
@@ -0,0 +1,547 @@
+package cli
+
+import (
+	"antigravity-rotator-v2/internal/config"
+	"antigravity-rotator-v2/internal/engine"
+	"antigravity-rotator-v2/internal/importer"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func runStatus(c *cmdContext, args []string) int {
+	fs := c.flags("status")
+	noRefresh := fs.Bool("no-refresh", false, "show cached status without querying Google")
+	if _, err := parse(fs, args); err != nil {
+		return ExitUsage
+	}
+	eng, err := c.engine()
+	if err != nil {
+		return c.fail(err)
+	}
+	var accounts []engine.AccountSummary
+	var tiers []engine.AccountTier
+	if l := c.leader(); l != nil {
+		status, err := l.Status(!*noRefresh)
+		if err != nil {
+			return c.fail(err)
+		}
+		accounts = engine.SummarizeAccounts(eng.Config(), status)
+		tiers, _ = l.AccountTiers()
+	} else {
+		if !*noRefresh {
+			if err := eng.RefreshStatus(); err != nil {
+				return c.fail(err)
+			}
+		}
+		accounts = eng.ListAccounts()
+		tiers = eng.AccountTiers()
+	}
+
+	if c.json {
+		c.writeJSON(accounts)
+		return ExitOK
+	}
+	tierOf := make(map[string]string)
+	for _, t := range tiers {
+		switch {
+		case !t.Onboarded:
+			tierOf[t.Email] = "not onboarded"
+		case t.TierName != "":
+			tierOf[t.Email] = t.TierName
+		default:
+			tierOf[t.Email] = t.Tier
+		}
+	}
+	var rows [][]string
+	for _, acc := range accounts {
+		models := make([]string, 0, len(acc.Quotas))
+		for m := range acc.Quotas {
+			models = append(models, m)
+		}
+		sort.Strings(models)
+		state := "enabled"
+		if acc.Disabled {
+			state = "disabled"
+		}
+		tier := orDash(tierOf[acc.Email])
+		if len(models) == 0 {
+			rows = append(rows, []string{acc.Email, "-", "-", state, tier})
+		}
+		for _, m := range models {
+			rows = append(rows, []string{acc.Email, m, fmt.Sprintf("%d%%", acc.Quotas[m]), state, tier})
+		}
+	}
+	c.table([]string{"ACCOUNT", "MODEL", "QUOTA", "STATE", "TIER"}, rows)
+	return ExitOK
+}
+
+// gatewaySwitcher is either the local engine or the running leader.
+type gatewaySwitcher interface {
+	SwitchModel(modelID string) error
+	SwitchAccount(email string) error
+}
+
+// switcher returns the leader when one runs, else the local engine.
+func (c *cmdContext) switcher() (gatewaySwitcher, error) {
+	if l := c.leader(); l != nil {
+		return l, nil
+	}
+	return c.engine()
+}
+
+func runRotate(c *cmdContext, args []string) int {
+	fs := c.flags("rotate")
+	dryRun := fs.Bool("dry-run", false, "only report what would be switched")
+	if _, err := parse(fs, args); err != nil {
+		return ExitUsage
+	}
+	var (
+		d   engine.Decision
+		ok  bool
+		err error
+	)
+	l := c.leader()
+	switch {
+	case l != nil && *dryRun:
+		d, ok, err = l.PlanRotation()
+	case l != nil:
+		d, ok, err = l.Rotate()
+	default:
+		var eng *engine.RotatorEngine
+		if eng, err = c.engine(); err == nil {
+			if *dryRun {
+				d, ok = eng.PlanRotation()
+			} else {
+				d, ok, err = eng.Rotate()
+			}
+		}
+	}
+	if err != nil {
+		return c.fail(err)
+	}
+	if !ok {
+		if c.json {
+			c.writeJSON(map[string]interface{}{"switched": false, "reason": "no healthy model above threshold"})
+		} else {
+			fmt.Fprintln(c.stderr, "no healthy model above threshold")
+		}
+		return ExitNoHealthy
+	}
+
+	if c.json {
+		c.writeJSON(map[string]interface{}{"switched": !*dryRun, "decision": d})
+	} else {
+		verb := "switched to"
+		if *dryRun {
+			verb = "would switch to"
+		}
+		fmt.Fprintf(c.stdout, "%s %s on %s (%d%%)\n", verb, d.Model, d.Account, d.Quota)
+		if len(d.Order) > 1 {
+			fmt.Fprintf(c.stdout, "balanced auth.order: %s\n", strings.Join(d.Order, ", "))
+		}
+	}
+	return ExitOK
+}
+
+func runTrigger(c *cmdContext, args []string) int {
+	fs := c.flags("trigger")
+	pos, err := parse(fs, args)
+	if err != nil {
+		return ExitUsage
+	}
+	reason := "manual"
+	if len(pos) > 0 {
+		reason = strings.Join(pos, " ")
+	}
+	l := c.leader()
+	if l == nil {
+		// Nothing is running to debounce against: rotate right away, but
+		// refuse like the leader would while auto-rotation is off.
+		eng, err := c.engine()
+		if err != nil {
+			return c.fail(err)
+		}
+		if err := eng.TriggerPaused(); err != nil {
+			return c.fail(err)
+		}
+		var rotateArgs []string
+		if c.json {
+			rotateArgs = []string{"--json"}
+		}
+		return runRotate(c, rotateArgs)
+	}
+	if err := l.TriggerRotation(reason); err != nil {
+		return c.fail(err)
+	}
+	return c.ok("rotation requested: " + reason)
+}
+
+func runSwitchModel(c *cmdContext, args []string) int {
+	fs := c.flags("switch-model")
+	dryRun := fs.Bool("dry-run", false, "only show which agents would be touched")
+	pos, err := parse(fs, args)
+	if err != nil {
+		return ExitUsage
+	}
+	if len(pos) != 1 {
+		return c.usage(commands["switch-model"].usage)
+	}
+	if *dryRun {
+		eng, err := c.engine()
+		if err != nil {
+			return c.fail(err)
+		}
+		changes, err := eng.PreviewModelSwitch(pos[0])
+		if err != nil {
+			return c.fail(err)
+		}
+		if c.json {
+			c.writeJSON(changes)
+			return ExitOK
+		}
+		var rows [][]string
+		for _, ch := range changes {
+			rows = append(rows, []string{ch.AgentID, ch.Mode, ch.Action, orDash(ch.From), orDash(ch.To), ch.Reason})
+		}
+		c.table([]string{"AGENT", "RULE", "ACTION", "FROM", "TO", "REASON"}, rows)
+		return ExitOK
+	}
+	target, err := c.switcher()
+	if err != nil {
+		return c.fail(err)
+	}
+	if err := target.SwitchModel(pos[0]); err != nil {
+		return c.fail(err)
+	}
+	return c.ok("model switched to " + pos[0])
+}
+
+func runSwitchAccount(c *cmdContext, args []string) int {
+	fs := c.flags("switch-account")
+	pos, err := parse(fs, args)
+	if err != nil {
+		return ExitUsage
+	}
+	if len(pos) != 1 {
+		return c.usage(commands["switch-account"].usage)
+	}
+	target, err := c.switcher()
+	if err != nil {
+		return c.fail(err)
+	}
+	if err := target.SwitchAccount(pos[0]); err != nil {
+		return c.fail(err)
+	}
+	return c.ok("account switched to " + pos[0])
+}
+
+func runImport(c *cmdContext, args []string) int {
+	fs := c.flags("import")
+	noValidate := fs.Bool("no-validate", false, "do not refresh the tokens before importing")
+	dryRun := fs.Bool("dry-run", false, "only report what would be imported")
+	pos, err := parse(fs, args)
+	if err != nil {
+		return ExitUsage
+	}
+	if len(pos) == 0 {
+		return c.usage(commands["import"].usage)
+	}
+
+	var entries []importer.Entry
+	var problems []importer.Problem
+	for _, name := range pos {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return c.fail(err)
+		}
+		e, p, err := importer.Parse(filepath.Base(name), data)
+		if err != nil {
+			return c.fail(err)
+		}
+		entries = append(entries, e...)
+		problems = append(problems, p...)
+	}
+	eng, err := c.engine()
+	if err != nil {
+		return c.fail(err)
+	}
+	report, err := eng.ImportCredentials(entries, problems, engine.ImportOptions{Validate: !*noValidate, DryRun: *dryRun})
+	if err != nil {
+		return c.fail(err)
+	}
+	if !*dryRun && report.Added+report.Updated > 0 {
+		c.notifyLeader()
+	}
+
+	if c.json {
+		c.writeJSON(report)
+		return ExitOK
+	}
+	var rows [][]string
+	for _, r := range report.Rows {
+		rows = append(rows, []string{r.Source, orDash(r.Email), r.Status, r.Reason})
+	}
+	c.table([]string{"SOURCE", "EMAIL", "STATUS", "REASON"}, rows)
+	fmt.Fprintf(c.stdout, "\n%d added, %d updated, %d skipped, %d duplicates\n",
+		report.Added, report.Updated, report.Skipped, report.Duplicates)
+	return ExitOK
+}
+
+func runAccounts(c *cmdContext, args []string) int {
+	fs := c.flags("accounts")
+	purge := fs.Bool("purge", false, "remove: also delete the credentials from auth-profiles.json")
+	pos, err := parse(fs, args)
+	if err != nil {
+		return ExitUsage
+	}
+	if len(pos) == 0 {
+		return c.usage(commands["accounts"].usage)
+	}
+	eng, err := c.engine()
+	if err != nil {
+		return c.fail(err)
+	}
+
+	action := pos[0]
+	if action == "list" {
+		accounts := eng.ListAccounts()
+		if c.json {
+			c.writeJSON(accounts)
+			return ExitOK
+		}
+		var rows [][]string
+		for _, acc := range accounts {
+			rows = append(rows, []string{acc.Email, strconv.FormatBool(acc.Disabled), orDash(strings.Join(acc.Tags, ","))})
+		}
+		c.table([]string{"ACCOUNT", "DISABLED", "TAGS"}, rows)
+		return ExitOK
+	}
+	if action == "tag" && len(pos) >= 2 {
+		if err := eng.SetAccountTags(pos[1], pos[2:]); err != nil {
+			return c.fail(err)
+		}
+		c.notifyLeader()
+		return c.ok("tagged " + pos[1])
+	}
+
+	if len(pos) != 2 {
+		return c.usage(commands["accounts"].usage)
+	}
+	email := pos[1]
+	switch action {
+	case "remove":
+		err = eng.RemoveAccount(email, *purge)
+	case "disable":
+		err = eng.SetAccountDisabled(email, true)
+	case "enable":
+		err = eng.SetAccountDisabled(email, false)
+	default:
+		return c.usage(commands["accounts"].usage)
+	}
+	if err != nil {
+		return c.fail(err)
+	}
+	c.notifyLeader()
+	return c.ok(action + "d " + email)
+}
+
+func runWorkspaces(c *cmdContext, args []string) int {
+	fs := c.flags("workspaces")
+	if _, err := parse(fs, args); err != nil {
+		return ExitUsage
+	}
+	eng, err := c.engine()
+	if err != nil {
+		return c.fail(err)
+	}
+	workspaces := eng.GetWorkspaces()
+	if c.json {
+		c.writeJSON(workspaces)
+		return ExitOK
+	}
+	var rows [][]string
+	for _, wp := range workspaces {
+		state := "in sync"
+		switch {
+		case wp.Primary:
+			state = "primary"
+		case len(wp.ParseErrors) > 0:
+			state = "parse error"
+		case wp.Drift != nil && !wp.Drift.InSync():
+			state = fmt.Sprintf("drift: %d missing, %d differ",
+				len(wp.Drift.MissingAccounts), len(wp.Drift.DifferentTokens))
+		case wp.Drift != nil && len(wp.Drift.ExtraAccounts)+len(wp.Drift.NewerTokens) > 0:
+			state = fmt.Sprintf("in sync, %d extra and %d newer kept", len(wp.Drift.ExtraAccounts), len(wp.Drift.NewerTokens))
+		case !wp.HasAuthProfiles:
+			state = "no credentials"
+		}
+		rows = append(rows, []string{wp.Path, wp.AgentID, strconv.Itoa(wp.ProfileCount), state})
+	}
+	c.table([]string{"PATH", "AGENT", "PROFILES", "STATE"}, rows)
+	return ExitOK
+}
+
+func runAgents(c *cmdContext, args []string) int {
+	fs := c.flags("agents")
+	if _, err := parse(fs, args); err != nil {
+		return ExitUsage
+	}
+	eng, err := c.engine()
+	if err != nil {
+		return c.fail(err)
+	}
+	agents := eng.GetAgents()
+	if c.json {
+		c.writeJSON(agents)
+		return ExitOK
+	}
+	var rows [][]string
+	for _, a := range agents {
+		rows = append(rows, []string{a.ID, a.Name, orDash(a.CurrentModel), a.Rule, a.Installation})
+	}
+	c.table([]string{"ID", "NAME", "MODEL", "RULE", "INSTALLATION"}, rows)
+	return ExitOK
+}
+
+func runConfig(c *cmdContext, args []string) int {
+	fs := c.flags("config")
+	pos, err := parse(fs, args)
+	if err != nil {
+		return ExitUsage
+	}
+	if len(pos) == 0 || (pos[0] != "get" && pos[0] != "set") {
+		return c.usage(commands["config"].usage)
+	}
+	eng, err := c.engine()
+	if err != nil {
+		return c.fail(err)
+	}
+
+	doc, err := toDocument(eng.Config())
+	if err != nil {
+		return c.fail(err)
+	}
+
+	if pos[0] == "get" {
+		if len(pos) > 2 {
+			return c.usage(commands["config"].usage)
+		}
+		path := ""
+		if len(pos) == 2 {
+			path = pos[1]
+		}
+		value, err := getPath(doc, path)
+		if err != nil {
+			return c.fail(err)
+		}
+		c.writeJSON(value)
+		return ExitOK
+	}
+
+	if len(pos) != 3 {
+		return c.usage(commands["config"].usage)
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(pos[2]), &value); err != nil {
+		value = pos[2] // not JSON: treat as a plain string
+	}
+	if err := setPath(doc, pos[1], value); err != nil {
+		return c.fail(err)
+	}
+	data, _ := json.Marshal(doc)
+	cfg, _, err := config.Parse(data)
+	if err != nil {
+		return c.fail(err)
+	}
+	if err := config.SaveConfig(cfg); err != nil {
+		return c.fail(err)
+	}
+	c.notifyLeader()
+	return c.ok("set " + pos[1])
+}
+
+// orDash shows empty values as "-" in tables.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func (c *cmdContext) ok(msg string) int {
+	if c.json {
+		c.writeJSON(map[string]interface{}{"ok": true, "message": msg})
+	} else {
+		fmt.Fprintln(c.stdout, msg)
+	}
+	return ExitOK
+}
+
+func toDocument(cfg *config.AppConfig) (interface{}, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	err = json.Unmarshal(data, &doc)
+	return doc, err
+}
+
+// getPath resolves a dotted path such as "rotator.quotas.low" or
+// "rotator.accounts.0" inside a JSON document.
+func getPath(doc interface{}, path string) (interface{}, error) {
+	if path == "" {
+		return doc, nil
+	}
+	cur := doc
+	for _, part := range strings.Split(path, ".") {
+		switch node := cur.(type) {
+		case map[string]interface{}:
+			v, ok := node[part]
+			if !ok {
+				return nil, fmt.Errorf("配置项不存在: %s", path)
+			}
+			cur = v
+		case []interface{}:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("无效的下标 %q: %s", part, path)
+			}
+			cur = node[i]
+		default:
+			return nil, fmt.Errorf("配置项不存在: %s", path)
+		}
+	}
+	return cur, nil
+}
+
+// setPath assigns value at a dotted path whose parent already exists.
+func setPath(doc interface{}, path string, value interface{}) error {
+	parts := strings.Split(path, ".")
+	parent, err := getPath(doc, strings.Join(parts[:len(parts)-1], "."))
+	if len(parts) == 1 {
+		parent, err = doc, nil
+	}
+	if err != nil {
+		return err
+	}
+	last := parts[len(parts)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+	case []interface{}:
+		i, err := strconv.Atoi(last)
+		if err != nil || i < 0 || i >= len(node) {
+			return fmt.Errorf("无效的下标 %q: %s", last, path)
+		}
+		node[i] = value
+	default:
+		return fmt.Errorf("无法设置配置项: %s", path)
+	}
+	return nil
+}
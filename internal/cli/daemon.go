@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"antigravity-rotator-v2/internal/config"
+	"antigravity-rotator-v2/internal/instance"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// runDaemon runs the engine headless as the leader instance until it is
+// interrupted. It refuses to start while a GUI or another daemon leads.
+func runDaemon(c *cmdContext, args []string) int {
+	fs := c.flags("daemon")
+	if _, err := parse(fs, args); err != nil {
+		return ExitUsage
+	}
+	eng, err := c.engine()
+	if err != nil {
+		return c.fail(err)
+	}
+
+	leader, other, err := instance.Acquire(config.DefaultOpenClawHome(), "daemon")
+	if err != nil {
+		return c.fail(err)
+	}
+	if other != nil {
+		return c.fail(fmt.Errorf("已有实例在运行 (%s, pid %d)", other.Role, other.PID))
+	}
+	defer leader.Release()
+
+	leader.Serve(instance.EngineAPI{RotatorEngine: eng})
+	eng.StartAutoLoop()
+	if err := eng.StartWatcher(); err != nil {
+		fmt.Fprintf(c.stderr, "warning: %v\n", err)
+	}
+	fmt.Fprintf(c.stdout, "daemon running (pid %d, api %s)\n", leader.PID, leader.Addr)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+
+	eng.StopWatcher()
+	eng.StopAutoLoop()
+	fmt.Fprintln(c.stdout, "daemon stopped")
+	return ExitOK
+}
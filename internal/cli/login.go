@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+func runLogin(c *cmdContext, args []string) int {
+	fs := c.flags("login")
+	noBrowser := fs.Bool("no-browser", false, "only print the login URL")
+	if _, err := parse(fs, args); err != nil {
+		return ExitUsage
+	}
+	eng, err := c.engine()
+	if err != nil {
+		return c.fail(err)
+	}
+
+	report, err := eng.LoginAccount(context.Background(), func(url string) {
+		fmt.Fprintf(c.stderr, "Open this URL to sign in with Google:\n\n  %s\n\nWaiting for the browser...\n", url)
+		if !*noBrowser {
+			openBrowser(url)
+		}
+	})
+	if err != nil {
+		return c.fail(err)
+	}
+	if report.Added+report.Updated > 0 {
+		c.notifyLeader()
+	}
+
+	if c.json {
+		c.writeJSON(report)
+		return ExitOK
+	}
+	var rows [][]string
+	for _, r := range report.Rows {
+		rows = append(rows, []string{orDash(r.Email), r.Status, r.Reason})
+	}
+	c.table([]string{"EMAIL", "STATUS", "REASON"}, rows)
+	return ExitOK
+}
+
+// openBrowser opens url in the default browser, best effort.
+func openBrowser(url string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	case "darwin":
+		cmd = exec.Command("open", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if cmd.Start() == nil {
+		go cmd.Wait()
+	}
+}
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"antigravity-rotator-v2/internal/google"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+func runModels(c *cmdContext, args []string) int {
+	fs := c.flags("models")
+	noRefresh := fs.Bool("no-refresh", false, "show the leader's cached catalog without querying Google")
+	pos, err := parse(fs, args)
+	if err != nil {
+		return ExitUsage
+	}
+	if len(pos) > 1 {
+		return c.usage(commands["models"].usage)
+	}
+	eng, err := c.engine()
+	if err != nil {
+		return c.fail(err)
+	}
+
+	var cats map[string]*google.Catalog
+	if l := c.leader(); l != nil {
+		if !*noRefresh {
+			if _, err := l.Status(true); err != nil {
+				return c.fail(err)
+			}
+		}
+		if cats, err = l.ModelCatalogs(); err != nil {
+			return c.fail(err)
+		}
+	} else {
+		if err := eng.RefreshStatus(); err != nil {
+			return c.fail(err)
+		}
+		cats = eng.ModelCatalogs()
+	}
+	if len(pos) == 1 {
+		cat, ok := cats[pos[0]]
+		if !ok {
+			return c.fail(fmt.Errorf("没有账号 %s 的模型目录", pos[0]))
+		}
+		cats = map[string]*google.Catalog{pos[0]: cat}
+	}
+
+	if c.json {
+		c.writeJSON(cats)
+		return ExitOK
+	}
+	emails := make([]string, 0, len(cats))
+	for email := range cats {
+		emails = append(emails, email)
+	}
+	sort.Strings(emails)
+	var rows [][]string
+	for _, email := range emails {
+		cat := cats[email]
+		for _, m := range cat.Models {
+			quota, reset, limits := "-", "-", ""
+			if m.Available {
+				quota = fmt.Sprintf("%d%%", m.Percent)
+				if !m.ResetTime.IsZero() {
+					reset = m.ResetTime.Local().Format("01-02 15:04")
+				}
+			}
+			if q, ok := cat.Display[m.Display]; ok && q.LimitedBy == m.ID {
+				limits = "*"
+			}
+			rows = append(rows, []string{email, m.ID, orDash(m.DisplayName), orDash(m.Display) + limits,
+				strconv.FormatBool(m.Available), quota, reset, orDash(maxTokens(m))})
+		}
+	}
+	c.table([]string{"ACCOUNT", "MODEL", "NAME", "COUNTS TOWARDS", "AVAILABLE", "QUOTA", "RESET", "TOKENS"}, rows)
+	fmt.Fprintln(c.stdout, "\n* the upstream model limiting the display model")
+	return ExitOK
+}
+
+func maxTokens(m google.CatalogModel) string {
+	if m.MaxTokens == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d/%d", m.MaxTokens, m.MaxOutputTokens)
+}
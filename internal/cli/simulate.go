@@ -0,0 +1,199 @@
+package cli
+
+import (
+	"antigravity-rotator-v2/internal/config"
+	"antigravity-rotator-v2/internal/engine"
+	"antigravity-rotator-v2/internal/trace"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// simRun is one policy variant of a simulate command and its outcome.
+type simRun struct {
+	Low      int              `json:"low"`
+	Interval int              `json:"rotateInterval"`
+	Tiered   bool             `json:"tiered"`
+	Balance  string           `json:"balance"`
+	Report   engine.SimReport `json:"report"`
+}
+
+func runSimulate(c *cmdContext, args []string) int {
+	fs := c.flags("simulate")
+	synthetic := fs.Bool("synthetic", false, "simulate made-up accounts instead of replaying a trace")
+	accounts := fs.Int("accounts", 0, "synthetic: number of accounts (default: the configured accounts, or 3)")
+	hours := fs.Float64("hours", 24, "synthetic: length of the run in hours")
+	resetHours := fs.Float64("reset-hours", 5, "synthetic: hours between quota resets")
+	stagger := fs.Bool("stagger", false, "synthetic: spread the accounts' resets instead of lining them up")
+	window := fs.Float64("window", 0, "refill each model this many hours after its first use (rolling quota windows)")
+	load := fs.Float64("load", 0, "percent per hour the active model consumes (synthetic default 20)")
+	lows := fs.String("low", "", "comma-separated thresholds to compare (default: config)")
+	intervals := fs.String("interval", "", "comma-separated rotate intervals in minutes to compare (default: config)")
+	tiered := fs.String("tiered", "", "true or false to override tiered polling")
+	balances := fs.String("balance", "", "comma-separated balance modes to compare: off, slice or weighted (default: config)")
+	slice := fs.Int("slice", 0, "quota percent per turn in slice mode (default: config)")
+	step := fs.Duration("step", time.Minute, "virtual clock resolution")
+	pos, err := parse(fs, args)
+	if err != nil || len(pos) > 1 || (*synthetic && len(pos) == 1) {
+		return c.usage(commands["simulate"].usage)
+	}
+	eng, err := c.engine()
+	if err != nil {
+		return c.fail(err)
+	}
+	rc := eng.Config().Rotator
+
+	var samples []trace.Sample
+	switch {
+	case *synthetic:
+		names := rc.Accounts
+		if *accounts > 0 || len(names) == 0 {
+			n := *accounts
+			if n <= 0 {
+				n = 3
+			}
+			names = make([]string, n)
+			for i := range names {
+				names[i] = fmt.Sprintf("account%d@example.com", i+1)
+			}
+		}
+		rc.Accounts = names
+		rc.DisabledAccounts = nil
+		resetEvery := time.Duration(*resetHours * float64(time.Hour))
+		if *window > 0 {
+			resetEvery = 0 // refills follow usage
+		}
+		samples = trace.Synthetic{
+			Accounts:   names,
+			Models:     rc.ModelPriority,
+			Start:      time.Now().Truncate(time.Hour),
+			Duration:   time.Duration(*hours * float64(time.Hour)),
+			ResetEvery: resetEvery,
+			Stagger:    *stagger,
+		}.Generate()
+		if *load == 0 {
+			*load = 20
+		}
+	case len(pos) == 1:
+		data, err := os.ReadFile(pos[0])
+		if err != nil {
+			return c.fail(err)
+		}
+		if samples, err = trace.Parse(pos[0], data); err != nil {
+			return c.fail(err)
+		}
+	default:
+		if samples, err = eng.QuotaTrace(); err != nil {
+			return c.fail(err)
+		}
+	}
+	opts := engine.SimOptions{Step: *step, Load: *load, ResetWindow: time.Duration(*window * float64(time.Hour))}
+	if *synthetic {
+		opts.Start = samples[0].Time
+		opts.End = opts.Start.Add(time.Duration(*hours * float64(time.Hour)))
+	}
+
+	if *tiered != "" {
+		if rc.Polling.Tiered, err = strconv.ParseBool(*tiered); err != nil {
+			return c.usage(commands["simulate"].usage)
+		}
+	}
+	lowList, err := intList(*lows, rc.Quotas.Low)
+	if err != nil {
+		return c.fail(fmt.Errorf("--low: %v", err))
+	}
+	intervalList, err := intList(*intervals, rc.RotateInterval)
+	if err != nil {
+		return c.fail(fmt.Errorf("--interval: %v", err))
+	}
+	balanceList := []string{rc.Balance.Mode}
+	if *balances != "" {
+		balanceList = nil
+		for _, f := range strings.Split(*balances, ",") {
+			mode := strings.TrimSpace(f)
+			switch mode {
+			case "off":
+				mode = config.BalanceOff
+			case config.BalanceSlice, config.BalanceWeighted:
+			default:
+				return c.fail(fmt.Errorf("--balance: 未知的均衡模式 %q", mode))
+			}
+			balanceList = append(balanceList, mode)
+		}
+	}
+	if *slice > 0 {
+		rc.Balance.Slice = *slice
+	}
+
+	var runs []simRun
+	for _, mode := range balanceList {
+		for _, low := range lowList {
+			for _, interval := range intervalList {
+				variant := rc
+				variant.Quotas.Low = low
+				variant.RotateInterval = interval
+				variant.Balance.Mode = mode
+				report, err := engine.Simulate(variant, samples, opts)
+				if err != nil {
+					return c.fail(err)
+				}
+				runs = append(runs, simRun{Low: low, Interval: interval, Tiered: variant.Polling.Tiered, Balance: balanceName(mode), Report: report})
+			}
+		}
+	}
+
+	if c.json {
+		c.writeJSON(runs)
+		return ExitOK
+	}
+	first := runs[0].Report
+	fmt.Fprintf(c.stdout, "Simulated %s to %s (%s) from %d samples, %d accounts\n\n",
+		first.Start.Local().Format("01-02 15:04"), first.End.Local().Format("01-02 15:04"),
+		first.End.Sub(first.Start), len(samples), len(rc.Accounts))
+	if len(runs) == 1 {
+		var rows [][]string
+		for _, sw := range first.Switches {
+			rows = append(rows, []string{sw.Time.Local().Format("01-02 15:04"), sw.Model, sw.Account, fmt.Sprintf("%d%%", sw.Quota)})
+		}
+		c.table([]string{"TIME", "MODEL", "ACCOUNT", "QUOTA"}, rows)
+		fmt.Fprintln(c.stdout)
+	}
+	var rows [][]string
+	for _, r := range runs {
+		rep := r.Report
+		rows = append(rows, []string{
+			r.Balance, strconv.Itoa(r.Low), fmt.Sprintf("%dm", r.Interval), strconv.FormatBool(r.Tiered),
+			strconv.Itoa(rep.Cycles), fmt.Sprintf("%d/%d", rep.ModelSwitches, rep.AccountSwitches),
+			rep.BelowThreshold.String(), rep.NoHealthy.String(), fmt.Sprintf("%.1f", rep.Spread),
+			strconv.Itoa(rep.Calls.Token + rep.Calls.Quota), strconv.Itoa(rep.Calls.Gateway),
+		})
+	}
+	c.table([]string{"BALANCE", "LOW", "INTERVAL", "TIERED", "CYCLES", "SWITCHES (MODEL/ACCOUNT)", "BELOW THRESHOLD", "NO HEALTHY", "SPREAD", "GOOGLE CALLS", "GATEWAY CALLS"}, rows)
+	return ExitOK
+}
+
+// balanceName shows the default mode as "off".
+func balanceName(mode string) string {
+	if mode == config.BalanceOff {
+		return "off"
+	}
+	return mode
+}
+
+// intList parses comma-separated integers, or returns def for "".
+func intList(s string, def int) ([]int, error) {
+	if s == "" {
+		return []int{def}, nil
+	}
+	var out []int
+	for _, f := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
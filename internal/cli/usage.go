@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"antigravity-rotator-v2/internal/engine"
+	"antigravity-rotator-v2/internal/usage"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func runUsage(c *cmdContext, args []string) int {
+	fs := c.flags("usage")
+	by := fs.String("by", usage.ByAccount, "group by account, agent or model")
+	period := fs.String("period", usage.Day, "day or week")
+	since := fs.String("since", "", "first day, YYYY-MM-DD (default: last 7 days, or 4 weeks)")
+	until := fs.String("until", "", "last day, YYYY-MM-DD (default: today)")
+	asCSV := fs.Bool("csv", false, "write the report as CSV")
+	output := fs.String("output", "", "write the CSV or JSON report to a file")
+	noIngest := fs.Bool("no-ingest", false, "report the stored usage without reading the logs first")
+	pos, err := parse(fs, args)
+	if err != nil {
+		return ExitUsage
+	}
+	eng, err := c.engine()
+	if err != nil {
+		return c.fail(err)
+	}
+
+	if len(pos) > 0 && pos[0] == "ingest" {
+		res, err := eng.IngestUsage(pos[1:])
+		if err != nil {
+			return c.fail(err)
+		}
+		if c.json {
+			c.writeJSON(res)
+		} else {
+			fmt.Fprintf(c.stdout, "Read %d requests from %d files: %d new, %d to other providers skipped\n",
+				res.Events, res.Files, res.Added, res.Foreign)
+		}
+		return ExitOK
+	}
+	if len(pos) > 0 {
+		return c.usage(commands["usage"].usage)
+	}
+
+	q := engine.UsageQuery{By: *by, Period: *period}
+	if *since != "" {
+		if q.From, err = time.ParseInLocation("2006-01-02", *since, time.Local); err != nil {
+			return c.fail(fmt.Errorf("--since: %v", err))
+		}
+	}
+	if *until != "" {
+		day, err := time.ParseInLocation("2006-01-02", *until, time.Local)
+		if err != nil {
+			return c.fail(fmt.Errorf("--until: %v", err))
+		}
+		q.To = day.AddDate(0, 0, 1)
+	}
+	if !*noIngest {
+		if _, err := eng.IngestUsage(nil); err != nil {
+			return c.fail(err)
+		}
+	}
+	report, err := eng.UsageReport(q)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	if *asCSV || c.json {
+		var buf bytes.Buffer
+		if *asCSV {
+			if err := usage.WriteCSV(&buf, report); err != nil {
+				return c.fail(err)
+			}
+		} else {
+			data, _ := json.MarshalIndent(report, "", "  ")
+			buf.Write(append(data, '\n'))
+		}
+		if *output == "" {
+			c.stdout.Write(buf.Bytes())
+			return ExitOK
+		}
+		if err := os.WriteFile(*output, buf.Bytes(), 0644); err != nil {
+			return c.fail(err)
+		}
+		if !c.json {
+			fmt.Fprintf(c.stdout, "Wrote %d rows to %s\n", len(report.Rows), *output)
+		}
+		return ExitOK
+	}
+
+	fmt.Fprintf(c.stdout, "Usage by %s per %s, %s to %s\n\n", report.By, report.Period,
+		report.From.Format("2006-01-02"), report.To.Add(-time.Second).Format("2006-01-02"))
+	var rows [][]string
+	for _, r := range report.Rows {
+		rows = append(rows, usageRow(r.Period.Format("2006-01-02"), r))
+	}
+	for _, r := range report.Totals {
+		rows = append(rows, usageRow("total", r))
+	}
+	all := report.Total
+	all.Key = "all"
+	rows = append(rows, usageRow("total", all))
+	c.table([]string{"PERIOD", strings.ToUpper(report.By), "REQUESTS", "ERRORS", "INPUT", "OUTPUT", "CACHE", "TOTAL TOKENS"}, rows)
+	return ExitOK
+}
+
+func usageRow(period string, r usage.Row) []string {
+	return []string{period, r.Key, strconv.Itoa(r.Requests), strconv.Itoa(r.Errors),
+		strconv.FormatInt(r.InputTokens, 10), strconv.FormatInt(r.OutputTokens, 10),
+		strconv.FormatInt(r.CacheTokens, 10), strconv.FormatInt(r.TotalTokens, 10)}
+}
@@ -0,0 +1,54 @@
+package config
+
+import "fmt"
+
+// Agent rule modes decide what SwitchModel does to an agent.
+const (
+	AgentDefault = "default" // no rule: inherit agents.defaults, keep an own model
+	AgentFollow  = "follow"  // track agents.defaults, replacing an own model
+	AgentPin     = "pin"     // always use Model
+	AgentRotate  = "rotate"  // best healthy model from its own Priority
+	AgentExclude = "exclude" // never touched by the rotator
+)
+
+// AgentRule is the per-agent model rotation rule, keyed by agent ID in
+// RotatorConfig.AgentRules.
+type AgentRule struct {
+	Mode     string   `json:"mode"`
+	Model    string   `json:"model,omitempty"`    // pin
+	Priority []string `json:"priority,omitempty"` // rotate
+}
+
+// AgentRuleFor returns the rule for an agent. Agents without one get
+// AgentDefault, so a deliberate per-agent model survives a switch unless the
+// user opts into follow.
+func (r RotatorConfig) AgentRuleFor(agentID string) AgentRule {
+	rule, ok := r.AgentRules[agentID]
+	if !ok || rule.Mode == "" {
+		rule.Mode = AgentDefault
+	}
+	return rule
+}
+
+func validateAgentRule(rule AgentRule) []string {
+	var msgs []string
+	switch rule.Mode {
+	case "", AgentDefault, AgentFollow, AgentExclude:
+	case AgentPin:
+		if rule.Model == "" {
+			msgs = append(msgs, "pin 模式需要指定模型")
+		}
+	case AgentRotate:
+		if len(rule.Priority) == 0 {
+			msgs = append(msgs, "rotate 模式至少需要一个模型")
+		}
+		for _, m := range rule.Priority {
+			if !isKnownModel(m) {
+				msgs = append(msgs, fmt.Sprintf("未知模型 %s", m))
+			}
+		}
+	default:
+		msgs = append(msgs, fmt.Sprintf("未知的规则模式 %q", rule.Mode))
+	}
+	return msgs
+}
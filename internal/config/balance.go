@@ -0,0 +1,44 @@
+package config
+
+// Balance modes.
+const (
+	BalanceOff      = ""         // the best account until it reaches the threshold
+	BalanceSlice    = "slice"    // move on after a fixed slice of quota
+	BalanceWeighted = "weighted" // pick accounts in proportion to quota left
+)
+
+// defaultSlice is the slice used when Slice is unset.
+const defaultSlice = 10
+
+// BalanceConfig spreads consumption over all accounts instead of draining
+// the best one down to the threshold, so that accounts degrade together and
+// reach their resets at different times. In slice mode the active account
+// is kept until it has used Slice percent of its quota, then the account
+// with the most quota left takes over. In weighted mode every cycle picks an
+// account in proportion to its quota above the threshold. Either way the
+// other healthy accounts follow in auth.order by quota left, and tiers are
+// not preferred.
+type BalanceConfig struct {
+	Mode  string `json:"mode,omitempty"`
+	Slice int    `json:"slice,omitempty"`
+}
+
+// SliceSize returns the quota percent an account uses per turn in slice
+// mode.
+func (b BalanceConfig) SliceSize() int {
+	if b.Slice <= 0 {
+		return defaultSlice
+	}
+	return b.Slice
+}
+
+func validateBalance(b BalanceConfig, add func(field, format string, args ...interface{})) {
+	switch b.Mode {
+	case BalanceOff, BalanceSlice, BalanceWeighted:
+	default:
+		add("rotator.balance.mode", "未知的均衡模式 %q (slice 或 weighted)", b.Mode)
+	}
+	if b.Slice < 0 || b.Slice > 100 {
+		add("rotator.balance.slice", "每轮配额份额必须在 1-100 之间")
+	}
+}
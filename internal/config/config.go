@@ -1,58 +1,230 @@
 package config
 
 import (
+	"antigravity-rotator-v2/internal/fsutil"
 	"encoding/json"
+	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
+// Proxy modes understood by the Google client.
+const (
+	ProxyNone   = "none"   // direct connection, ignore environment
+	ProxySystem = "system" // HTTP_PROXY / HTTPS_PROXY / NO_PROXY
+	ProxyHTTP   = "http"   // fixed HTTP(S) proxy
+	ProxySOCKS5 = "socks5" // fixed SOCKS5 proxy
+)
+
+// ProxyConfig describes how outgoing requests to Google are routed.
+// An empty Mode behaves like ProxySystem.
+type ProxyConfig struct {
+	Mode     string `json:"mode"`
+	URL      string `json:"url"` // host:port or full URL, used by http/socks5
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// ProxyURL builds the proxy URL for the http and socks5 modes.
+func (p ProxyConfig) ProxyURL() (*url.URL, error) {
+	raw := strings.TrimSpace(p.URL)
+	if raw == "" {
+		return nil, fmt.Errorf("代理地址为空")
+	}
+	if !strings.Contains(raw, "://") {
+		raw = p.Mode + "://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("代理地址无效: %v", err)
+	}
+	switch p.Mode {
+	case ProxyHTTP:
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("HTTP 代理不支持协议 %s", u.Scheme)
+		}
+	case ProxySOCKS5:
+		if u.Scheme != "socks5" && u.Scheme != "socks5h" {
+			return nil, fmt.Errorf("SOCKS5 代理不支持协议 %s", u.Scheme)
+		}
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("代理地址缺少主机: %s", p.URL)
+	}
+	if p.Username != "" {
+		u.User = url.UserPassword(p.Username, p.Password)
+	}
+	return u, nil
+}
+
 type RotatorConfig struct {
-	Accounts      []string `json:"accounts"`
-	ModelPriority []string `json:"modelPriority"`
-	Quotas        struct {
+	Accounts []string `json:"accounts"`
+	// DisabledAccounts are kept but skipped by polling and rotation.
+	DisabledAccounts []string `json:"disabledAccounts,omitempty"`
+	ModelPriority    []string `json:"modelPriority"`
+	Quotas           struct {
 		Low int `json:"low"`
 	} `json:"quotas"`
-	AutoRotate     bool `json:"autoRotate"`
-	RotateInterval int  `json:"rotateInterval"` // in minutes
-	OpenClawBin    string `json:"openclawBin"`
-	Proxy          string `json:"proxy"`
+	AutoRotate     bool        `json:"autoRotate"`
+	RotateInterval int         `json:"rotateInterval"` // in minutes
+	OpenClawBin    string      `json:"openclawBin"`
+	Proxy          ProxyConfig `json:"proxy"`
+	// AccountProxies overrides Proxy per account email, so accounts can
+	// egress from different IPs.
+	AccountProxies map[string]ProxyConfig `json:"accountProxies,omitempty"`
+	// AccountTags are free-form labels per account email.
+	AccountTags map[string][]string `json:"accountTags,omitempty"`
+	// SyncExclude lists workspaces (by path or agent name) that credential
+	// sync must leave untouched.
+	SyncExclude []string `json:"syncExclude,omitempty"`
+	// AutoSync re-syncs agent workspaces whenever the primary auth file is
+	// changed by someone else (e.g. the gateway refreshing a token).
+	AutoSync bool `json:"autoSync"`
+	// OpenClawHome relocates the OpenClaw home; the --openclaw-home flag
+	// and $OPENCLAW_STATE_DIR take precedence.
+	OpenClawHome string `json:"openclawHome,omitempty"`
+	// Installations lists several OpenClaw instances to manage together.
+	// When empty, the single instance at OpenClawHome is used.
+	Installations []Installation `json:"installations,omitempty"`
+	// AgentRules overrides how model switches apply to individual agents;
+	// agents without a rule follow the defaults.
+	AgentRules map[string]AgentRule `json:"agentRules,omitempty"`
+	// Triggers rotate early on gateway quota errors or external signals.
+	Triggers TriggerConfig `json:"triggers"`
+	// Polling sets how often quotas are refreshed per account.
+	Polling PollingConfig `json:"polling"`
+	// Balance spreads consumption evenly over the accounts.
+	Balance BalanceConfig `json:"balance"`
+	// RecordTrace appends every quota poll to a trace file that the
+	// simulator can replay.
+	RecordTrace bool `json:"recordTrace,omitempty"`
+	// Schedules override the model priority, threshold and auto-rotation
+	// at certain times; the first matching schedule wins.
+	Schedules []Schedule `json:"schedules,omitempty"`
 }
 
 type AppConfig struct {
-	Rotator RotatorConfig `json:"rotator"`
+	Version  int           `json:"version"`
+	Rotator  RotatorConfig `json:"rotator"`
 	LastScan []string      `json:"lastScan"`
 }
 
+// GetConfigPath returns the rotator config file: $ANTIGRAVITY_ROTATOR_CONFIG,
+// or antigravity-rotator-v2.json in the OpenClaw home.
 func GetConfigPath() string {
-	home, _ := os.UserHomeDir()
-	return filepath.Join(home, ".openclaw", "antigravity-rotator-v2.json")
+	if p := os.Getenv(EnvConfigPath); p != "" {
+		return p
+	}
+	return filepath.Join(DefaultOpenClawHome(), "antigravity-rotator-v2.json")
+}
+
+// DefaultConfig returns the configuration used when no file exists yet.
+func DefaultConfig() *AppConfig {
+	cfg := &AppConfig{Version: CurrentVersion}
+	cfg.Rotator.Quotas.Low = 21
+	cfg.Rotator.RotateInterval = 10
+	cfg.Rotator.ModelPriority = []string{"google-antigravity/gemini-3-pro-high", "google-antigravity/gemini-3-flash"}
+	cfg.Rotator.Polling = DefaultPolling()
+	return cfg
 }
 
+// CorruptConfigError is returned by LoadConfig when the config file could not
+// be parsed. The broken file has been moved to Backup and defaults are in use.
+type CorruptConfigError struct {
+	Path   string
+	Backup string
+	Err    error
+}
+
+func (e *CorruptConfigError) Error() string {
+	return fmt.Sprintf("配置文件 %s 已损坏 (%v)，已备份至 %s 并恢复默认配置", e.Path, e.Err, e.Backup)
+}
+
+func (e *CorruptConfigError) Unwrap() error { return e.Err }
+
+// LoadConfig reads, migrates and validates the config file. A file that
+// cannot be parsed is backed up and replaced by defaults (reported as
+// *CorruptConfigError); a file that parses but fails validation is returned
+// together with its ValidationErrors, and a valid one whose migration changed
+// settings with its MigrationNotices.
 func LoadConfig() (*AppConfig, error) {
 	path := GetConfigPath()
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		// Default config
-		cfg := &AppConfig{}
-		cfg.Rotator.Quotas.Low = 21
-		cfg.Rotator.ModelPriority = []string{"google-antigravity/gemini-3-pro-high", "google-antigravity/gemini-3-flash"}
-		return cfg, nil
-	}
-
 	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return DefaultConfig(), nil
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	cfg, notices, err := Parse(data)
+	if err != nil {
+		backup := fmt.Sprintf("%s.corrupt-%s", path, time.Now().Format("20060102-150405"))
+		if rerr := os.Rename(path, backup); rerr != nil {
+			return DefaultConfig(), fmt.Errorf("配置文件已损坏且备份失败: %v (%v)", err, rerr)
+		}
+		return DefaultConfig(), &CorruptConfigError{Path: path, Backup: backup, Err: err}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return cfg, err
+	}
+	if len(notices) > 0 {
+		return cfg, MigrationNotices(notices)
+	}
+	return cfg, nil
+}
+
+// Parse decodes a config document of any supported version, migrating it to
+// CurrentVersion. It also returns the notices of the migration.
+func Parse(data []byte) (*AppConfig, []string, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, nil, fmt.Errorf("解析配置失败: %v", err)
+	}
+	if raw == nil {
+		return nil, nil, fmt.Errorf("配置内容为空")
+	}
+	notices, err := Migrate(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	migrated, _ := json.Marshal(raw)
 	var cfg AppConfig
-	err = json.Unmarshal(data, &cfg)
-	return &cfg, err
+	if err := json.Unmarshal(migrated, &cfg); err != nil {
+		return nil, nil, fmt.Errorf("解析配置失败: %v", err)
+	}
+	return &cfg, notices, nil
 }
 
+// SaveConfig validates cfg and writes it to disk.
 func SaveConfig(cfg *AppConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	cfg.Version = CurrentVersion
+
 	path := GetConfigPath()
 	data, err := json.MarshalIndent(cfg, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0644)
+	return fsutil.WithLock(path, func() error {
+		if _, err := fsutil.Backup(path); err != nil {
+			return err
+		}
+		return fsutil.WriteFileAtomic(path, data, 0644)
+	})
+}
+
+// Clone returns a deep copy of the configuration.
+func (c *AppConfig) Clone() *AppConfig {
+	data, _ := json.Marshal(c)
+	var out AppConfig
+	json.Unmarshal(data, &out)
+	return &out
 }
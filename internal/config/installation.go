@@ -0,0 +1,120 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Environment variables understood by the rotator. OPENCLAW_STATE_DIR is the
+// variable OpenClaw itself uses to relocate its home.
+const (
+	EnvOpenClawHome = "OPENCLAW_STATE_DIR"
+	EnvConfigPath   = "ANTIGRAVITY_ROTATOR_CONFIG"
+)
+
+// homeOverride is set from the --openclaw-home flag and beats everything else.
+var homeOverride string
+
+// SetOpenClawHome overrides the OpenClaw home for this process.
+func SetOpenClawHome(dir string) {
+	homeOverride = dir
+}
+
+// explicitHome returns the home given by flag or environment, if any.
+func explicitHome() string {
+	if homeOverride != "" {
+		return homeOverride
+	}
+	return os.Getenv(EnvOpenClawHome)
+}
+
+// DefaultOpenClawHome resolves the OpenClaw home from the flag, the
+// environment or ~/.openclaw, in that order. The rotator config lives here.
+func DefaultOpenClawHome() string {
+	if h := explicitHome(); h != "" {
+		return h
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".openclaw")
+}
+
+// Installation is one OpenClaw instance managed by the rotator, with its own
+// home directory, gateway binary and auth file.
+type Installation struct {
+	Name        string `json:"name"`
+	Home        string `json:"home"`
+	OpenClawBin string `json:"openclawBin,omitempty"`
+	// AuthFile defaults to agents/main/agent/auth-profiles.json or
+	// agents/main/auth-profiles.json, falling back to
+	// <home>/auth-profiles.json. Relative paths are under Home.
+	AuthFile string `json:"authFile,omitempty"`
+	// custom marks homes that differ from what the openclaw CLI would pick
+	// up on its own, so gateway calls must point it at Home explicitly.
+	custom bool
+}
+
+// Custom reports whether gateway commands need to be pointed at Home.
+func (i Installation) Custom() bool { return i.custom }
+
+// Bin returns the gateway binary for this installation.
+func (i Installation) Bin() string {
+	if i.OpenClawBin != "" {
+		return i.OpenClawBin
+	}
+	return "openclaw"
+}
+
+// AuthPath returns the primary auth-profiles.json of the installation.
+func (i Installation) AuthPath() string {
+	if i.AuthFile != "" {
+		if filepath.IsAbs(i.AuthFile) {
+			return i.AuthFile
+		}
+		return filepath.Join(i.Home, i.AuthFile)
+	}
+	for _, dir := range []string{filepath.Join("agents", "main", "agent"), filepath.Join("agents", "main")} {
+		mainAgentAuth := filepath.Join(i.Home, dir, "auth-profiles.json")
+		if _, err := os.Stat(mainAgentAuth); err == nil {
+			return mainAgentAuth
+		}
+	}
+	return filepath.Join(i.Home, "auth-profiles.json")
+}
+
+// GatewayConfigPath returns the installation's openclaw.json.
+func (i Installation) GatewayConfigPath() string {
+	return filepath.Join(i.Home, "openclaw.json")
+}
+
+// ResolvedInstallations returns the installations the rotator manages. The
+// first one is primary: its auth file is the source of truth for refreshing
+// quotas. Without explicit installations a single "default" one is derived
+// from the flag, environment, openclawHome and openclawBin settings.
+func (c *AppConfig) ResolvedInstallations() []Installation {
+	r := c.Rotator
+	if len(r.Installations) > 0 {
+		out := make([]Installation, len(r.Installations))
+		for i, inst := range r.Installations {
+			if inst.OpenClawBin == "" {
+				inst.OpenClawBin = r.OpenClawBin
+			}
+			inst.custom = true
+			out[i] = inst
+		}
+		return out
+	}
+
+	inst := Installation{Name: "default", OpenClawBin: r.OpenClawBin}
+	switch {
+	case explicitHome() != "":
+		inst.Home = explicitHome()
+		// The flag is not seen by openclaw, the environment variable is.
+		inst.custom = homeOverride != ""
+	case r.OpenClawHome != "":
+		inst.Home = r.OpenClawHome
+		inst.custom = true
+	default:
+		inst.Home = DefaultOpenClawHome()
+	}
+	return []Installation{inst}
+}
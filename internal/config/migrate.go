@@ -0,0 +1,102 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CurrentVersion is the config schema version written by this build.
+//
+//	0: original unversioned format, proxy stored as a plain URL string
+//	1: proxy object, explicit rotateInterval default
+//	2: tiered quota polling, on by default
+const CurrentVersion = 2
+
+// migrations[i] upgrades a raw document from version i to i+1, noting any
+// setting it had to change.
+var migrations = []func(raw map[string]interface{}, notices *[]string) error{
+	migrateV0,
+	migrateV1,
+}
+
+// MigrationNotices lists the settings a migration changed rather than just
+// converted. LoadConfig returns it alongside a usable config.
+type MigrationNotices []string
+
+func (n MigrationNotices) Error() string {
+	return "配置已迁移: " + strings.Join(n, "; ")
+}
+
+// Migrate upgrades a raw config document in place to CurrentVersion and
+// returns notices for the settings it changed.
+func Migrate(raw map[string]interface{}) ([]string, error) {
+	version := 0
+	if v, ok := raw["version"].(float64); ok {
+		version = int(v)
+	}
+	if version > CurrentVersion {
+		return nil, fmt.Errorf("配置版本 %d 高于当前支持的版本 %d", version, CurrentVersion)
+	}
+	var notices []string
+	for ; version < CurrentVersion; version++ {
+		if err := migrations[version](raw, &notices); err != nil {
+			return nil, fmt.Errorf("配置迁移 v%d -> v%d 失败: %v", version, version+1, err)
+		}
+	}
+	raw["version"] = CurrentVersion
+	return notices, nil
+}
+
+func migrateV0(raw map[string]interface{}, notices *[]string) error {
+	rotator, ok := raw["rotator"].(map[string]interface{})
+	if !ok {
+		if raw["rotator"] != nil {
+			return fmt.Errorf("rotator 字段格式错误")
+		}
+		rotator = make(map[string]interface{})
+		raw["rotator"] = rotator
+	}
+
+	// "proxy": "http://127.0.0.1:7890" -> {"mode": "http", "url": ...}
+	switch p := rotator["proxy"].(type) {
+	case nil:
+	case string:
+		if p == "" {
+			delete(rotator, "proxy")
+			break
+		}
+		mode := ProxyHTTP
+		if strings.HasPrefix(p, "socks5") {
+			mode = ProxySOCKS5
+		}
+		rotator["proxy"] = map[string]interface{}{"mode": mode, "url": p}
+	case map[string]interface{}:
+	default:
+		return fmt.Errorf("proxy 字段格式错误")
+	}
+
+	// Unversioned configs were written with rotateInterval 0 until the
+	// frontend first set it; 10 is what the UI has always assumed.
+	if v, _ := rotator["rotateInterval"].(float64); v <= 0 {
+		rotator["rotateInterval"] = 10
+		*notices = append(*notices, fmt.Sprintf("rotateInterval %v 无效，已改为 10 分钟", v))
+	}
+	return nil
+}
+
+func migrateV1(raw map[string]interface{}, notices *[]string) error {
+	rotator, ok := raw["rotator"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("rotator 字段格式错误")
+	}
+	if _, ok := rotator["polling"]; !ok {
+		p := DefaultPolling()
+		rotator["polling"] = map[string]interface{}{
+			"tiered":      p.Tiered,
+			"hotSeconds":  p.HotSeconds,
+			"candidates":  p.Candidates,
+			"idleMinutes": p.IdleMinutes,
+		}
+	}
+	return nil
+}
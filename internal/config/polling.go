@@ -0,0 +1,33 @@
+package config
+
+// PollingConfig controls how often each account's quota is refreshed. With
+// Tiered set, the active account and the next Candidates accounts are polled
+// every HotSeconds, other healthy accounts every IdleMinutes, and exhausted
+// accounts only once their quota resets. Otherwise every account is
+// refreshed on each rotation cycle.
+type PollingConfig struct {
+	Tiered      bool `json:"tiered"`
+	HotSeconds  int  `json:"hotSeconds"`
+	Candidates  int  `json:"candidates"`
+	IdleMinutes int  `json:"idleMinutes"`
+}
+
+// DefaultPolling returns the polling settings of new configs.
+func DefaultPolling() PollingConfig {
+	return PollingConfig{Tiered: true, HotSeconds: 120, Candidates: 2, IdleMinutes: 30}
+}
+
+func validatePolling(p PollingConfig, add func(field, format string, args ...interface{})) {
+	if !p.Tiered {
+		return
+	}
+	if p.HotSeconds < 30 || p.HotSeconds > 3600 {
+		add("rotator.polling.hotSeconds", "活跃账号轮询间隔必须在 30-3600 秒之间")
+	}
+	if p.Candidates < 0 {
+		add("rotator.polling.candidates", "候选账号数不能为负数")
+	}
+	if p.IdleMinutes < 1 || p.IdleMinutes > 1440 {
+		add("rotator.polling.idleMinutes", "空闲账号轮询间隔必须在 1-1440 分钟之间")
+	}
+}
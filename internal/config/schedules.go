@@ -0,0 +1,89 @@
+package config
+
+import (
+	"antigravity-rotator-v2/internal/schedule"
+	"fmt"
+	"time"
+)
+
+// Schedule overrides rotation settings during the minutes matched by its
+// cron expression. Unset overrides keep the base settings.
+type Schedule struct {
+	Name string `json:"name"`
+	// Cron selects the minutes the schedule is in effect, e.g.
+	// "* 9-17 * * 1-5" for working hours.
+	Cron          string   `json:"cron"`
+	ModelPriority []string `json:"modelPriority,omitempty"`
+	Low           *int     `json:"low,omitempty"`
+	// AutoRotate false pauses automatic rotation, e.g. for deploy windows.
+	AutoRotate *bool `json:"autoRotate,omitempty"`
+}
+
+// Policy is the effective rotation settings at a point in time.
+type Policy struct {
+	Schedule      string   `json:"schedule"` // empty when no schedule matches
+	ModelPriority []string `json:"modelPriority"`
+	Low           int      `json:"low"`
+	AutoRotate    bool     `json:"autoRotate"`
+}
+
+// PolicyAt applies the first schedule matching t to the base settings.
+func (r RotatorConfig) PolicyAt(t time.Time) Policy {
+	p := Policy{ModelPriority: r.ModelPriority, Low: r.Quotas.Low, AutoRotate: r.AutoRotate}
+	for _, s := range r.Schedules {
+		spec, err := schedule.Parse(s.Cron)
+		if err != nil || !spec.Matches(t) {
+			continue
+		}
+		p.Schedule = s.Name
+		if len(s.ModelPriority) > 0 {
+			p.ModelPriority = s.ModelPriority
+		}
+		if s.Low != nil {
+			p.Low = *s.Low
+		}
+		if s.AutoRotate != nil {
+			p.AutoRotate = *s.AutoRotate
+		}
+		break
+	}
+	return p
+}
+
+// MayAutoRotate reports whether automatic rotation can be on at any time,
+// i.e. the base setting or some schedule enables it.
+func (r RotatorConfig) MayAutoRotate() bool {
+	if r.AutoRotate {
+		return true
+	}
+	for _, s := range r.Schedules {
+		if s.AutoRotate != nil && *s.AutoRotate {
+			return true
+		}
+	}
+	return false
+}
+
+func validateSchedules(list []Schedule, add func(field, format string, args ...interface{})) {
+	seen := make(map[string]bool)
+	for i, s := range list {
+		field := fmt.Sprintf("rotator.schedules[%d]", i)
+		if s.Name == "" {
+			add(field+".name", "计划名称不能为空")
+		} else if seen[s.Name] {
+			add(field+".name", "计划 %s 重复", s.Name)
+		}
+		seen[s.Name] = true
+		if _, err := schedule.Parse(s.Cron); err != nil {
+			add(field+".cron", "%v", err)
+		}
+		for j, m := range s.ModelPriority {
+			if !isKnownModel(m) {
+				add(fmt.Sprintf("%s.modelPriority[%d]", field, j), "未知模型 %s", m)
+			}
+		}
+		if s.Low != nil && (*s.Low < 0 || *s.Low > 100) {
+			add(field+".low", "阈值必须在 0-100 之间")
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"net"
+	"regexp"
+)
+
+// DefaultQuotaErrorPattern matches quota and rate-limit errors in gateway logs.
+const DefaultQuotaErrorPattern = `(?i)(\b429\b|RESOURCE_EXHAUSTED|quota (exceeded|exhausted)|rate.?limit)`
+
+// TriggerConfig enables rotations on signals besides the RotateInterval
+// timer. Triggered rotations are debounced and rate-limited by Cooldown.
+type TriggerConfig struct {
+	// GatewayLog is a gateway log file tailed for quota errors.
+	GatewayLog string `json:"gatewayLog,omitempty"`
+	// LogPattern overrides DefaultQuotaErrorPattern.
+	LogPattern string `json:"logPattern,omitempty"`
+	// WebhookAddr, e.g. "127.0.0.1:18790", accepts POST /trigger.
+	WebhookAddr string `json:"webhookAddr,omitempty"`
+	// WebhookToken, if set, must be sent as "Authorization: Bearer <token>".
+	WebhookToken string `json:"webhookToken,omitempty"`
+	// ActiveCheckSeconds re-checks only the active account this often
+	// between full refreshes; 0 disables it.
+	ActiveCheckSeconds int `json:"activeCheckSeconds,omitempty"`
+	// CooldownSeconds is the minimum time between triggered rotations
+	// (default 60).
+	CooldownSeconds int `json:"cooldownSeconds,omitempty"`
+}
+
+// Pattern returns the compiled log pattern.
+func (t TriggerConfig) Pattern() (*regexp.Regexp, error) {
+	if t.LogPattern == "" {
+		return regexp.Compile(DefaultQuotaErrorPattern)
+	}
+	return regexp.Compile(t.LogPattern)
+}
+
+// Cooldown returns CooldownSeconds with its default applied.
+func (t TriggerConfig) Cooldown() int {
+	if t.CooldownSeconds <= 0 {
+		return 60
+	}
+	return t.CooldownSeconds
+}
+
+func validateTriggers(t TriggerConfig, add func(field, format string, args ...interface{})) {
+	if _, err := t.Pattern(); err != nil {
+		add("rotator.triggers.logPattern", "无效的正则表达式: %v", err)
+	}
+	if t.WebhookAddr != "" {
+		if _, _, err := net.SplitHostPort(t.WebhookAddr); err != nil {
+			add("rotator.triggers.webhookAddr", "无效的监听地址: %v", err)
+		}
+	}
+	if t.ActiveCheckSeconds != 0 && t.ActiveCheckSeconds < 10 {
+		add("rotator.triggers.activeCheckSeconds", "检查间隔至少为 10 秒")
+	}
+	if t.CooldownSeconds < 0 {
+		add("rotator.triggers.cooldownSeconds", "冷却时间不能为负数")
+	}
+}
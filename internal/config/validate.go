@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// KnownModels lists the display model IDs the rotator can report quota for.
+var KnownModels = []string{
+	"google-antigravity/gemini-3-pro-high",
+	"google-antigravity/gemini-3-flash",
+	"google-antigravity/gemini-3-image",
+	"google-antigravity/claude-sonnet-4-5-thinking",
+}
+
+// FieldError is a validation failure of a single config field. Field is a
+// JSON path such as "rotator.modelPriority[1]".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors collects every FieldError found by Validate.
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	parts := make([]string, len(v))
+	for i, fe := range v {
+		parts[i] = fe.Field + ": " + fe.Message
+	}
+	return "配置校验失败: " + strings.Join(parts, "; ")
+}
+
+func isKnownModel(id string) bool {
+	for _, m := range KnownModels {
+		if m == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the configuration and returns ValidationErrors, or nil.
+func (c *AppConfig) Validate() error {
+	var errs ValidationErrors
+	add := func(field, format string, args ...interface{}) {
+		errs = append(errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	r := c.Rotator
+	if r.RotateInterval < 1 || r.RotateInterval > 1440 {
+		add("rotator.rotateInterval", "轮换间隔必须在 1-1440 分钟之间")
+	}
+	if r.Quotas.Low < 0 || r.Quotas.Low > 100 {
+		add("rotator.quotas.low", "阈值必须在 0-100 之间")
+	}
+
+	if len(r.ModelPriority) == 0 {
+		add("rotator.modelPriority", "至少需要一个模型")
+	}
+	seenModels := make(map[string]bool)
+	for i, m := range r.ModelPriority {
+		field := fmt.Sprintf("rotator.modelPriority[%d]", i)
+		if !isKnownModel(m) {
+			add(field, "未知模型 %s", m)
+		}
+		if seenModels[m] {
+			add(field, "模型 %s 重复", m)
+		}
+		seenModels[m] = true
+	}
+
+	seenAccounts := make(map[string]bool)
+	for i, a := range r.Accounts {
+		field := fmt.Sprintf("rotator.accounts[%d]", i)
+		if !strings.Contains(a, "@") {
+			add(field, "无效的邮箱 %q", a)
+		}
+		if seenAccounts[a] {
+			add(field, "账号 %s 重复", a)
+		}
+		seenAccounts[a] = true
+	}
+
+	if msg := validateProxy(r.Proxy); msg != "" {
+		add("rotator.proxy", "%s", msg)
+	}
+	for email, p := range r.AccountProxies {
+		if msg := validateProxy(p); msg != "" {
+			add(fmt.Sprintf("rotator.accountProxies[%s]", email), "%s", msg)
+		}
+	}
+
+	seenInst := make(map[string]bool)
+	for i, inst := range r.Installations {
+		field := fmt.Sprintf("rotator.installations[%d]", i)
+		if inst.Name == "" {
+			add(field+".name", "安装实例名称不能为空")
+		} else if seenInst[inst.Name] {
+			add(field+".name", "安装实例 %s 重复", inst.Name)
+		}
+		seenInst[inst.Name] = true
+		if inst.Home == "" {
+			add(field+".home", "安装实例目录不能为空")
+		}
+	}
+
+	validateTriggers(r.Triggers, add)
+	validatePolling(r.Polling, add)
+	validateBalance(r.Balance, add)
+	validateSchedules(r.Schedules, add)
+
+	for id, rule := range r.AgentRules {
+		for _, msg := range validateAgentRule(rule) {
+			add(fmt.Sprintf("rotator.agentRules[%s]", id), "%s", msg)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateProxy(p ProxyConfig) string {
+	switch p.Mode {
+	case "", ProxyNone, ProxySystem:
+		return ""
+	case ProxyHTTP, ProxySOCKS5:
+		if _, err := p.ProxyURL(); err != nil {
+			return err.Error()
+		}
+		return ""
+	}
+	return fmt.Sprintf("未知的代理模式 %q", p.Mode)
+}
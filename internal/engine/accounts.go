@@ -0,0 +1,167 @@
+package engine
+
+import (
+	"antigravity-rotator-v2/internal/config"
+	"fmt"
+	"strings"
+)
+
+// AccountSummary is one managed account as listed by the CLI and UI.
+type AccountSummary struct {
+	Email    string         `json:"email"`
+	Disabled bool           `json:"disabled"`
+	Tags     []string       `json:"tags"`
+	Quotas   map[string]int `json:"quotas"`
+}
+
+func (e *RotatorEngine) isDisabled(email string) bool {
+	for _, d := range e.Config().Rotator.DisabledAccounts {
+		if d == email {
+			return true
+		}
+	}
+	return false
+}
+
+// ListAccounts returns every managed account with its last known quotas.
+func (e *RotatorEngine) ListAccounts() []AccountSummary {
+	return SummarizeAccounts(e.Config(), e.StatusSnapshot())
+}
+
+// SummarizeAccounts groups an email:model status map by account.
+func SummarizeAccounts(cfg *config.AppConfig, status map[string]int) []AccountSummary {
+	disabled := make(map[string]bool)
+	for _, d := range cfg.Rotator.DisabledAccounts {
+		disabled[d] = true
+	}
+
+	out := []AccountSummary{}
+	for _, email := range cfg.Rotator.Accounts {
+		acc := AccountSummary{Email: email, Disabled: disabled[email], Tags: cfg.Rotator.AccountTags[email], Quotas: make(map[string]int)}
+		if acc.Tags == nil {
+			acc.Tags = []string{}
+		}
+		prefix := email + ":"
+		for key, q := range status {
+			if strings.HasPrefix(key, prefix) {
+				acc.Quotas[strings.TrimPrefix(key, prefix)] = q
+			}
+		}
+		out = append(out, acc)
+	}
+	return out
+}
+
+// StatusSnapshot returns a copy of the quota status map.
+func (e *RotatorEngine) StatusSnapshot() map[string]int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	out := make(map[string]int, len(e.Status))
+	for k, v := range e.Status {
+		out[k] = v
+	}
+	return out
+}
+
+func (e *RotatorEngine) hasAccount(email string) bool {
+	for _, a := range e.Config().Rotator.Accounts {
+		if a == email {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateConfig saves and applies a modified copy of the running config.
+func (e *RotatorEngine) UpdateConfig(fn func(cfg *config.AppConfig)) error {
+	cfg := e.Config().Clone()
+	fn(cfg)
+	if err := config.SaveConfig(cfg); err != nil {
+		return err
+	}
+	_, err := e.ApplyConfig(cfg)
+	return err
+}
+
+// SetAccountDisabled excludes an account from rotation (and quota polling)
+// without removing its credentials.
+func (e *RotatorEngine) SetAccountDisabled(email string, disabled bool) error {
+	if !e.hasAccount(email) {
+		return fmt.Errorf("账号不存在: %s", email)
+	}
+	return e.UpdateConfig(func(cfg *config.AppConfig) {
+		var list []string
+		for _, d := range cfg.Rotator.DisabledAccounts {
+			if d != email {
+				list = append(list, d)
+			}
+		}
+		if disabled {
+			list = append(list, email)
+		}
+		cfg.Rotator.DisabledAccounts = list
+	})
+}
+
+// SetAccountTags replaces the tags of an account; no tags clears them.
+func (e *RotatorEngine) SetAccountTags(email string, tags []string) error {
+	if !e.hasAccount(email) {
+		return fmt.Errorf("账号不存在: %s", email)
+	}
+	var clean []string
+	for _, t := range tags {
+		if t = strings.TrimSpace(t); t != "" && !containsString(clean, t) {
+			clean = append(clean, t)
+		}
+	}
+	return e.UpdateConfig(func(cfg *config.AppConfig) {
+		if len(clean) == 0 {
+			delete(cfg.Rotator.AccountTags, email)
+			return
+		}
+		if cfg.Rotator.AccountTags == nil {
+			cfg.Rotator.AccountTags = make(map[string][]string)
+		}
+		cfg.Rotator.AccountTags[email] = clean
+	})
+}
+
+// RemoveAccount stops managing an account. With purge, its profile is also
+// deleted from every installation's primary auth file.
+func (e *RotatorEngine) RemoveAccount(email string, purge bool) error {
+	if !e.hasAccount(email) {
+		return fmt.Errorf("账号不存在: %s", email)
+	}
+	if purge {
+		profileKey := "google-antigravity:" + email
+		for _, inst := range e.installations() {
+			err := e.updateJSON(inst.AuthPath(), func(authData map[string]interface{}) (bool, error) {
+				profiles, _ := authData["profiles"].(map[string]interface{})
+				if _, ok := profiles[profileKey]; !ok {
+					return false, nil
+				}
+				delete(profiles, profileKey)
+				return true, nil
+			})
+			if err != nil {
+				return fmt.Errorf("[%s] 删除凭据失败: %v", inst.Name, err)
+			}
+		}
+	}
+	return e.UpdateConfig(func(cfg *config.AppConfig) {
+		var accounts, disabled []string
+		for _, a := range cfg.Rotator.Accounts {
+			if a != email {
+				accounts = append(accounts, a)
+			}
+		}
+		for _, d := range cfg.Rotator.DisabledAccounts {
+			if d != email {
+				disabled = append(disabled, d)
+			}
+		}
+		cfg.Rotator.Accounts = accounts
+		cfg.Rotator.DisabledAccounts = disabled
+		delete(cfg.Rotator.AccountTags, email)
+	})
+}
@@ -0,0 +1,268 @@
+package engine
+
+import (
+	"antigravity-rotator-v2/internal/config"
+	"fmt"
+	"sort"
+)
+
+// Actions a model switch takes on an agent.
+const (
+	AgentPatch   = "patch"   // the agent's own model is rewritten
+	AgentInherit = "inherit" // no override, picks up agents.defaults
+	AgentKeep    = "keep"    // left as is
+)
+
+// AgentModelChange describes what a model switch does to one agent.
+type AgentModelChange struct {
+	Installation string `json:"installation,omitempty"`
+	AgentID      string `json:"agentId"`
+	Name         string `json:"name"`
+	Mode         string `json:"mode"`
+	Action       string `json:"action"`
+	From         string `json:"from"`
+	To           string `json:"to"`
+	// Fallbacks is the new fallback list for agents with an object model.
+	Fallbacks []string `json:"fallbacks,omitempty"`
+	Reason    string   `json:"reason,omitempty"`
+}
+
+// agentModel returns the agent's own primary model, or "" if it inherits
+// agents.defaults.
+func agentModel(agent map[string]interface{}) string {
+	return modelPrimary(agent["model"])
+}
+
+// modelPrimary returns the primary of a string or object model entry.
+func modelPrimary(model interface{}) string {
+	if m, ok := model.(string); ok {
+		return m
+	}
+	if m, ok := model.(map[string]interface{}); ok {
+		if p, ok := m["primary"].(string); ok {
+			return p
+		}
+	}
+	return ""
+}
+
+// modelFallbacks returns the fallbacks of an object model entry.
+func modelFallbacks(model interface{}) []string {
+	mObj, _ := model.(map[string]interface{})
+	list, _ := mObj["fallbacks"].([]interface{})
+	out := make([]string, 0, len(list))
+	for _, f := range list {
+		if s, ok := f.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// withAgentModel returns a copy of agent using model as primary, keeping the
+// string or object form of its model entry. Object entries also get
+// fallbacks, unless it is nil.
+func withAgentModel(agent map[string]interface{}, model string, fallbacks []string) map[string]interface{} {
+	newAgent := make(map[string]interface{}, len(agent))
+	for k, v := range agent {
+		newAgent[k] = v
+	}
+	if mObj, ok := agent["model"].(map[string]interface{}); ok {
+		newModelObj := make(map[string]interface{}, len(mObj))
+		for k, v := range mObj {
+			newModelObj[k] = v
+		}
+		newModelObj["primary"] = model
+		if fallbacks != nil {
+			newModelObj["fallbacks"] = fallbacks
+		}
+		newAgent["model"] = newModelObj
+	} else {
+		newAgent["model"] = model
+	}
+	return newAgent
+}
+
+// planAgents applies the agent rules to a switch of the default model to
+// modelID. It returns one change per agent and the agents.list to patch, or
+// nil when no agent entry needs rewriting. Callers hold e.mu.
+func (e *RotatorEngine) planAgents(ocData map[string]interface{}, modelID string) ([]AgentModelChange, []interface{}) {
+	cfg := e.Config()
+	ocCfg, _ := ocData["config"].(map[string]interface{})
+	agents, _ := ocCfg["agents"].(map[string]interface{})
+	list, _ := agents["list"].([]interface{})
+
+	var changes []AgentModelChange
+	patched := false
+	listPatch := make([]interface{}, len(list))
+	for i, a := range list {
+		listPatch[i] = a
+		agentMap, ok := a.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id, _ := agentMap["id"].(string)
+		name, _ := agentMap["name"].(string)
+		rule := cfg.Rotator.AgentRuleFor(id)
+		current := agentModel(agentMap)
+
+		ch := AgentModelChange{AgentID: id, Name: name, Mode: rule.Mode, From: current, To: current, Action: AgentKeep}
+		candidates := e.policy().ModelPriority
+		switch rule.Mode {
+		case config.AgentExclude:
+			ch.Reason = "excluded"
+		case config.AgentPin:
+			ch.To = rule.Model
+			candidates = nil
+		case config.AgentRotate:
+			candidates = rule.Priority
+			if m, ok := e.rotateTarget(rule.Priority); ok {
+				ch.To = m
+			} else {
+				ch.Reason = "no healthy model in its priority list"
+			}
+		default:
+			switch {
+			case current == "":
+				ch.Action, ch.To = AgentInherit, modelID
+			case rule.Mode == config.AgentFollow:
+				ch.To = modelID
+			default:
+				// Without a rule an agent's own model is deliberate.
+				ch.Reason = "own model; a follow rule makes it track switches"
+				candidates = nil
+			}
+		}
+		if ch.Action != AgentKeep || rule.Mode == config.AgentExclude {
+			changes = append(changes, ch)
+			continue
+		}
+
+		// Object models also carry the fallback list the gateway fails
+		// over to between cycles.
+		var fallbacks []string
+		changed := ch.To != current
+		if _, isObj := agentMap["model"].(map[string]interface{}); isObj && candidates != nil {
+			old := modelFallbacks(agentMap["model"])
+			if fb, ok := e.fallbacksFor(ch.To, candidates, old); ok {
+				fallbacks = fb
+				ch.Fallbacks = fb
+				changed = changed || !equalStrings(fb, old)
+			}
+		}
+		if changed {
+			ch.Action = AgentPatch
+			listPatch[i] = withAgentModel(agentMap, ch.To, fallbacks)
+			patched = true
+		}
+		changes = append(changes, ch)
+	}
+	if !patched {
+		return changes, nil
+	}
+	return changes, listPatch
+}
+
+// rotateTarget returns the first model of priority that has a healthy
+// account. Callers hold e.mu.
+func (e *RotatorEngine) rotateTarget(priority []string) (string, bool) {
+	threshold := e.policy().Low
+	for _, m := range priority {
+		if _, q := e.bestAccount(m); q > threshold {
+			return m, true
+		}
+	}
+	return "", false
+}
+
+// fallbacksFor orders the candidate models other than primary by the quota
+// of their best account, dropping models without a healthy account. Entries
+// of current that the rotator does not manage (e.g. other providers) are
+// kept at the end. ok is false while no quota is known yet, so fallbacks
+// are not wiped before the first refresh. Callers hold e.mu.
+func (e *RotatorEngine) fallbacksFor(primary string, candidates, current []string) ([]string, bool) {
+	if len(e.Status) == 0 {
+		return nil, false
+	}
+	threshold := e.policy().Low
+
+	type ranked struct {
+		model string
+		quota int
+	}
+	var healthy []ranked
+	for _, m := range candidates {
+		if m == primary {
+			continue
+		}
+		if _, q := e.bestAccount(m); q > threshold {
+			healthy = append(healthy, ranked{m, q})
+		}
+	}
+	sort.SliceStable(healthy, func(i, j int) bool { return healthy[i].quota > healthy[j].quota })
+
+	out := []string{}
+	for _, r := range healthy {
+		out = append(out, r.model)
+	}
+	for _, m := range current {
+		if m != primary && !isManagedModel(m) {
+			out = append(out, m)
+		}
+	}
+	return out, true
+}
+
+// isManagedModel reports whether the rotator tracks quota for model.
+func isManagedModel(model string) bool {
+	for _, m := range config.KnownModels {
+		if m == model {
+			return true
+		}
+	}
+	return false
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// PreviewModelSwitch reports, without changing anything, which agents
+// SwitchModel(modelID) would touch on every installation.
+func (e *RotatorEngine) PreviewModelSwitch(modelID string) ([]AgentModelChange, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	insts := e.installations()
+	out := []AgentModelChange{}
+	for _, inst := range insts {
+		ocData, err := e.gatewayConfig(inst)
+		if err != nil {
+			return nil, fmt.Errorf("[%s] %v", inst.Name, err)
+		}
+		changes, _ := e.planAgents(ocData, modelID)
+		for _, ch := range changes {
+			if len(insts) > 1 {
+				ch.Installation = inst.Name
+			}
+			out = append(out, ch)
+		}
+	}
+	return out, nil
+}
+
+// defaultsModel returns agents.defaults.model from a config.get result.
+func defaultsModel(ocData map[string]interface{}) interface{} {
+	ocCfg, _ := ocData["config"].(map[string]interface{})
+	agents, _ := ocCfg["agents"].(map[string]interface{})
+	defaults, _ := agents["defaults"].(map[string]interface{})
+	return defaults["model"]
+}
@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"antigravity-rotator-v2/internal/config"
+	"antigravity-rotator-v2/internal/fsutil"
+	"antigravity-rotator-v2/internal/scanner"
+	"errors"
+	"path/filepath"
+	"sort"
+)
+
+// backupTargets lists every file the rotator modifies.
+func (e *RotatorEngine) backupTargets() []string {
+	targets := []string{config.GetConfigPath()}
+	for _, inst := range e.installations() {
+		authPath := inst.AuthPath()
+		targets = append(targets, authPath)
+		if workspaces, err := scanner.ScanWorkspaces(inst.Home); err == nil {
+			for _, wp := range workspaces {
+				p := filepath.Join(wp.Path, "auth-profiles.json")
+				if p != authPath {
+					targets = append(targets, p)
+				}
+			}
+		}
+	}
+	return targets
+}
+
+// ListBackups returns the backups of all managed files, newest first.
+func (e *RotatorEngine) ListBackups() []fsutil.BackupInfo {
+	var all []fsutil.BackupInfo
+	for _, target := range e.backupTargets() {
+		backups, _ := fsutil.ListBackups(target)
+		all = append(all, backups...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Time.After(all[j].Time) })
+	return all
+}
+
+// RestoreBackup writes a backup back over its original file. Restoring the
+// rotator config also reloads it into the running engine.
+func (e *RotatorEngine) RestoreBackup(backupPath string) error {
+	target, err := fsutil.Restore(backupPath)
+	if err != nil {
+		return err
+	}
+	if target == config.GetConfigPath() {
+		cfg, err := config.LoadConfig()
+		var notices config.MigrationNotices
+		if err != nil && !errors.As(err, &notices) {
+			return err
+		}
+		if _, err := e.ApplyConfig(cfg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
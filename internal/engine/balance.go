@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"antigravity-rotator-v2/internal/config"
+	"sort"
+)
+
+// balanceState is what the balancing modes carry between cycles.
+type balanceState struct {
+	// account has been active for model since its quota was start.
+	account string
+	model   string
+	start   int
+	// credits are the smooth weighted round-robin counters of the
+	// weighted mode.
+	credits map[string]int
+}
+
+// healthy returns the usable accounts above threshold for modelID with
+// their quota, in config order.
+func (e *RotatorEngine) healthy(modelID string, threshold int) ([]string, map[string]int) {
+	var accounts []string
+	quota := make(map[string]int)
+	for _, email := range e.Config().Rotator.Accounts {
+		if !e.usable(email) {
+			continue
+		}
+		if q, ok := e.Status[email+":"+modelID]; ok && q > threshold {
+			accounts = append(accounts, email)
+			quota[email] = q
+		}
+	}
+	return accounts, quota
+}
+
+// balancedDecision picks the account for modelID in balancing mode b and
+// orders the other healthy accounts behind it by quota left.
+func (e *RotatorEngine) balancedDecision(b config.BalanceConfig, modelID string, threshold int) Decision {
+	accounts, quota := e.healthy(modelID, threshold)
+	d := Decision{Model: modelID}
+	if b.Mode == config.BalanceWeighted {
+		d.Account, d.credits = e.weightedAccount(accounts, quota, threshold)
+	} else {
+		d.Account = e.sliceAccount(accounts, quota, modelID, b.SliceSize())
+	}
+	d.Quota = quota[d.Account]
+
+	rest := make([]string, 0, len(accounts))
+	for _, email := range accounts {
+		if email != d.Account {
+			rest = append(rest, email)
+		}
+	}
+	sort.SliceStable(rest, func(i, j int) bool { return quota[rest[i]] > quota[rest[j]] })
+	d.Order = append([]string{d.Account}, rest...)
+	return d
+}
+
+// sliceAccount keeps the active account until it has used slice percent
+// since it took over, then picks the account with the most quota left.
+func (e *RotatorEngine) sliceAccount(accounts []string, quota map[string]int, modelID string, slice int) string {
+	e.pollMu.Lock()
+	active := e.active
+	e.pollMu.Unlock()
+	e.balanceMu.Lock()
+	st := e.balance
+	e.balanceMu.Unlock()
+
+	if q, ok := quota[active]; ok {
+		// A different account, model or a refill starts a new slice.
+		if st.account != active || st.model != modelID || q > st.start || st.start-q < slice {
+			return active
+		}
+	}
+	best := ""
+	for _, email := range accounts {
+		if email == active {
+			continue
+		}
+		if best == "" || quota[email] > quota[best] {
+			best = email
+		}
+	}
+	if best == "" {
+		return active
+	}
+	return best
+}
+
+// weightedAccount runs one round of smooth weighted round-robin over the
+// healthy accounts, weighted by their quota above threshold, and returns
+// the pick and the new counters.
+func (e *RotatorEngine) weightedAccount(accounts []string, quota map[string]int, threshold int) (string, map[string]int) {
+	e.balanceMu.Lock()
+	old := e.balance.credits
+	e.balanceMu.Unlock()
+
+	credits := make(map[string]int, len(accounts))
+	best, total := "", 0
+	for _, email := range accounts {
+		w := quota[email] - threshold
+		credits[email] = old[email] + w
+		total += w
+		if best == "" || credits[email] > credits[best] {
+			best = email
+		}
+	}
+	credits[best] -= total
+	return best, credits
+}
+
+// commitBalance records an applied decision for the next cycles.
+func (e *RotatorEngine) commitBalance(d Decision) {
+	e.balanceMu.Lock()
+	defer e.balanceMu.Unlock()
+	if d.credits != nil {
+		e.balance.credits = d.credits
+	}
+	st := &e.balance
+	if st.account != d.Account || st.model != d.Model || d.Quota > st.start {
+		st.account, st.model, st.start = d.Account, d.Model, d.Quota
+	}
+}
@@ -0,0 +1,260 @@
+package engine
+
+import (
+	"antigravity-rotator-v2/internal/bundle"
+	"antigravity-rotator-v2/internal/config"
+	"antigravity-rotator-v2/internal/history"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RestorePlan describes what restoring a bundle changes on this machine.
+type RestorePlan struct {
+	CreatedAt time.Time `json:"createdAt"`
+	Version   int       `json:"version"`
+	Encrypted bool      `json:"encrypted"`
+	// AccountsAdded and AccountsRemoved compare the managed account lists.
+	AccountsAdded   []string `json:"accountsAdded"`
+	AccountsRemoved []string `json:"accountsRemoved"`
+	// ProfilesAdded and ProfilesUpdated are the credentials written into
+	// the auth files; other profiles are left alone.
+	ProfilesAdded     []string `json:"profilesAdded"`
+	ProfilesUpdated   []string `json:"profilesUpdated"`
+	ProfilesUnchanged int      `json:"profilesUnchanged"`
+	// ConfigChanges lists the rotator settings that differ.
+	ConfigChanges  []string `json:"configChanges"`
+	HistoryEntries int      `json:"historyEntries"`
+}
+
+// ExportBundle returns a backup of the config, the google-antigravity
+// credentials of the primary installation, the account states and the
+// rotation history. A non-empty passphrase encrypts it.
+func (e *RotatorEngine) ExportBundle(passphrase string) ([]byte, error) {
+	cfg := e.Config()
+	cfgData, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	b := &bundle.Bundle{
+		CreatedAt: time.Now(),
+		Config:    cfgData,
+		Profiles:  make(map[string]interface{}),
+	}
+
+	authPath, _ := e.getPaths()
+	authData, err := e.readJSON(authPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取凭据失败: %v", err)
+	}
+	profiles, _ := authData["profiles"].(map[string]interface{})
+	for key, p := range profiles {
+		if strings.HasPrefix(key, "google-antigravity:") {
+			b.Profiles[key] = p
+		}
+	}
+
+	for _, acc := range SummarizeAccounts(cfg, e.StatusSnapshot()) {
+		b.Accounts = append(b.Accounts, bundle.Account{
+			Email: acc.Email, Disabled: acc.Disabled, Tags: acc.Tags, Quotas: acc.Quotas,
+		})
+	}
+	if b.History, err = e.History(); err != nil {
+		return nil, fmt.Errorf("读取轮换历史失败: %v", err)
+	}
+	return bundle.Encode(b, passphrase)
+}
+
+// PreviewRestore decodes a bundle and reports what RestoreBundle would
+// change, without writing anything.
+func (e *RotatorEngine) PreviewRestore(data []byte, passphrase string) (RestorePlan, error) {
+	b, cfg, err := e.decodeBundle(data, passphrase)
+	if err != nil {
+		return RestorePlan{}, err
+	}
+	return e.planRestore(b, cfg, bundle.Encrypted(data)), nil
+}
+
+// RestoreBundle writes a bundle back: its credentials into every
+// installation, its config (keeping this machine's paths and
+// installations) and its rotation history. Every file is backed up first.
+func (e *RotatorEngine) RestoreBundle(data []byte, passphrase string) (RestorePlan, error) {
+	b, cfg, err := e.decodeBundle(data, passphrase)
+	if err != nil {
+		return RestorePlan{}, err
+	}
+	plan := e.planRestore(b, cfg, bundle.Encrypted(data))
+
+	insts := e.installations()
+	for _, inst := range insts {
+		if err := e.restoreProfiles(inst.AuthPath(), b.Profiles); err != nil {
+			return plan, fmt.Errorf("[%s] 恢复凭据失败: %v", inst.Name, err)
+		}
+	}
+	if err := config.SaveConfig(cfg); err != nil {
+		return plan, fmt.Errorf("保存配置失败: %v", err)
+	}
+	if _, err := e.ApplyConfig(cfg); err != nil {
+		return plan, err
+	}
+	if err := history.Replace(e.historyPath(), b.History); err != nil {
+		return plan, fmt.Errorf("恢复轮换历史失败: %v", err)
+	}
+
+	// Last known quotas let the UI show something before the first refresh.
+	e.mu.Lock()
+	for _, acc := range b.Accounts {
+		for model, q := range acc.Quotas {
+			if _, ok := e.Status[acc.Email+":"+model]; !ok {
+				e.Status[acc.Email+":"+model] = q
+			}
+		}
+	}
+	e.mu.Unlock()
+	for _, inst := range e.installations() {
+		e.syncInstallation(inst)
+	}
+	e.emitStatus()
+	return plan, nil
+}
+
+// decodeBundle decodes a bundle and builds the config it restores to.
+func (e *RotatorEngine) decodeBundle(data []byte, passphrase string) (*bundle.Bundle, *config.AppConfig, error) {
+	b, err := bundle.Decode(data, passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg, _, err := config.Parse(b.Config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("备份中的配置无效: %v", err)
+	}
+
+	// Paths and installations belong to this machine, not the backup.
+	cur := e.Config().Rotator
+	cfg.Rotator.OpenClawBin = cur.OpenClawBin
+	cfg.Rotator.OpenClawHome = cur.OpenClawHome
+	cfg.Rotator.Installations = cur.Installations
+
+	// Account states travel in the bundle; they win over the config copy.
+	cfg.Rotator.DisabledAccounts = nil
+	cfg.Rotator.AccountTags = nil
+	for _, acc := range b.Accounts {
+		if !containsString(cfg.Rotator.Accounts, acc.Email) {
+			cfg.Rotator.Accounts = append(cfg.Rotator.Accounts, acc.Email)
+		}
+		if acc.Disabled {
+			cfg.Rotator.DisabledAccounts = append(cfg.Rotator.DisabledAccounts, acc.Email)
+		}
+		if len(acc.Tags) > 0 {
+			if cfg.Rotator.AccountTags == nil {
+				cfg.Rotator.AccountTags = make(map[string][]string)
+			}
+			cfg.Rotator.AccountTags[acc.Email] = acc.Tags
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return b, cfg, nil
+}
+
+func (e *RotatorEngine) planRestore(b *bundle.Bundle, cfg *config.AppConfig, encrypted bool) RestorePlan {
+	cur := e.Config().Rotator
+	plan := RestorePlan{
+		CreatedAt:       b.CreatedAt,
+		Version:         b.Version,
+		Encrypted:       encrypted,
+		AccountsAdded:   []string{},
+		AccountsRemoved: []string{},
+		ProfilesAdded:   []string{},
+		ProfilesUpdated: []string{},
+		ConfigChanges:   []string{},
+		HistoryEntries:  len(b.History),
+	}
+	for _, a := range cfg.Rotator.Accounts {
+		if !containsString(cur.Accounts, a) {
+			plan.AccountsAdded = append(plan.AccountsAdded, a)
+		}
+	}
+	for _, a := range cur.Accounts {
+		if !containsString(cfg.Rotator.Accounts, a) {
+			plan.AccountsRemoved = append(plan.AccountsRemoved, a)
+		}
+	}
+
+	authPath, _ := e.getPaths()
+	authData, _ := e.readJSON(authPath)
+	current, _ := authData["profiles"].(map[string]interface{})
+	for key, p := range b.Profiles {
+		email := strings.TrimPrefix(key, "google-antigravity:")
+		switch old, ok := current[key]; {
+		case !ok:
+			plan.ProfilesAdded = append(plan.ProfilesAdded, email)
+		case reflect.DeepEqual(old, p):
+			plan.ProfilesUnchanged++
+		default:
+			plan.ProfilesUpdated = append(plan.ProfilesUpdated, email)
+		}
+	}
+	sort.Strings(plan.ProfilesAdded)
+	sort.Strings(plan.ProfilesUpdated)
+
+	plan.ConfigChanges = diffFields(cur, cfg.Rotator)
+	return plan
+}
+
+// diffFields returns the JSON names of the top-level fields that differ.
+func diffFields(a, b config.RotatorConfig) []string {
+	var ma, mb map[string]interface{}
+	da, _ := json.Marshal(a)
+	db, _ := json.Marshal(b)
+	json.Unmarshal(da, &ma)
+	json.Unmarshal(db, &mb)
+
+	changed := []string{}
+	for k, v := range mb {
+		if !reflect.DeepEqual(ma[k], v) {
+			changed = append(changed, k)
+		}
+	}
+	for k := range ma {
+		if _, ok := mb[k]; !ok {
+			changed = append(changed, k)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// restoreProfiles puts the bundled profiles into an auth file, creating it
+// on a fresh machine. Other profiles are kept.
+func (e *RotatorEngine) restoreProfiles(path string, bundled map[string]interface{}) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(`{"profiles":{}}`), 0600); err != nil {
+			return err
+		}
+	}
+	return e.updateJSON(path, func(authData map[string]interface{}) (bool, error) {
+		profiles, ok := authData["profiles"].(map[string]interface{})
+		if !ok {
+			profiles = make(map[string]interface{})
+			authData["profiles"] = profiles
+		}
+		changed := false
+		for key, p := range bundled {
+			if !reflect.DeepEqual(profiles[key], p) {
+				profiles[key] = p
+				changed = true
+			}
+		}
+		return changed, nil
+	})
+}
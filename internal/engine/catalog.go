@@ -0,0 +1,27 @@
+package engine
+
+import "antigravity-rotator-v2/internal/google"
+
+// recordCatalog keeps the last model catalog fetched for an account.
+func (e *RotatorEngine) recordCatalog(email string, cat *google.Catalog) {
+	e.catalogMu.Lock()
+	defer e.catalogMu.Unlock()
+	if e.catalogs == nil {
+		e.catalogs = make(map[string]*google.Catalog)
+	}
+	e.catalogs[email] = cat
+}
+
+// ModelCatalogs returns the last model catalog of every refreshed account,
+// keyed by email. Each display quota names the upstream model limiting it.
+func (e *RotatorEngine) ModelCatalogs() map[string]*google.Catalog {
+	e.catalogMu.Lock()
+	defer e.catalogMu.Unlock()
+	out := make(map[string]*google.Catalog, len(e.catalogs))
+	for email, cat := range e.catalogs {
+		if e.hasAccount(email) {
+			out[email] = cat
+		}
+	}
+	return out
+}
@@ -2,30 +2,57 @@ package engine
 
 import (
 	"antigravity-rotator-v2/internal/config"
+	"antigravity-rotator-v2/internal/fsutil"
 	"antigravity-rotator-v2/internal/google"
-	"antigravity-rotator-v2/internal/scanner"
+	"antigravity-rotator-v2/internal/history"
+	"antigravity-rotator-v2/internal/watcher"
 	"context"
 	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
-	"path/filepath"
 	"strings"
 	"sync"
-	"syscall"
 	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 type RotatorEngine struct {
-	Config *config.AppConfig
-	Client *google.Client
+	cfgMu  sync.RWMutex
+	cfg    *config.AppConfig
+	client *google.Client
 	mu     sync.Mutex
 	Status map[string]int // key: email:model
 	ctx    context.Context
-	ticker *time.Ticker
-	done   chan bool
+
+	loopMu   sync.Mutex
+	loopStop chan struct{}
+	standby  bool
+	trig     triggerState
+
+	scheduleMu   sync.Mutex
+	lastSchedule string
+
+	pollMu sync.Mutex
+	polls  map[string]*AccountPoll
+	active string // front of auth.order, as last seen or set
+
+	catalogMu sync.Mutex
+	catalogs  map[string]*google.Catalog
+
+	tierMu     sync.Mutex
+	tiers      map[string]*AccountTier
+	onboarding map[string]bool
+
+	balanceMu sync.Mutex
+	balance   balanceState
+
+	watchMu  sync.Mutex
+	watchers []*watcher.Watcher
+
+	// clock replaces time.Now for rotation decisions and polling, so the
+	// simulator can run them in virtual time.
+	clock func() time.Time
 }
 
 type AgentInfo struct {
@@ -33,102 +60,134 @@ type AgentInfo struct {
 	Name         string `json:"name"`
 	CurrentModel string `json:"currentModel"`
 	Emoji        string `json:"emoji"`
+	Installation string `json:"installation,omitempty"`
+	Rule         string `json:"rule"` // default, follow, pin, rotate or exclude
 }
 
 func NewRotatorEngine(cfg *config.AppConfig) *RotatorEngine {
 	return &RotatorEngine{
-		Config: cfg,
-		Client: &google.Client{
+		cfg: cfg,
+		client: &google.Client{
 			Proxy: cfg.Rotator.Proxy,
 		},
 		Status: make(map[string]int),
-		done:   make(chan bool),
 	}
 }
 
-func (e *RotatorEngine) getOpenClawCmd(args ...string) *exec.Cmd {
-	bin := "openclaw"
-	if e.Config.Rotator.OpenClawBin != "" {
-		bin = e.Config.Rotator.OpenClawBin
+// now returns the engine's current time.
+func (e *RotatorEngine) now() time.Time {
+	if e.clock != nil {
+		return e.clock()
+	}
+	return time.Now()
+}
+
+// Config returns the configuration the engine is currently running with.
+// Callers must treat it as read-only; use ApplyConfig to change it.
+func (e *RotatorEngine) Config() *config.AppConfig {
+	e.cfgMu.RLock()
+	defer e.cfgMu.RUnlock()
+	return e.cfg
+}
+
+// clientFor returns the Google client for an account, honouring any
+// per-account proxy override.
+func (e *RotatorEngine) clientFor(email string) *google.Client {
+	e.cfgMu.RLock()
+	defer e.cfgMu.RUnlock()
+	if p, ok := e.cfg.Rotator.AccountProxies[email]; ok && p.Mode != "" {
+		return &google.Client{Proxy: p}
 	}
-	cmd := exec.Command(bin, args...)
-	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
-	return cmd
+	return e.client
 }
 
 func (e *RotatorEngine) SetContext(ctx context.Context) {
 	e.ctx = ctx
 }
 
+// emitStatus sends a copy of the status to the frontend. Callers must not
+// hold e.mu.
 func (e *RotatorEngine) emitStatus() {
 	if e.ctx != nil {
-		runtime.EventsEmit(e.ctx, "status_updated", e.Status)
+		runtime.EventsEmit(e.ctx, "status_updated", e.StatusSnapshot())
 	}
 }
 
 func (e *RotatorEngine) StartAutoLoop() {
-	if e.Config.Rotator.AutoRotate && e.Config.Rotator.RotateInterval > 0 {
-		e.StopAutoLoop() // Ensure no duplicate loops
-		e.ticker = time.NewTicker(time.Duration(e.Config.Rotator.RotateInterval) * time.Minute)
-		go func() {
-			for {
-				select {
-				case <-e.done:
-					return
-				case <-e.ticker.C:
-					fmt.Println("Auto-Rotation Triggered")
-					e.RunCycle()
+	e.loopMu.Lock()
+	defer e.loopMu.Unlock()
+
+	e.stopLoopLocked() // Ensure no duplicate loops
+	rc := e.Config().Rotator
+	if e.standby || !rc.MayAutoRotate() || rc.RotateInterval <= 0 {
+		return
+	}
+
+	// Each loop owns its ticker and stop channel, so a restarted loop can
+	// never be driven by a previous loop's goroutine.
+	stop := make(chan struct{})
+	ticker := time.NewTicker(time.Duration(rc.RotateInterval) * time.Minute)
+	e.loopStop = stop
+	go func() {
+		defer ticker.Stop()
+		minute := time.NewTicker(time.Minute)
+		defer minute.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-minute.C:
+				e.CurrentPolicy() // report schedule changes between cycles
+			case <-ticker.C:
+				if p := e.CurrentPolicy(); !p.AutoRotate {
+					fmt.Printf("Auto-rotation paused by schedule %q\n", p.Schedule)
+					continue
 				}
+				fmt.Println("Auto-Rotation Triggered")
+				e.runCycle("auto")
 			}
-		}()
-		fmt.Printf("Auto-rotation started every %d minutes\n", e.Config.Rotator.RotateInterval)
-	}
-}
-
-func (e *RotatorEngine) StopAutoLoop() {
-	if e.ticker != nil {
-		e.ticker.Stop()
-		e.ticker = nil
-		// Non-blocking send
-		select {
-		case e.done <- true:
-		default:
 		}
-		fmt.Println("Auto-rotation stopped")
+	}()
+	e.startTriggers(rc.Triggers, stop)
+	if rc.Polling.Tiered {
+		e.startPoller(stop)
 	}
+	fmt.Printf("Auto-rotation started every %d minutes\n", rc.RotateInterval)
 }
 
-func (e *RotatorEngine) getPaths() (string, string) {
-	home, _ := os.UserHomeDir()
-	// Priority: agents/main/agent/auth-profiles.json then ~/.openclaw/auth-profiles.json
-	mainAgentAuth := filepath.Join(home, ".openclaw", "agents", "main", "agent", "auth-profiles.json")
-	if _, err := os.Stat(mainAgentAuth); err == nil {
-		return mainAgentAuth, filepath.Join(home, ".openclaw", "openclaw.json")
+// SetStandby marks the engine as a client of another instance's leader:
+// it will not run the auto loop or background syncs until promoted.
+func (e *RotatorEngine) SetStandby(standby bool) {
+	e.loopMu.Lock()
+	e.standby = standby
+	e.loopMu.Unlock()
+	if standby {
+		e.StopAutoLoop()
+	} else {
+		e.StartAutoLoop()
 	}
-	return filepath.Join(home, ".openclaw", "auth-profiles.json"), filepath.Join(home, ".openclaw", "openclaw.json")
 }
 
-func (e *RotatorEngine) SyncAuthToAgents(sourcePath string) error {
-	workspaces, err := scanner.ScanWorkspaces()
-	if err != nil {
-		return err
-	}
+// Standby reports whether another instance currently owns the auto loop.
+func (e *RotatorEngine) Standby() bool {
+	e.loopMu.Lock()
+	defer e.loopMu.Unlock()
+	return e.standby
+}
 
-	data, err := os.ReadFile(sourcePath)
-	if err != nil {
-		return err
-	}
+func (e *RotatorEngine) StopAutoLoop() {
+	e.loopMu.Lock()
+	defer e.loopMu.Unlock()
+	e.stopLoopLocked()
+}
 
-	for _, wp := range workspaces {
-		target := filepath.Join(wp.Path, "auth-profiles.json")
-		absTarget, _ := filepath.Abs(target)
-		absSource, _ := filepath.Abs(sourcePath)
-		if absTarget == absSource {
-			continue
-		}
-		os.WriteFile(target, data, 0644)
+func (e *RotatorEngine) stopLoopLocked() {
+	e.stopWebhook()
+	if e.loopStop != nil {
+		close(e.loopStop)
+		e.loopStop = nil
+		fmt.Println("Auto-rotation stopped")
 	}
-	return nil
 }
 
 type accountTask struct {
@@ -137,293 +196,285 @@ type accountTask struct {
 	projectId string
 }
 
+// RefreshStatus refreshes the quotas of every enabled account, onboarding
+// accounts without a project first. Callers must not hold e.mu.
 func (e *RotatorEngine) RefreshStatus() error {
-	authPath, _ := e.getPaths()
-	authData, err := e.readJSON(authPath)
-	if err != nil {
-		return fmt.Errorf("读取凭据失败: %v", err)
-	}
+	e.onboardMissing()
+	return e.refreshStatus()
+}
 
-	accounts := e.Config.Rotator.Accounts
+// refreshStatus is RefreshStatus without waiting for onboarding: accounts
+// without a project are onboarded in the background and skipped. Callers
+// must not hold e.mu.
+func (e *RotatorEngine) refreshStatus() error {
+	accounts := e.Config().Rotator.Accounts
 	if len(accounts) == 0 {
 		return nil
 	}
 
-	profiles, ok := authData["profiles"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("profiles 字段不存在")
+	profiles, err := e.loadProfiles()
+	if err != nil {
+		return err
 	}
 
 	var tasks []accountTask
 	for _, email := range accounts {
-		profileKey := "google-antigravity:" + email
-		if profile, ok := profiles[profileKey].(map[string]interface{}); ok {
-			refresh, _ := profile["refresh"].(string)
-			projectId, _ := profile["projectId"].(string)
-			if refresh != "" {
-				tasks = append(tasks, accountTask{email: email, refresh: refresh, projectId: projectId})
-			}
+		if e.isDisabled(email) {
+			continue
+		}
+		if t, ok := accountTaskFor(profiles, email); ok {
+			tasks = append(tasks, t)
 		}
 	}
 
 	var wg sync.WaitGroup
-	var statusMu sync.Mutex
-
 	for _, task := range tasks {
 		wg.Add(1)
 		go func(t accountTask) {
 			defer wg.Done()
-
-			newAccess, err := e.Client.RefreshAccessToken(t.refresh)
-			if err != nil {
-				return
+			if e.refreshAccount(t) == nil {
+				e.emitStatus()
 			}
-
-			pId := t.projectId
-			if pId == "" {
-				pId, err = e.Client.FetchProjectID(newAccess)
-				if err != nil {
-					return
-				}
-				// 这种按需更新 projectId 的逻辑暂不写回文件以保证线程安全，仅本次刷新使用
-			}
-
-			quotas, err := e.Client.FetchAccountQuota(newAccess, pId)
-			if err != nil {
-				return
-			}
-
-			statusMu.Lock()
-			for model, q := range quotas {
-				e.Status[t.email+":"+model] = q
-			}
-			statusMu.Unlock()
-			e.emitStatus()
 		}(task)
 	}
 
 	wg.Wait()
+	e.mu.Lock()
+	e.schedulePolls()
+	e.mu.Unlock()
 	e.emitStatus()
 	return nil
 }
 
-func (e *RotatorEngine) GetConfigHash() (string, error) {
-	cmd := e.getOpenClawCmd("gateway", "call", "config.get", "--json")
-	output, err := cmd.Output()
+// refreshAccount fetches the model catalog of one account and its quotas
+// into e.Status. It takes e.mu only to write them, so callers must not hold
+// it.
+func (e *RotatorEngine) refreshAccount(t accountTask) error {
+	client := e.clientFor(t.email)
+	newAccess, err := client.RefreshAccessToken(t.refresh)
 	if err != nil {
-		return "", fmt.Errorf("获取配置 Hash 失败: %v", err)
-	}
-
-	// 过滤非 JSON 输出（例如插件加载日志）
-	outputStr := string(output)
-	jsonStart := strings.Index(outputStr, "{")
-	if jsonStart == -1 {
-		return "", fmt.Errorf("未在输出中找到 JSON 数据: %s", outputStr)
+		return err
 	}
-	outputStr = outputStr[jsonStart:]
 
-	var res struct {
-		Hash string `json:"hash"`
-	}
-	if err := json.Unmarshal([]byte(outputStr), &res); err != nil {
-		return "", fmt.Errorf("解析配置 Hash 失败: %v (原始内容: %s)", err, outputStr)
+	pId := t.projectId
+	if pId == "" {
+		// Provisioning can take minutes, so the account is onboarded in
+		// the background and polled once its project is saved.
+		go e.ensureProject(client, t.email, newAccess)
+		return fmt.Errorf("账号 %s 尚无项目，正在开通", t.email)
+	} else if _, fresh := e.tierOf(t.email); !fresh {
+		// Tier and onboarding state change rarely; re-check them every
+		// few hours.
+		if info, err := client.LoadCodeAssist(newAccess); err == nil {
+			e.recordTier(t.email, info)
+		}
 	}
-	return res.Hash, nil
-}
 
-func (e *RotatorEngine) PatchConfig(patch string) error {
-	hash, err := e.GetConfigHash()
+	cat, err := client.FetchModelCatalog(newAccess, pId)
 	if err != nil {
 		return err
 	}
 
-	params := fmt.Sprintf(`{"raw":%q, "baseHash":%q, "restartDelayMs":100}`, patch, hash)
-	cmd := e.getOpenClawCmd("gateway", "call", "config.patch", "--params", params)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("执行 config.patch 失败: %v (输出: %s)", err, string(output))
+	e.mu.Lock()
+	for model, q := range cat.Display {
+		e.Status[t.email+":"+model] = q.Percent
 	}
+	e.mu.Unlock()
+	e.recordPoll(t.email, cat.Display)
+	e.recordTrace(t.email, cat.Display)
+	e.recordCatalog(t.email, cat)
 	return nil
 }
 
+// accountTaskFor builds the refresh task of one account from the profiles.
+func accountTaskFor(profiles map[string]interface{}, email string) (accountTask, bool) {
+	profile, ok := profiles["google-antigravity:"+email].(map[string]interface{})
+	if !ok {
+		return accountTask{}, false
+	}
+	refresh, _ := profile["refresh"].(string)
+	projectId, _ := profile["projectId"].(string)
+	return accountTask{email: email, refresh: refresh, projectId: projectId}, refresh != ""
+}
+
+// RunCycle refreshes quotas and switches to the best model and account.
 func (e *RotatorEngine) RunCycle() string {
-	e.mu.Lock()
-	defer e.mu.Unlock()
+	return e.runCycle("manual")
+}
 
-	// 1. Refresh Status
-	e.RefreshStatus()
-
-	// 2. Define Priority Models
-	priorityModels := []string{
-		"google-antigravity/gemini-3-pro-high",
-		"google-antigravity/gemini-3-flash",
-		"google-antigravity/claude-sonnet-4-5-thinking",
-	}
-
-	threshold := e.Config.Rotator.Quotas.Low
-	
-	// 3. Find Best Model & Account
-	for _, modelID := range priorityModels {
-		bestAccount := ""
-		maxQuota := -1
-
-		for _, email := range e.Config.Rotator.Accounts {
-			key := email + ":" + modelID
-			if q, ok := e.Status[key]; ok {
-				if q > maxQuota {
-					maxQuota = q
-					bestAccount = email
-				}
-			}
-		}
+// runCycle is RunCycle; reason is recorded in the rotation history.
+func (e *RotatorEngine) runCycle(reason string) string {
+	d, ok, err := e.rotate(reason)
+	return CycleMessage(d, ok, err)
+}
 
-		// If this model has a healthy account (above threshold), switch to it!
-		if maxQuota > threshold {
-			fmt.Printf("Deep Rotation: Switching to %s on %s (Quota: %d%%)\n", modelID, bestAccount, maxQuota)
-			
-			// Switch Model (logic from SwitchModel)
-			e.mu.Unlock() // avoid deadlock as SwitchModel takes lock
-			errModel := e.SwitchModel(modelID)
-			e.mu.Lock()
-			if errModel != nil {
-				return fmt.Sprintf("Error switching model: %v", errModel)
-			}
+// CycleMessage describes the outcome of a rotation cycle.
+func CycleMessage(d Decision, ok bool, err error) string {
+	switch {
+	case !ok:
+		return "No healthy models found above threshold"
+	case err != nil:
+		return err.Error()
+	}
+	return fmt.Sprintf("Switched to %s (%d%%)", d.Model, d.Quota)
+}
 
-			// Switch Account (logic from SwitchAccount)
-			e.mu.Unlock()
-			errAcc := e.SwitchAccount(bestAccount)
-			e.mu.Lock()
-			if errAcc != nil {
-				return fmt.Sprintf("Error switching account: %v", errAcc)
-			}
+// Rotate runs a rotation cycle like RunCycle and returns the decision it
+// applied. ok is false when no priority model has a healthy account.
+func (e *RotatorEngine) Rotate() (Decision, bool, error) {
+	return e.rotate("manual")
+}
 
-			return fmt.Sprintf("Switched to %s (%d%%)", modelID, maxQuota)
-		}
+// rotate is Rotate; reason is recorded in the rotation history.
+func (e *RotatorEngine) rotate(reason string) (Decision, bool, error) {
+	defer e.markRotated()
+	e.onboardMissing()
+
+	// 1. Refresh Status (only the accounts that are due, when tiered)
+	e.refreshForCycle()
+
+	// 2. Find Best Model & Account
+	e.mu.Lock()
+	d, ok := e.decide()
+	e.mu.Unlock()
+	if !ok {
+		return d, false, nil
 	}
 
-	return "No healthy models found above threshold"
+	// If this model has a healthy account (above threshold), switch to it!
+	fmt.Printf("Deep Rotation: Switching to %s on %s (Quota: %d%%)\n", d.Model, d.Account, d.Quota)
+	return d, true, e.applyDecision(d, reason)
 }
 
+// applyDecision switches every installation to the decision's model and
+// account order, and records it for balancing and in the rotation history.
+func (e *RotatorEngine) applyDecision(d Decision, reason string) error {
+	if err := e.switchModel(d.Model, ""); err != nil {
+		return fmt.Errorf("Error switching model: %v", err)
+	}
+	if err := e.switchAccounts(d.order(), ""); err != nil {
+		return fmt.Errorf("Error switching account: %v", err)
+	}
+	e.commitBalance(d)
+	e.recordSwitch(history.Entry{Account: d.Account, Model: d.Model, Quota: d.Quota, Reason: reason})
+	return nil
+}
+
+// SwitchModel makes modelID the default model on every managed installation
+// and applies the agent rules to agents with their own model. Use
+// PreviewModelSwitch to see which agents it touches.
 func (e *RotatorEngine) SwitchModel(modelID string) error {
+	return e.switchModel(modelID, "manual")
+}
+
+// switchModel is SwitchModel; a non-empty reason records the switch in the
+// rotation history.
+func (e *RotatorEngine) switchModel(modelID, reason string) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	// 1. Prepare base patch for defaults
-	patchObj := map[string]interface{}{
-		"agents": map[string]interface{}{
-			"defaults": map[string]interface{}{
-				"model": map[string]interface{}{
-					"primary": modelID,
-				},
-			},
-		},
+	for _, inst := range e.installations() {
+		if err := e.switchModelOn(inst, modelID); err != nil {
+			return fmt.Errorf("[%s] %v", inst.Name, err)
+		}
+	}
+	if reason != "" {
+		e.recordSwitch(history.Entry{Model: modelID, Reason: reason})
 	}
+	return nil
+}
 
-	// 2. Fetch current config to identify agents to override
-	cmd := e.getOpenClawCmd("gateway", "call", "config.get", "--json")
-	output, err := cmd.Output()
+func (e *RotatorEngine) switchModelOn(inst config.Installation, modelID string) error {
+	ocData, err := e.gatewayConfig(inst)
 	if err != nil {
 		return fmt.Errorf("Failed to get config for patching: %v", err)
 	}
 
-	outputStr := string(output)
-	jsonStart := strings.Index(outputStr, "{")
-	if jsonStart == -1 {
-		return fmt.Errorf("Invalid config output (no JSON start found)")
+	// 1. Prepare base patch for defaults, with fallbacks ranked by quota
+	defaultModel := map[string]interface{}{
+		"primary": modelID,
 	}
-
-	var ocData map[string]interface{}
-	if err := json.Unmarshal([]byte(outputStr[jsonStart:]), &ocData); err != nil {
-		return fmt.Errorf("Failed to parse config JSON: %v", err)
+	if fb, ok := e.fallbacksFor(modelID, e.policy().ModelPriority, modelFallbacks(defaultsModel(ocData))); ok {
+		defaultModel["fallbacks"] = fb
+	}
+	patchObj := map[string]interface{}{
+		"agents": map[string]interface{}{
+			"defaults": map[string]interface{}{
+				"model": defaultModel,
+			},
+		},
 	}
 
-	if config, ok := ocData["config"].(map[string]interface{}); ok {
-		if agents, ok := config["agents"].(map[string]interface{}); ok {
-			if list, ok := agents["list"].([]interface{}); ok {
-				agentListPatch := make([]interface{}, len(list))
-				for i, a := range list {
-					agentMap, ok := a.(map[string]interface{})
-					if !ok {
-						agentListPatch[i] = a
-						continue
-					}
-
-					// Check if the agent has a model override
-					shouldPatch := false
-					if m, ok := agentMap["model"]; ok && m != nil {
-						// Be aggressive: if there is a model override, update it.
-						// This handles both string and object formats.
-						shouldPatch = true
-					}
-
-					if shouldPatch {
-						newAgent := make(map[string]interface{})
-						for k, v := range agentMap {
-							newAgent[k] = v
-						}
-						// Update model to match the format it was in (string or object)
-						if mObj, ok := agentMap["model"].(map[string]interface{}); ok {
-							// Clone the map to avoid mutating the original if we were reusing it
-							newModelObj := make(map[string]interface{})
-							for k, v := range mObj {
-								newModelObj[k] = v
-							}
-							newModelObj["primary"] = modelID
-							newAgent["model"] = newModelObj
-						} else {
-							newAgent["model"] = modelID
-						}
-						agentListPatch[i] = newAgent
-					} else {
-						agentListPatch[i] = a
-					}
-				}
-				patchObj["agents"].(map[string]interface{})["list"] = agentListPatch
-			}
-		}
+	// 2. Apply the per-agent rules to agents with their own model
+	if _, list := e.planAgents(ocData, modelID); list != nil {
+		patchObj["agents"].(map[string]interface{})["list"] = list
 	}
 
 	patchBytes, _ := json.Marshal(patchObj)
-	return e.PatchConfig(string(patchBytes))
+	return e.PatchConfig(inst, string(patchBytes))
 }
 
+// SwitchAccount moves email to the front of auth.order on every managed
+// installation.
 func (e *RotatorEngine) SwitchAccount(email string) error {
+	return e.switchAccount(email, "manual")
+}
+
+// switchAccount is SwitchAccount; a non-empty reason records the switch in
+// the rotation history.
+func (e *RotatorEngine) switchAccount(email, reason string) error {
+	return e.switchAccounts([]string{email}, reason)
+}
+
+// switchAccounts moves accounts, in this order, to the front of auth.order
+// on every managed installation. The first one becomes active.
+func (e *RotatorEngine) switchAccounts(accounts []string, reason string) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	profileKey := "google-antigravity:" + email
+	for _, inst := range e.installations() {
+		if err := e.switchAccountOn(inst, accounts); err != nil {
+			return fmt.Errorf("[%s] %v", inst.Name, err)
+		}
+	}
+	e.setActive(accounts[0])
+	e.schedulePolls()
+	if reason != "" {
+		e.recordSwitch(history.Entry{Account: accounts[0], Reason: reason})
+	}
+	return nil
+}
+
+func (e *RotatorEngine) switchAccountOn(inst config.Installation, accounts []string) error {
+	front := make(map[string]bool, len(accounts))
+	var profileKeys []string
+	for _, email := range accounts {
+		front["google-antigravity:"+email] = true
+		profileKeys = append(profileKeys, "google-antigravity:"+email)
+	}
 
 	// Deep patch to ensure auth.order is updated correctly
 	patchObj := map[string]interface{}{
 		"auth": map[string]interface{}{
 			"order": map[string]interface{}{
-				"google-antigravity": []string{profileKey},
+				"google-antigravity": profileKeys,
 			},
 		},
 	}
 
 	// Fetch current order to preserve other accounts
-	cmd := e.getOpenClawCmd("gateway", "call", "config.get", "--json")
-	output, err := cmd.Output()
-	if err == nil {
-		outputStr := string(output)
-		jsonStart := strings.Index(outputStr, "{")
-		if jsonStart != -1 {
-			var ocData map[string]interface{}
-			if err := json.Unmarshal([]byte(outputStr[jsonStart:]), &ocData); err == nil {
-				if config, ok := ocData["config"].(map[string]interface{}); ok {
-					if auth, ok := config["auth"].(map[string]interface{}); ok {
-						if order, ok := auth["order"].(map[string]interface{}); ok {
-							if gaOrder, ok := order["google-antigravity"].([]interface{}); ok {
-								newOrder := []string{profileKey}
-								for _, p := range gaOrder {
-									if ps, ok := p.(string); ok && ps != profileKey {
-										newOrder = append(newOrder, ps)
-									}
-								}
-								patchObj["auth"].(map[string]interface{})["order"].(map[string]interface{})["google-antigravity"] = newOrder
+	if ocData, err := e.gatewayConfig(inst); err == nil {
+		if config, ok := ocData["config"].(map[string]interface{}); ok {
+			if auth, ok := config["auth"].(map[string]interface{}); ok {
+				if order, ok := auth["order"].(map[string]interface{}); ok {
+					if gaOrder, ok := order["google-antigravity"].([]interface{}); ok {
+						newOrder := append([]string{}, profileKeys...)
+						for _, p := range gaOrder {
+							if ps, ok := p.(string); ok && !front[ps] {
+								newOrder = append(newOrder, ps)
 							}
 						}
+						patchObj["auth"].(map[string]interface{})["order"].(map[string]interface{})["google-antigravity"] = newOrder
 					}
 				}
 			}
@@ -431,81 +482,21 @@ func (e *RotatorEngine) SwitchAccount(email string) error {
 	}
 
 	patchBytes, _ := json.Marshal(patchObj)
-	return e.PatchConfig(string(patchBytes))
-}
-
-func (e *RotatorEngine) ImportAccounts(input []struct {
-	Email        string `json:"email"`
-	RefreshToken string `json:"refresh_token"`
-}) error {
-	e.mu.Lock()
-	defer e.mu.Unlock()
-
-	authPath, _ := e.getPaths()
-	authData, err := e.readJSON(authPath)
-	if err != nil {
-		return fmt.Errorf("读取凭据失败: %v", err)
-	}
-
-	profiles, ok := authData["profiles"].(map[string]interface{})
-	if !ok {
-		profiles = make(map[string]interface{})
-		authData["profiles"] = profiles
-	}
-
-	updated := false
-	for _, item := range input {
-		if item.Email == "" || item.RefreshToken == "" {
-			continue
-		}
-
-		profileKey := "google-antigravity:" + item.Email
-		profile, _ := profiles[profileKey].(map[string]interface{})
-		if profile == nil {
-			profile = make(map[string]interface{})
-			profile["provider"] = "google-antigravity"
-			profile["type"] = "oauth"
-			profiles[profileKey] = profile
-		}
-
-		profile["email"] = item.Email
-		profile["refresh"] = item.RefreshToken
-		updated = true
-
-		exists := false
-		for _, acc := range e.Config.Rotator.Accounts {
-			if acc == item.Email {
-				exists = true
-				break
-			}
-		}
-		if !exists {
-			e.Config.Rotator.Accounts = append(e.Config.Rotator.Accounts, item.Email)
-		}
-	}
-
-	if updated {
-		if err := e.writeJSON(authPath, authData); err != nil {
-			return fmt.Errorf("保存凭据失败: %v", err)
-		}
-		if err := config.SaveConfig(e.Config); err != nil {
-			return fmt.Errorf("保存配置失败: %v", err)
-		}
-		e.SyncAuthToAgents(authPath)
-	}
-
-	return nil
+	return e.PatchConfig(inst, string(patchBytes))
 }
 
+// GetVipEmail returns the account at the front of auth.order on the primary
+// installation.
 func (e *RotatorEngine) GetVipEmail() string {
-	cmd := e.getOpenClawCmd("gateway", "call", "config.get", "--json")
-	output, err := cmd.Output()
+	ocData, err := e.gatewayConfig(e.primary())
 	if err != nil {
 		return ""
 	}
-	var ocData map[string]interface{}
-	json.Unmarshal(output, &ocData)
+	return activeAccount(ocData)
+}
 
+// activeAccount returns the first google-antigravity account of auth.order.
+func activeAccount(ocData map[string]interface{}) string {
 	if config, ok := ocData["config"].(map[string]interface{}); ok {
 		if auth, ok := config["auth"].(map[string]interface{}); ok {
 			if order, ok := auth["order"].(map[string]interface{}); ok {
@@ -521,28 +512,35 @@ func (e *RotatorEngine) GetVipEmail() string {
 }
 
 func (e *RotatorEngine) RestartGateway() {
-	cmd := e.getOpenClawCmd("gateway", "restart")
-	cmd.Run()
+	for _, inst := range e.installations() {
+		cmd := e.getOpenClawCmd(inst, "gateway", "restart")
+		cmd.Run()
+	}
 }
 
+// GetAgents lists the agents of every managed installation.
 func (e *RotatorEngine) GetAgents() []AgentInfo {
-	cmd := e.getOpenClawCmd("gateway", "call", "config.get", "--json")
-	output, err := cmd.Output()
-	if err != nil {
-		return []AgentInfo{}
-	}
-
-	outputStr := string(output)
-	jsonStart := strings.Index(outputStr, "{")
-	if jsonStart == -1 {
-		return []AgentInfo{}
+	agents := []AgentInfo{}
+	insts := e.installations()
+	for _, inst := range insts {
+		list := e.getAgentsOn(inst)
+		if len(insts) > 1 {
+			for i := range list {
+				list[i].Installation = inst.Name
+			}
+		}
+		agents = append(agents, list...)
 	}
+	return agents
+}
 
-	var ocData map[string]interface{}
-	if err := json.Unmarshal([]byte(outputStr[jsonStart:]), &ocData); err != nil {
-		return []AgentInfo{}
+func (e *RotatorEngine) getAgentsOn(inst config.Installation) []AgentInfo {
+	ocData, err := e.gatewayConfig(inst)
+	if err != nil {
+		return nil
 	}
 
+	rules := e.Config().Rotator
 	var agents []AgentInfo
 	if config, ok := ocData["config"].(map[string]interface{}); ok {
 		if ags, ok := config["agents"].(map[string]interface{}); ok {
@@ -553,15 +551,9 @@ func (e *RotatorEngine) GetAgents() []AgentInfo {
 							ID:   am["id"].(string),
 							Name: am["name"].(string),
 						}
-						
-						// Extract Model
-						if m, ok := am["model"].(string); ok {
-							info.CurrentModel = m
-						} else if m, ok := am["model"].(map[string]interface{}); ok {
-							if p, ok := m["primary"].(string); ok {
-								info.CurrentModel = p
-							}
-						}
+
+						info.CurrentModel = agentModel(am)
+						info.Rule = rules.AgentRuleFor(info.ID).Mode
 
 						// Extract Identity Emoji
 						if idty, ok := am["identity"].(map[string]interface{}); ok {
@@ -589,10 +581,34 @@ func (e *RotatorEngine) readJSON(path string) (map[string]interface{}, error) {
 	return res, err
 }
 
+// writeJSON backs up path and atomically replaces it. Callers doing a
+// read-modify-write should hold the file lock, see updateJSON.
 func (e *RotatorEngine) writeJSON(path string, data interface{}) error {
 	bytes, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, bytes, 0644)
+	if _, err := fsutil.Backup(path); err != nil {
+		return fmt.Errorf("备份 %s 失败: %v", path, err)
+	}
+	return fsutil.WriteFileAtomic(path, bytes, 0644)
+}
+
+// updateJSON runs a locked read-modify-write of a JSON file. fn reports
+// whether it changed the document; unchanged documents are not rewritten.
+func (e *RotatorEngine) updateJSON(path string, fn func(data map[string]interface{}) (bool, error)) error {
+	return fsutil.WithLock(path, func() error {
+		data, err := e.readJSON(path)
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			data = make(map[string]interface{})
+		}
+		changed, err := fn(data)
+		if err != nil || !changed {
+			return err
+		}
+		return e.writeJSON(path, data)
+	})
 }
@@ -0,0 +1,832 @@
+package engine
+
+import (
+	"antigravity-rotator-v2/internal/config"
+	"antigravity-rotator-v2/internal/fake"
+	"antigravity-rotator-v2/internal/google"
+	"antigravity-rotator-v2/internal/importer"
+	"antigravity-rotator-v2/internal/scanner"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	pro   = "google-antigravity/gemini-3-pro-high"
+	flash = "google-antigravity/gemini-3-flash"
+)
+
+func TestMain(m *testing.M) {
+	fake.RunGatewayIfRequested()
+	os.Exit(m.Run())
+}
+
+type harness struct {
+	e       *RotatorEngine
+	google  *fake.Google
+	gateway *fake.Gateway
+	home    string
+}
+
+// newHarness starts an engine on a temporary OpenClaw home whose gateway and
+// Google endpoints are fakes. accounts are known to Google, written to the
+// auth file and listed in the config, first one active.
+func newHarness(t *testing.T, accounts ...fake.Account) *harness {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv(config.EnvOpenClawHome, home)
+	t.Setenv(config.EnvConfigPath, filepath.Join(home, "antigravity-rotator-v2.json"))
+
+	g := fake.NewGoogle()
+	t.Cleanup(g.Close)
+	saved := google.DefaultEndpoints
+	google.DefaultEndpoints = g.Endpoints()
+	t.Cleanup(func() { google.DefaultEndpoints = saved })
+
+	profiles := make(map[string]interface{})
+	var order []interface{}
+	cfg := config.DefaultConfig()
+	// Refresh every account on each cycle, so quota changes show at once.
+	cfg.Rotator.Polling.Tiered = false
+	for _, a := range accounts {
+		g.AddAccount(a)
+		profile := map[string]interface{}{
+			"provider": "google-antigravity",
+			"type":     "oauth",
+			"email":    a.Email,
+			"refresh":  a.Refresh,
+		}
+		if a.Project != "" {
+			profile["projectId"] = a.Project
+		}
+		profiles["google-antigravity:"+a.Email] = profile
+		order = append(order, "google-antigravity:"+a.Email)
+		cfg.Rotator.Accounts = append(cfg.Rotator.Accounts, a.Email)
+	}
+	writeFile(t, filepath.Join(home, "auth-profiles.json"), map[string]interface{}{"profiles": profiles})
+
+	gw := fake.NewGateway(t, home, map[string]interface{}{
+		"agents": map[string]interface{}{
+			"defaults": map[string]interface{}{"model": map[string]interface{}{"primary": flash}},
+		},
+		"auth": map[string]interface{}{
+			"order": map[string]interface{}{"google-antigravity": order},
+		},
+	})
+	cfg.Rotator.OpenClawBin = gw.Bin
+	return &harness{e: NewRotatorEngine(cfg), google: g, gateway: gw, home: home}
+}
+
+func writeFile(t *testing.T, path string, v interface{}) {
+	t.Helper()
+	data, _ := json.MarshalIndent(v, "", "  ")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func quotas(proFraction, flashFraction float64) map[string]fake.Quota {
+	return map[string]fake.Quota{
+		"gemini-3-pro-high": {Fraction: proFraction},
+		"gemini-3-pro-low":  {Fraction: proFraction},
+		"gemini-3-flash":    {Fraction: flashFraction},
+	}
+}
+
+// primaryModel returns agents.defaults.model.primary of the gateway config.
+func (h *harness) primaryModel(t *testing.T) string {
+	cfg := h.gateway.Config(t)
+	return modelPrimary(defaultsModel(map[string]interface{}{"config": cfg}))
+}
+
+// activeAccount returns the front of auth.order of the gateway config.
+func (h *harness) activeAccount(t *testing.T) string {
+	return activeAccount(map[string]interface{}{"config": h.gateway.Config(t)})
+}
+
+func (h *harness) authOrder(t *testing.T) []string {
+	cfg := h.gateway.Config(t)
+	order := cfg["auth"].(map[string]interface{})["order"].(map[string]interface{})["google-antigravity"].([]interface{})
+	out := make([]string, len(order))
+	for i, p := range order {
+		out[i] = strings.TrimPrefix(p.(string), "google-antigravity:")
+	}
+	return out
+}
+
+func TestRefreshStatus(t *testing.T) {
+	h := newHarness(t,
+		fake.Account{Email: "a@example.com", Refresh: "ra", Project: "pa", Quotas: quotas(0.8, 0.5)},
+		fake.Account{Email: "b@example.com", Refresh: "rb", Project: "pb", Quotas: map[string]fake.Quota{
+			"gemini-3-pro-high": {Fraction: 0.9},
+			"gemini-3-pro-low":  {Fraction: 0.3}, // limits the pro quota
+		}},
+	)
+	h.e.cfg.Rotator.RecordTrace = true
+	if err := h.e.RefreshStatus(); err != nil {
+		t.Fatal(err)
+	}
+
+	status := h.e.StatusSnapshot()
+	want := map[string]int{
+		"a@example.com:" + pro:   80,
+		"a@example.com:" + flash: 50,
+		"b@example.com:" + pro:   30,
+	}
+	for key, q := range want {
+		if status[key] != q {
+			t.Errorf("status[%s] = %d, want %d", key, status[key], q)
+		}
+	}
+	if _, ok := status["b@example.com:"+flash]; ok {
+		t.Errorf("b@example.com reports flash without quota")
+	}
+	if got := h.e.ModelCatalogs()["b@example.com"].Display[pro].LimitedBy; got != "gemini-3-pro-low" {
+		t.Errorf("pro limited by %q, want gemini-3-pro-low", got)
+	}
+	samples, err := h.e.QuotaTrace()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != len(want) {
+		t.Errorf("recorded %d trace samples, want %d", len(samples), len(want))
+	}
+}
+
+func TestRefreshStatusOnboardsAccountWithoutProject(t *testing.T) {
+	h := newHarness(t, fake.Account{Email: "new@example.com", Refresh: "rn", Quotas: quotas(1, 1)})
+	if err := h.e.RefreshStatus(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := h.e.StatusSnapshot()["new@example.com:"+pro]; got != 100 {
+		t.Errorf("quota after onboarding = %d, want 100", got)
+	}
+	if h.google.Calls(fake.EndpointOnboardUser) == 0 {
+		t.Errorf("onboardUser was not called")
+	}
+	profiles, err := h.e.loadProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile := profiles["google-antigravity:new@example.com"].(map[string]interface{})
+	if profile["projectId"] != h.google.Project("new@example.com") {
+		t.Errorf("profile projectId = %v, want %s", profile["projectId"], h.google.Project("new@example.com"))
+	}
+	tiers := h.e.AccountTiers()
+	if len(tiers) != 1 || !tiers[0].Onboarded || tiers[0].Tier != google.TierFree {
+		t.Errorf("tiers = %+v, want one onboarded free-tier account", tiers)
+	}
+}
+
+func TestRefreshStatusKeepsOtherAccountsOnErrors(t *testing.T) {
+	h := newHarness(t,
+		fake.Account{Email: "a@example.com", Refresh: "ra", Project: "pa", Quotas: quotas(0.8, 0.5)},
+		fake.Account{Email: "b@example.com", Refresh: "rb", Project: "pb", Quotas: quotas(0.6, 0.6)},
+	)
+	h.e.RefreshStatus()
+
+	// One account's quota request fails: its last known quota stays.
+	h.google.SetQuota("a@example.com", "gemini-3-flash", 0.1)
+	h.google.SetQuota("b@example.com", "gemini-3-flash", 0.2)
+	h.google.Fail(fake.EndpointModels, http.StatusInternalServerError, "backend error", 1)
+	h.e.RefreshStatus()
+
+	status := h.e.StatusSnapshot()
+	a, b := status["a@example.com:"+flash], status["b@example.com:"+flash]
+	if !(a == 50 && b == 20) && !(a == 10 && b == 60) {
+		t.Errorf("flash quotas = %d, %d; want exactly one account updated", a, b)
+	}
+
+	// Revoked tokens fail the refresh without touching the status.
+	h.google.Fail(fake.EndpointToken, http.StatusBadRequest, `{"error":"invalid_grant"}`, -1)
+	if err := h.e.RefreshStatus(); err != nil {
+		t.Fatal(err)
+	}
+	if got := h.e.StatusSnapshot(); len(got) != len(status) {
+		t.Errorf("status changed to %v after failed refresh", got)
+	}
+}
+
+func TestRunCycle(t *testing.T) {
+	h := newHarness(t,
+		fake.Account{Email: "a@example.com", Refresh: "ra", Project: "pa", Quotas: quotas(0.1, 0.9)},
+		fake.Account{Email: "b@example.com", Refresh: "rb", Project: "pb", Quotas: quotas(0.7, 0.4)},
+		fake.Account{Email: "c@example.com", Refresh: "rc", Project: "pc", Quotas: quotas(0.5, 0.5)},
+	)
+
+	msg := h.e.RunCycle()
+	if !strings.HasPrefix(msg, "Switched to "+pro) {
+		t.Fatalf("RunCycle = %q", msg)
+	}
+	if got := h.primaryModel(t); got != pro {
+		t.Errorf("primary model = %s, want %s", got, pro)
+	}
+	if got := h.authOrder(t); strings.Join(got, ",") != "b@example.com,a@example.com,c@example.com" {
+		t.Errorf("auth.order = %v, want b first and the rest kept", got)
+	}
+	entries, err := h.e.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Account != "b@example.com" || entries[0].Model != pro || entries[0].Quota != 70 || entries[0].Reason != "manual" {
+		t.Errorf("history = %+v", entries)
+	}
+
+	// Pro runs out everywhere: fall back to flash on its best account.
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		h.google.SetQuota(email, "gemini-3-pro-high", 0.1)
+	}
+	if msg := h.e.RunCycle(); !strings.HasPrefix(msg, "Switched to "+flash) {
+		t.Fatalf("RunCycle = %q", msg)
+	}
+	if got := h.primaryModel(t); got != flash {
+		t.Errorf("primary model = %s, want %s", got, flash)
+	}
+	if got := h.activeAccount(t); got != "a@example.com" {
+		t.Errorf("active account = %s, want a@example.com", got)
+	}
+}
+
+func TestRunCycleBalanceSlice(t *testing.T) {
+	h := newHarness(t,
+		fake.Account{Email: "a@example.com", Refresh: "ra", Project: "pa", Quotas: quotas(0.5, 0.5)},
+		fake.Account{Email: "b@example.com", Refresh: "rb", Project: "pb", Quotas: quotas(0.7, 0.5)},
+		fake.Account{Email: "c@example.com", Refresh: "rc", Project: "pc", Quotas: quotas(0.1, 0.5)},
+		fake.Account{Email: "d@example.com", Refresh: "rd", Project: "pd", Quotas: quotas(0.9, 0.5)},
+	)
+	h.e.cfg.Rotator.Balance = config.BalanceConfig{Mode: config.BalanceSlice, Slice: 10}
+
+	cycle := func(wantOrder string) {
+		t.Helper()
+		if msg := h.e.RunCycle(); !strings.HasPrefix(msg, "Switched to "+pro) {
+			t.Fatalf("RunCycle = %q", msg)
+		}
+		if got := strings.Join(h.authOrder(t), ","); got != wantOrder {
+			t.Errorf("auth.order = %s, want %s", got, wantOrder)
+		}
+	}
+	// The healthy accounts by quota, c below the threshold kept last.
+	cycle("d@example.com,b@example.com,a@example.com,c@example.com")
+	// d stays until it has used its slice of 10%.
+	h.google.SetQuota("d@example.com", "gemini-3-pro-high", 0.82)
+	cycle("d@example.com,b@example.com,a@example.com,c@example.com")
+	h.google.SetQuota("d@example.com", "gemini-3-pro-high", 0.78)
+	cycle("b@example.com,d@example.com,a@example.com,c@example.com")
+}
+
+func TestRunCycleBalanceWeighted(t *testing.T) {
+	h := newHarness(t,
+		fake.Account{Email: "a@example.com", Refresh: "ra", Project: "pa", Quotas: quotas(0.81, 0.5)},
+		fake.Account{Email: "b@example.com", Refresh: "rb", Project: "pb", Quotas: quotas(0.41, 0.5)},
+	)
+	h.e.cfg.Rotator.Balance = config.BalanceConfig{Mode: config.BalanceWeighted}
+	h.e.cfg.Rotator.Quotas.Low = 21
+
+	// Weights 60 and 20: a takes three of every four cycles.
+	var picks []string
+	for i := 0; i < 4; i++ {
+		d, ok, err := h.e.Rotate()
+		if !ok || err != nil {
+			t.Fatalf("Rotate = %v, %v", ok, err)
+		}
+		if got := h.activeAccount(t); got != d.Account || len(d.Order) != 2 {
+			t.Errorf("decision %+v applied as %s", d, got)
+		}
+		picks = append(picks, d.Account)
+	}
+	if got := strings.Join(picks, ","); got != "a@example.com,a@example.com,b@example.com,a@example.com" {
+		t.Errorf("picks = %s", got)
+	}
+}
+
+func TestRunCycleWithoutHealthyModel(t *testing.T) {
+	h := newHarness(t, fake.Account{Email: "a@example.com", Refresh: "ra", Project: "pa", Quotas: quotas(0.2, 0.1)})
+
+	if msg := h.e.RunCycle(); msg != "No healthy models found above threshold" {
+		t.Errorf("RunCycle = %q", msg)
+	}
+	for _, call := range h.gateway.Calls() {
+		if call == "config.patch" {
+			t.Errorf("gateway was patched without a healthy model")
+		}
+	}
+}
+
+func TestRunCycleGatewayError(t *testing.T) {
+	h := newHarness(t, fake.Account{Email: "a@example.com", Refresh: "ra", Project: "pa", Quotas: quotas(0.9, 0.9)})
+	h.gateway.FailNext("config.patch")
+
+	if msg := h.e.RunCycle(); !strings.HasPrefix(msg, "Error switching model") {
+		t.Errorf("RunCycle = %q, want model switch error", msg)
+	}
+	if entries, _ := h.e.History(); len(entries) != 0 {
+		t.Errorf("failed cycle recorded history %+v", entries)
+	}
+}
+
+func TestSwitchModel(t *testing.T) {
+	h := newHarness(t, fake.Account{Email: "a@example.com", Refresh: "ra", Project: "pa"})
+
+	if err := h.e.SwitchModel(pro); err != nil {
+		t.Fatal(err)
+	}
+	if got := h.primaryModel(t); got != pro {
+		t.Errorf("primary model = %s, want %s", got, pro)
+	}
+	entries, _ := h.e.History()
+	if len(entries) != 1 || entries[0].Model != pro || entries[0].Reason != "manual" {
+		t.Errorf("history = %+v", entries)
+	}
+
+	h.gateway.FailNext("config.get")
+	if err := h.e.SwitchModel(flash); err == nil {
+		t.Errorf("SwitchModel succeeded with the gateway down")
+	}
+	if got := h.primaryModel(t); got != pro {
+		t.Errorf("primary model = %s after failed switch, want %s", got, pro)
+	}
+}
+
+func TestPlanAgentsKeepsOwnModel(t *testing.T) {
+	h := newHarness(t, fake.Account{Email: "a@example.com", Refresh: "ra", Project: "pa"})
+	ocData := map[string]interface{}{"config": map[string]interface{}{
+		"agents": map[string]interface{}{"list": []interface{}{
+			map[string]interface{}{"id": "main"},
+			map[string]interface{}{"id": "ops", "model": flash},
+		}},
+	}}
+
+	changes, patch := h.e.planAgents(ocData, pro)
+	if patch != nil || changes[0].Action != AgentInherit || changes[1].Action != AgentKeep || changes[1].To != flash {
+		t.Errorf("without rules: changes = %+v, patch = %v", changes, patch)
+	}
+
+	h.e.cfg.Rotator.AgentRules = map[string]config.AgentRule{"ops": {Mode: config.AgentFollow}}
+	changes, patch = h.e.planAgents(ocData, pro)
+	if patch == nil || changes[1].Action != AgentPatch || changes[1].To != pro {
+		t.Errorf("with follow rule: changes = %+v, patch = %v", changes, patch)
+	}
+}
+
+func TestSwitchAccount(t *testing.T) {
+	h := newHarness(t,
+		fake.Account{Email: "a@example.com", Refresh: "ra", Project: "pa"},
+		fake.Account{Email: "b@example.com", Refresh: "rb", Project: "pb"},
+		fake.Account{Email: "c@example.com", Refresh: "rc", Project: "pc"},
+	)
+
+	if err := h.e.SwitchAccount("c@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if got := h.authOrder(t); strings.Join(got, ",") != "c@example.com,a@example.com,b@example.com" {
+		t.Errorf("auth.order = %v", got)
+	}
+	if got := h.e.GetVipEmail(); got != "c@example.com" {
+		t.Errorf("GetVipEmail = %s, want c@example.com", got)
+	}
+	entries, _ := h.e.History()
+	if len(entries) != 1 || entries[0].Account != "c@example.com" || entries[0].Reason != "manual" {
+		t.Errorf("history = %+v", entries)
+	}
+}
+
+func TestImportCredentials(t *testing.T) {
+	h := newHarness(t, fake.Account{Email: "a@example.com", Refresh: "ra", Project: "pa"})
+	h.google.AddAccount(fake.Account{Email: "new@example.com", Refresh: "rn", Project: "pn"})
+	h.google.AddAccount(fake.Account{Email: "anon@example.com", Refresh: "rx", Project: "px"})
+
+	entries := []importer.Entry{
+		{Source: "row 1", Email: "a@example.com", RefreshToken: "ra"},
+		{Source: "row 2", Email: "new@example.com", RefreshToken: "rn", ProjectID: "pn"},
+		{Source: "row 3", RefreshToken: "rx"}, // email looked up
+		{Source: "row 4", Email: "new@example.com", RefreshToken: "rn"},
+		{Source: "row 5", Email: "gone@example.com", RefreshToken: "revoked"},
+	}
+	problems := []importer.Problem{{Source: "row 6", Reason: "无法解析"}}
+	report, err := h.e.ImportCredentials(entries, problems, ImportOptions{Validate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Added != 2 || report.Duplicates != 2 || report.Skipped != 2 || report.Updated != 0 {
+		t.Errorf("report = %+v", report)
+	}
+
+	accounts := h.e.Config().Rotator.Accounts
+	if strings.Join(accounts, ",") != "a@example.com,new@example.com,anon@example.com" {
+		t.Errorf("accounts = %v", accounts)
+	}
+	profiles, _ := h.e.loadProfiles()
+	if p, ok := profiles["google-antigravity:anon@example.com"].(map[string]interface{}); !ok || p["refresh"] != "rx" {
+		t.Errorf("anon profile = %v", profiles["google-antigravity:anon@example.com"])
+	}
+	if _, ok := profiles["google-antigravity:gone@example.com"]; ok {
+		t.Errorf("revoked token was imported")
+	}
+	saved, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Rotator.Accounts) != 3 {
+		t.Errorf("saved accounts = %v", saved.Rotator.Accounts)
+	}
+
+	// The imported accounts take part in the next refresh.
+	if err := h.e.RefreshStatus(); err != nil {
+		t.Fatal(err)
+	}
+	if h.google.Calls(fake.EndpointModels) != 3 {
+		t.Errorf("fetchAvailableModels called %d times, want 3", h.google.Calls(fake.EndpointModels))
+	}
+}
+
+func TestImportCredentialsDryRun(t *testing.T) {
+	h := newHarness(t, fake.Account{Email: "a@example.com", Refresh: "ra", Project: "pa"})
+	h.google.AddAccount(fake.Account{Email: "new@example.com", Refresh: "rn"})
+
+	entries := []importer.Entry{{Source: "row 1", Email: "new@example.com", RefreshToken: "rn"}}
+	report, err := h.e.ImportCredentials(entries, nil, ImportOptions{Validate: true, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Added != 1 || !report.DryRun {
+		t.Errorf("report = %+v", report)
+	}
+	if len(h.e.Config().Rotator.Accounts) != 1 {
+		t.Errorf("dry run changed accounts to %v", h.e.Config().Rotator.Accounts)
+	}
+}
+
+func TestUsageAttributedToActiveAccount(t *testing.T) {
+	h := newHarness(t,
+		fake.Account{Email: "a@example.com", Refresh: "ra", Project: "pa"},
+		fake.Account{Email: "b@example.com", Refresh: "rb", Project: "pb"},
+	)
+	if err := h.e.SwitchAccount("b@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Now().Add(time.Second).UTC().Format(time.RFC3339Nano)
+	session := filepath.Join(h.home, "agents", "main", "sessions", "s1.jsonl")
+	if err := os.MkdirAll(filepath.Dir(session), 0755); err != nil {
+		t.Fatal(err)
+	}
+	line := `{"type":"message","message":{"role":"assistant","provider":"google-antigravity","model":"gemini-3-flash","usage":{"input":30,"output":12},"timestamp":"` + at + `"}}` + "\n"
+	if err := os.WriteFile(session, []byte(line), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []int{1, 0} {
+		res, err := h.e.IngestUsage(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Files != 1 || res.Events != 1 || res.Added != want {
+			t.Errorf("ingest = %+v, want %d added", res, want)
+		}
+	}
+	report, err := h.e.UsageReport(UsageQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Totals) != 1 || report.Totals[0].Key != "b@example.com" || report.Totals[0].TotalTokens != 42 {
+		t.Errorf("totals = %+v", report.Totals)
+	}
+	if report, _ := h.e.UsageReport(UsageQuery{By: "agent"}); len(report.Totals) != 1 || report.Totals[0].Key != "main" {
+		t.Errorf("agent totals = %+v", report.Totals)
+	}
+	if _, err := h.e.IngestUsage([]string{filepath.Join(h.home, "missing.log")}); err == nil {
+		t.Errorf("missing log accepted")
+	}
+}
+
+func TestApplyConfigPollingAndBalance(t *testing.T) {
+	h := newHarness(t, fake.Account{Email: "a@example.com", Refresh: "ra", Project: "pa"})
+	h.e.balance = balanceState{account: "a@example.com", model: pro, start: 80}
+
+	cfg := *h.e.Config()
+	cfg.Rotator.Polling.Tiered = true
+	cfg.Rotator.Balance.Mode = config.BalanceSlice
+	change, err := h.e.ApplyConfig(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !change.ScheduleChanged || !change.BalanceChanged {
+		t.Errorf("change = %+v, want schedule and balance changed", change)
+	}
+	if h.e.balance.account != "" {
+		t.Errorf("balance state kept across a mode change: %+v", h.e.balance)
+	}
+
+	same := *h.e.Config()
+	if change, _ := h.e.ApplyConfig(&same); change.ScheduleChanged || change.BalanceChanged {
+		t.Errorf("unchanged config reported %+v", change)
+	}
+}
+
+func TestTriggerRotationWhilePaused(t *testing.T) {
+	h := newHarness(t, fake.Account{Email: "a@example.com", Refresh: "ra", Project: "pa"})
+	if err := h.e.TriggerRotation("manual"); err == nil || !strings.Contains(err.Error(), "未开启") {
+		t.Errorf("TriggerRotation with auto-rotation off = %v", err)
+	}
+	h.e.trig.mu.Lock()
+	defer h.e.trig.mu.Unlock()
+	if h.e.trig.pending != nil {
+		t.Errorf("rejected trigger was scheduled")
+	}
+}
+
+func TestWebhookSurvivesLoopRestart(t *testing.T) {
+	h := newHarness(t, fake.Account{Email: "a@example.com", Refresh: "ra", Project: "pa"})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	h.e.cfg.Rotator.AutoRotate = true
+	h.e.cfg.Rotator.Triggers.WebhookAddr = addr
+	h.e.cfg.Rotator.Triggers.WebhookToken = "secret"
+	t.Cleanup(h.e.StopAutoLoop)
+
+	// Restarting the loop re-listens on the same address right away.
+	h.e.StartAutoLoop()
+	h.e.StartAutoLoop()
+	resp, err := http.Post("http://"+addr+"/trigger", "application/json", nil)
+	if err != nil {
+		t.Fatalf("webhook down after restart: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("unauthenticated trigger answered %d", resp.StatusCode)
+	}
+
+	h.e.StopAutoLoop()
+	if _, err := http.Post("http://"+addr+"/trigger", "application/json", nil); err == nil {
+		t.Errorf("webhook still up after the loop stopped")
+	}
+}
+
+func TestGetWorkspacesLayouts(t *testing.T) {
+	h := newHarness(t, fake.Account{Email: "a@example.com", Refresh: "ra"})
+	auth := map[string]interface{}{"profiles": map[string]interface{}{
+		"google-antigravity:a@example.com": map[string]interface{}{"refresh": "ra"},
+	}}
+	for _, dir := range []string{"agents/ops", "agents/dev/agent"} {
+		os.MkdirAll(filepath.Join(h.home, dir, "sessions"), 0755)
+		writeFile(t, filepath.Join(h.home, dir, "auth-profiles.json"), auth)
+	}
+
+	found := make(map[string]string)
+	for _, ws := range h.e.GetWorkspaces() {
+		rel, _ := filepath.Rel(h.home, ws.Path)
+		found[filepath.ToSlash(rel)] = ws.AgentID
+		if !ws.Primary && (ws.Drift == nil || !ws.Drift.InSync()) {
+			t.Errorf("%s drift = %+v", rel, ws.Drift)
+		}
+	}
+	if len(found) != 3 || found["."] != "" || found["agents/ops"] != "ops" || found["agents/dev/agent"] != "dev" {
+		t.Errorf("workspaces = %v", found)
+	}
+
+	config.SetOpenClawHome(h.home)
+	defer config.SetOpenClawHome("")
+	if inst := h.e.Config().ResolvedInstallations()[0]; !inst.Custom() {
+		t.Errorf("--openclaw-home installation is not passed to openclaw")
+	}
+}
+
+func TestDriftFollowsSyncWinner(t *testing.T) {
+	h := newHarness(t)
+	profile := func(refresh string, expires int) map[string]interface{} {
+		return map[string]interface{}{"provider": "google-antigravity", "refresh": refresh, "expires": expires}
+	}
+	writeFile(t, filepath.Join(h.home, "auth-profiles.json"), map[string]interface{}{"profiles": map[string]interface{}{
+		"google-antigravity:a@example.com": profile("ra", 100),
+		"google-antigravity:b@example.com": profile("rb", 100),
+	}})
+	ws := filepath.Join(h.home, "agents", "ops", "agent")
+	os.MkdirAll(ws, 0755)
+	writeFile(t, filepath.Join(ws, "auth-profiles.json"), map[string]interface{}{"profiles": map[string]interface{}{
+		"google-antigravity:a@example.com": profile("ra2", 200),
+		"google-antigravity:b@example.com": profile("rb0", 50),
+		"google-antigravity:c@example.com": profile("rc", 100), // agent-only
+	}})
+
+	drift := func() *scanner.Drift {
+		for _, wp := range h.e.GetWorkspaces() {
+			if wp.Path == ws {
+				return wp.Drift
+			}
+		}
+		t.Fatal("workspace not found")
+		return nil
+	}
+	d := drift()
+	if strings.Join(d.DifferentTokens, ",") != "b@example.com" || strings.Join(d.NewerTokens, ",") != "a@example.com" ||
+		strings.Join(d.ExtraAccounts, ",") != "c@example.com" {
+		t.Fatalf("drift = %+v", d)
+	}
+
+	plans, err := h.e.PreviewSync()
+	if err != nil || len(plans) != 1 {
+		t.Fatalf("plans %+v, %v", plans, err)
+	}
+	actions := make(map[string]string)
+	for _, c := range plans[0].Changes {
+		actions[c.Profile] = c.Action
+	}
+	if actions["google-antigravity:a@example.com"] != SyncKeep || actions["google-antigravity:b@example.com"] != SyncUpdate {
+		t.Errorf("changes = %+v", plans[0].Changes)
+	}
+
+	if _, err := h.e.FixDrift(ws); err != nil {
+		t.Fatal(err)
+	}
+	if d := drift(); !d.InSync() || len(d.NewerTokens) != 1 || len(d.ExtraAccounts) != 1 {
+		t.Errorf("drift after fix = %+v", d)
+	}
+}
+
+func TestReloadConfigIgnoresEmptyCollections(t *testing.T) {
+	h := newHarness(t, fake.Account{Email: "a@example.com", Refresh: "ra"})
+
+	// The GUI sends empty lists and objects where the file has none.
+	cfg := h.e.Config().Clone()
+	cfg.LastScan = []string{}
+	cfg.Rotator.Schedules = []config.Schedule{}
+	cfg.Rotator.AccountProxies = map[string]config.ProxyConfig{}
+	cfg.Rotator.Installations = []config.Installation{}
+	if _, err := h.e.ApplyConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.SaveConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change := diffConfig(cfg, loaded); change.ScheduleChanged || change.ProxyChanged || change.InstallationsChanged {
+		t.Errorf("empty collections reported as %+v", change)
+	}
+	if err := h.e.ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if h.e.Config() != cfg {
+		t.Errorf("unchanged config was applied again")
+	}
+
+	cfg = cfg.Clone()
+	cfg.Rotator.Quotas.Low += 5
+	if err := config.SaveConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.e.ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if h.e.Config().Rotator.Quotas.Low != cfg.Rotator.Quotas.Low {
+		t.Errorf("threshold edit was not applied")
+	}
+}
+
+func TestReloadConfigReportsMigration(t *testing.T) {
+	h := newHarness(t, fake.Account{Email: "a@example.com", Refresh: "ra"})
+
+	// An unversioned file written before the interval was ever set.
+	var doc map[string]interface{}
+	data, _ := json.Marshal(h.e.Config())
+	json.Unmarshal(data, &doc)
+	delete(doc, "version")
+	doc["rotator"].(map[string]interface{})["rotateInterval"] = 0
+	writeFile(t, config.GetConfigPath(), doc)
+	var notices config.MigrationNotices
+	if err := h.e.ReloadConfig(); !errors.As(err, &notices) || len(notices) != 1 {
+		t.Fatalf("ReloadConfig = %v, want a migration notice", err)
+	}
+	if got := h.e.Config().Rotator.RotateInterval; got != 10 {
+		t.Errorf("rotateInterval = %d, want 10", got)
+	}
+}
+
+func TestRotateOnboardsOutsideLock(t *testing.T) {
+	h := newHarness(t, fake.Account{Email: "new@example.com", Refresh: "rn", Quotas: quotas(1, 1)})
+	h.google.OnboardPolls = 3
+	h.e.client.PollInterval = 200 * time.Millisecond
+
+	var d Decision
+	var ok bool
+	var err error
+	done := make(chan struct{})
+	go func() {
+		d, ok, err = h.e.Rotate()
+		close(done)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for h.google.Calls(fake.EndpointOnboardUser) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("onboarding did not start")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	read := make(chan struct{})
+	go func() {
+		h.e.StatusSnapshot()
+		close(read)
+	}()
+	select {
+	case <-read:
+	case <-time.After(150 * time.Millisecond):
+		t.Errorf("status is locked while the account is onboarded")
+	}
+
+	<-done
+	if err != nil || !ok || d.Account != "new@example.com" {
+		t.Errorf("rotate = %+v, %v, %v", d, ok, err)
+	}
+}
+
+func TestRestoreBundleRemovingAccount(t *testing.T) {
+	h := newHarness(t,
+		fake.Account{Email: "a@example.com", Refresh: "ra", Project: "pa", Quotas: quotas(0.8, 0.8)},
+		fake.Account{Email: "b@example.com", Refresh: "rb", Project: "pb", Quotas: quotas(0.6, 0.6)},
+	)
+	if err := h.e.RefreshStatus(); err != nil {
+		t.Fatal(err)
+	}
+	full := h.e.Config()
+	only := full.Clone()
+	only.Rotator.Accounts = []string{"a@example.com"}
+	if _, err := h.e.ApplyConfig(only); err != nil {
+		t.Fatal(err)
+	}
+	data, err := h.e.ExportBundle("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.e.ApplyConfig(full); err != nil {
+		t.Fatal(err)
+	}
+	h.e.RefreshStatus()
+
+	done := make(chan error)
+	go func() {
+		plan, err := h.e.RestoreBundle(data, "")
+		if err == nil && strings.Join(plan.AccountsRemoved, ",") != "b@example.com" {
+			t.Errorf("plan = %+v", plan)
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("restore did not finish")
+	}
+	if accounts := h.e.Config().Rotator.Accounts; strings.Join(accounts, ",") != "a@example.com" {
+		t.Errorf("accounts = %v", accounts)
+	}
+	for key := range h.e.StatusSnapshot() {
+		if strings.HasPrefix(key, "b@example.com:") {
+			t.Errorf("status of removed account kept: %s", key)
+		}
+	}
+}
+
+func TestConcurrentRefreshes(t *testing.T) {
+	h := newHarness(t,
+		fake.Account{Email: "a@example.com", Refresh: "ra", Project: "pa", Quotas: quotas(0.8, 0.8)},
+		fake.Account{Email: "b@example.com", Refresh: "rb", Project: "pb", Quotas: quotas(0.6, 0.6)},
+	)
+	// Manual refreshes, the tiered poller and rotations all write the
+	// status; run them together under -race.
+	done := make(chan struct{})
+	for _, refresh := range []func(){
+		func() { h.e.RefreshStatus() },
+		func() { h.e.refreshDue() },
+		func() { h.e.PlanRotation() },
+		func() { h.e.StatusSnapshot() },
+	} {
+		go func(refresh func()) {
+			for i := 0; i < 5; i++ {
+				refresh()
+			}
+			done <- struct{}{}
+		}(refresh)
+	}
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+	if got := h.e.StatusSnapshot()["b@example.com:"+flash]; got != 60 {
+		t.Errorf("flash quota of b = %d, want 60", got)
+	}
+}
@@ -0,0 +1,11 @@
+package engine
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// hideWindow keeps gateway calls from flashing a console window.
+func hideWindow(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+}
@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"antigravity-rotator-v2/internal/config"
+	"antigravity-rotator-v2/internal/scanner"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// installations returns the OpenClaw installations managed by the engine.
+func (e *RotatorEngine) installations() []config.Installation {
+	return e.Config().ResolvedInstallations()
+}
+
+// primary returns the installation whose auth file is the source of truth.
+func (e *RotatorEngine) primary() config.Installation {
+	return e.installations()[0]
+}
+
+// getPaths returns the primary installation's auth file and gateway config.
+func (e *RotatorEngine) getPaths() (string, string) {
+	inst := e.primary()
+	return inst.AuthPath(), inst.GatewayConfigPath()
+}
+
+func (e *RotatorEngine) getOpenClawCmd(inst config.Installation, args ...string) *exec.Cmd {
+	cmd := exec.Command(inst.Bin(), args...)
+	hideWindow(cmd)
+	if inst.Custom() {
+		cmd.Env = append(os.Environ(),
+			config.EnvOpenClawHome+"="+inst.Home,
+			"OPENCLAW_CONFIG_PATH="+inst.GatewayConfigPath(),
+		)
+	}
+	return cmd
+}
+
+// gatewayConfig runs config.get against the installation's gateway and
+// returns the decoded response ({"hash": ..., "config": {...}}).
+func (e *RotatorEngine) gatewayConfig(inst config.Installation) (map[string]interface{}, error) {
+	cmd := e.getOpenClawCmd(inst, "gateway", "call", "config.get", "--json")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("获取网关配置失败: %v", err)
+	}
+
+	// 过滤非 JSON 输出（例如插件加载日志）
+	outputStr := string(output)
+	jsonStart := strings.Index(outputStr, "{")
+	if jsonStart == -1 {
+		return nil, fmt.Errorf("未在输出中找到 JSON 数据: %s", outputStr)
+	}
+	outputStr = outputStr[jsonStart:]
+
+	var ocData map[string]interface{}
+	if err := json.Unmarshal([]byte(outputStr), &ocData); err != nil {
+		return nil, fmt.Errorf("解析网关配置失败: %v (原始内容: %s)", err, outputStr)
+	}
+	return ocData, nil
+}
+
+func (e *RotatorEngine) GetConfigHash(inst config.Installation) (string, error) {
+	ocData, err := e.gatewayConfig(inst)
+	if err != nil {
+		return "", fmt.Errorf("获取配置 Hash 失败: %v", err)
+	}
+	hash, _ := ocData["hash"].(string)
+	return hash, nil
+}
+
+func (e *RotatorEngine) PatchConfig(inst config.Installation, patch string) error {
+	hash, err := e.GetConfigHash(inst)
+	if err != nil {
+		return err
+	}
+
+	params := fmt.Sprintf(`{"raw":%q, "baseHash":%q, "restartDelayMs":100}`, patch, hash)
+	cmd := e.getOpenClawCmd(inst, "gateway", "call", "config.patch", "--params", params)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("执行 config.patch 失败: %v (输出: %s)", err, string(output))
+	}
+	return nil
+}
+
+// loadProfiles merges the auth profiles of all installations, the primary
+// installation taking precedence.
+func (e *RotatorEngine) loadProfiles() (map[string]interface{}, error) {
+	insts := e.installations()
+	profiles := make(map[string]interface{})
+	for i := len(insts) - 1; i >= 0; i-- {
+		authData, err := e.readJSON(insts[i].AuthPath())
+		if err != nil {
+			if i == 0 {
+				return nil, fmt.Errorf("读取凭据失败: %v", err)
+			}
+			continue
+		}
+		p, ok := authData["profiles"].(map[string]interface{})
+		if !ok {
+			if i == 0 {
+				return nil, fmt.Errorf("profiles 字段不存在")
+			}
+			continue
+		}
+		for k, v := range p {
+			profiles[k] = v
+		}
+	}
+	return profiles, nil
+}
+
+// GetWorkspaces scans the workspaces of every managed installation and
+// reports their drift from the installation's primary auth file.
+func (e *RotatorEngine) GetWorkspaces() []scanner.WorkspaceInfo {
+	workspaces := []scanner.WorkspaceInfo{}
+	for _, inst := range e.installations() {
+		ws, _ := scanner.ScanWorkspaces(inst.Home)
+		scanner.CheckDrift(ws, inst.AuthPath())
+		workspaces = append(workspaces, ws...)
+	}
+	return workspaces
+}
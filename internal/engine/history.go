@@ -0,0 +1,26 @@
+package engine
+
+import (
+	"antigravity-rotator-v2/internal/history"
+	"fmt"
+	"time"
+)
+
+// historyPath is the rotation history of the primary installation.
+func (e *RotatorEngine) historyPath() string {
+	return history.Path(e.primary().Home)
+}
+
+// recordSwitch appends a switch to the rotation history. Failures are only
+// logged; a missing history entry must not fail a rotation.
+func (e *RotatorEngine) recordSwitch(entry history.Entry) {
+	entry.Time = time.Now()
+	if err := history.Append(e.historyPath(), entry); err != nil {
+		fmt.Printf("Failed to record rotation history: %v\n", err)
+	}
+}
+
+// History returns the recorded switches, oldest first.
+func (e *RotatorEngine) History() ([]history.Entry, error) {
+	return history.Read(e.historyPath())
+}
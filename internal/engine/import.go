@@ -0,0 +1,232 @@
+package engine
+
+import (
+	"antigravity-rotator-v2/internal/config"
+	"antigravity-rotator-v2/internal/importer"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Outcomes of one import row.
+const (
+	ImportAdded     = "added"
+	ImportUpdated   = "updated"
+	ImportSkipped   = "skipped"
+	ImportDuplicate = "duplicate"
+)
+
+// importConcurrency bounds the parallel token validations.
+const importConcurrency = 8
+
+// ImportOptions controls ImportCredentials.
+type ImportOptions struct {
+	// Validate refreshes every token before it is written, and looks up
+	// the email of credentials that do not carry one.
+	Validate bool `json:"validate"`
+	// DryRun only reports what would happen.
+	DryRun bool `json:"dryRun"`
+}
+
+// ImportRow is the outcome of one credential in an import.
+type ImportRow struct {
+	Source string `json:"source"`
+	Email  string `json:"email"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// ImportReport lists every row of an import with its outcome.
+type ImportReport struct {
+	Rows       []ImportRow `json:"rows"`
+	Added      int         `json:"added"`
+	Updated    int         `json:"updated"`
+	Skipped    int         `json:"skipped"`
+	Duplicates int         `json:"duplicates"`
+	DryRun     bool        `json:"dryRun"`
+}
+
+func (r *ImportReport) add(row ImportRow) {
+	switch row.Status {
+	case ImportAdded:
+		r.Added++
+	case ImportUpdated:
+		r.Updated++
+	case ImportSkipped:
+		r.Skipped++
+	case ImportDuplicate:
+		r.Duplicates++
+	}
+	r.Rows = append(r.Rows, row)
+}
+
+// ImportCredentials writes the parsed credentials into every installation
+// and the account list, and reports per row what happened. Rows that could
+// not be parsed are reported as skipped.
+func (e *RotatorEngine) ImportCredentials(entries []importer.Entry, problems []importer.Problem, opts ImportOptions) (ImportReport, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	report := ImportReport{Rows: []ImportRow{}, DryRun: opts.DryRun}
+	for _, p := range problems {
+		report.add(ImportRow{Source: p.Source, Status: ImportSkipped, Reason: p.Reason})
+	}
+
+	rows := make([]ImportRow, len(entries))
+	for i, en := range entries {
+		rows[i] = ImportRow{Source: en.Source, Email: en.Email}
+		if en.RefreshToken == "" {
+			rows[i].Status, rows[i].Reason = ImportSkipped, "缺少 refresh token"
+		} else if en.Email == "" && !opts.Validate {
+			rows[i].Status, rows[i].Reason = ImportSkipped, "缺少邮箱"
+		} else if en.Email != "" && !strings.Contains(en.Email, "@") {
+			rows[i].Status, rows[i].Reason = ImportSkipped, fmt.Sprintf("无效的邮箱 %q", en.Email)
+		}
+	}
+
+	if opts.Validate {
+		e.validateEntries(entries, rows)
+	}
+
+	// Compare with what the primary installation already has, and with
+	// earlier rows of the same import.
+	profiles, _ := e.loadProfiles()
+	seen := make(map[string]int)
+	var accepted []importer.Entry
+	for i := range entries {
+		en, row := &entries[i], &rows[i]
+		if row.Status != "" {
+			continue
+		}
+		if first, ok := seen[en.Email]; ok {
+			row.Status, row.Reason = ImportDuplicate, "与 "+rows[first].Source+" 重复"
+			continue
+		}
+		seen[en.Email] = i
+
+		existing, _ := profiles["google-antigravity:"+en.Email].(map[string]interface{})
+		switch {
+		case existing == nil:
+			row.Status = ImportAdded
+		case existing["refresh"] == en.RefreshToken:
+			if e.hasAccount(en.Email) {
+				row.Status, row.Reason = ImportDuplicate, "凭据已存在"
+				continue
+			}
+			row.Status, row.Reason = ImportAdded, "凭据已存在，加入账号列表"
+		default:
+			row.Status = ImportUpdated
+		}
+		accepted = append(accepted, *en)
+	}
+	for _, row := range rows {
+		report.add(row)
+	}
+
+	if opts.DryRun || len(accepted) == 0 {
+		return report, nil
+	}
+	if err := e.commitCredentials(accepted); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// validateEntries refreshes each pending token and fills in missing emails.
+// Rows that fail are marked skipped.
+func (e *RotatorEngine) validateEntries(entries []importer.Entry, rows []ImportRow) {
+	sem := make(chan struct{}, importConcurrency)
+	var wg sync.WaitGroup
+	for i := range entries {
+		if rows[i].Status != "" {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(en *importer.Entry, row *ImportRow) {
+			defer func() { <-sem; wg.Done() }()
+			client := e.clientFor(en.Email)
+			access, err := client.RefreshAccessToken(en.RefreshToken)
+			if err != nil {
+				row.Status, row.Reason = ImportSkipped, "token 无效: "+err.Error()
+				return
+			}
+			if en.Email == "" {
+				email, err := client.FetchUserEmail(access)
+				if err != nil {
+					row.Status, row.Reason = ImportSkipped, err.Error()
+					return
+				}
+				en.Email, row.Email = email, email
+			}
+		}(&entries[i], &rows[i])
+	}
+	wg.Wait()
+}
+
+// commitCredentials writes the profiles into every installation, adds the
+// accounts to the config and re-syncs the agent workspaces. Callers hold
+// e.mu.
+func (e *RotatorEngine) commitCredentials(entries []importer.Entry) error {
+	newCfg := e.Config().Clone()
+
+	importInto := func(authData map[string]interface{}) (bool, error) {
+		profiles, ok := authData["profiles"].(map[string]interface{})
+		if !ok {
+			profiles = make(map[string]interface{})
+			authData["profiles"] = profiles
+		}
+
+		for _, item := range entries {
+			profileKey := "google-antigravity:" + item.Email
+			profile, _ := profiles[profileKey].(map[string]interface{})
+			if profile == nil {
+				profile = make(map[string]interface{})
+				profile["provider"] = "google-antigravity"
+				profile["type"] = "oauth"
+				profiles[profileKey] = profile
+			}
+
+			profile["email"] = item.Email
+			profile["refresh"] = item.RefreshToken
+			if item.ProjectID != "" {
+				profile["projectId"] = item.ProjectID
+			}
+		}
+		return true, nil
+	}
+
+	// Every installation gets the accounts, so a rotation decision can be
+	// applied to all of them.
+	insts := e.installations()
+	for _, inst := range insts {
+		if err := e.updateJSON(inst.AuthPath(), importInto); err != nil {
+			return fmt.Errorf("[%s] 保存凭据失败: %v", inst.Name, err)
+		}
+	}
+
+	for _, item := range entries {
+		if !containsString(newCfg.Rotator.Accounts, item.Email) {
+			newCfg.Rotator.Accounts = append(newCfg.Rotator.Accounts, item.Email)
+		}
+	}
+	if err := config.SaveConfig(newCfg); err != nil {
+		return fmt.Errorf("保存配置失败: %v", err)
+	}
+	if _, err := e.ApplyConfig(newCfg); err != nil {
+		return err
+	}
+	for _, inst := range insts {
+		e.syncInstallation(inst)
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package engine
+
+import (
+	"antigravity-rotator-v2/internal/importer"
+	"context"
+	"fmt"
+	"time"
+)
+
+// loginTimeout bounds how long a login waits for the browser.
+const loginTimeout = 5 * time.Minute
+
+// LoginAccount adds an account through an interactive Google login. open is
+// given the consent URL, e.g. to launch the browser; the account is then
+// imported like ImportCredentials does.
+func (e *RotatorEngine) LoginAccount(ctx context.Context, open func(url string)) (ImportReport, error) {
+	s, err := e.clientFor("").StartLogin()
+	if err != nil {
+		return ImportReport{}, err
+	}
+	defer s.Close()
+	open(s.URL)
+
+	ctx, cancel := context.WithTimeout(ctx, loginTimeout)
+	defer cancel()
+	res, err := s.Wait(ctx)
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	entry := importer.Entry{Source: "google-login", Email: res.Email, RefreshToken: res.RefreshToken, ProjectID: res.ProjectID}
+	report, err := e.ImportCredentials([]importer.Entry{entry}, nil, ImportOptions{})
+	if err == nil && res.ProjectError != "" && len(report.Rows) > 0 && report.Rows[0].Reason == "" {
+		report.Rows[0].Reason = fmt.Sprintf("未获取到 projectId: %s", res.ProjectError)
+	}
+	return report, err
+}
@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"antigravity-rotator-v2/internal/google"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// onboardTimeout bounds how long a refresh waits for a project to be
+// provisioned.
+const onboardTimeout = 2 * time.Minute
+
+// onboardMissing gets a project for every enabled account whose profile has
+// none, onboarding it if needed. It can wait for provisioning, so callers
+// must not hold e.mu.
+func (e *RotatorEngine) onboardMissing() {
+	profiles, err := e.loadProfiles()
+	if err != nil {
+		return
+	}
+	var wg sync.WaitGroup
+	for _, email := range e.Config().Rotator.Accounts {
+		t, ok := accountTaskFor(profiles, email)
+		if !ok || t.projectId != "" || e.isDisabled(email) {
+			continue
+		}
+		wg.Add(1)
+		go func(t accountTask) {
+			defer wg.Done()
+			client := e.clientFor(t.email)
+			if access, err := client.RefreshAccessToken(t.refresh); err == nil {
+				e.ensureProject(client, t.email, access)
+			}
+		}(t)
+	}
+	wg.Wait()
+}
+
+// ensureProject returns the project of an account whose profile has none,
+// onboarding the account first if needed, and writes it into the profile
+// of every installation.
+func (e *RotatorEngine) ensureProject(client *google.Client, email, accessToken string) (string, error) {
+	e.tierMu.Lock()
+	if e.onboarding[email] {
+		e.tierMu.Unlock()
+		return "", fmt.Errorf("账号 %s 正在开通中", email)
+	}
+	if e.onboarding == nil {
+		e.onboarding = make(map[string]bool)
+	}
+	e.onboarding[email] = true
+	e.tierMu.Unlock()
+	defer func() {
+		e.tierMu.Lock()
+		delete(e.onboarding, email)
+		e.tierMu.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), onboardTimeout)
+	defer cancel()
+	info, err := client.EnsureProject(ctx, accessToken)
+	if info != nil {
+		e.recordTier(email, info)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err := e.saveProjectID(email, info.ProjectID); err != nil {
+		fmt.Printf("Failed to save projectId of %s: %v\n", email, err)
+	} else {
+		fmt.Printf("Project %s ready for %s\n", info.ProjectID, email)
+		e.emit("account_onboarded", map[string]string{"email": email, "projectId": info.ProjectID})
+	}
+	return info.ProjectID, nil
+}
+
+// saveProjectID writes a project ID into an account's profile on every
+// installation that has the profile.
+func (e *RotatorEngine) saveProjectID(email, projectID string) error {
+	profileKey := "google-antigravity:" + email
+	for _, inst := range e.installations() {
+		err := e.updateJSON(inst.AuthPath(), func(authData map[string]interface{}) (bool, error) {
+			profiles, _ := authData["profiles"].(map[string]interface{})
+			profile, ok := profiles[profileKey].(map[string]interface{})
+			if !ok || profile["projectId"] == projectID {
+				return false, nil
+			}
+			profile["projectId"] = projectID
+			return true, nil
+		})
+		if err != nil {
+			return fmt.Errorf("[%s] %v", inst.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package engine
+
+import (
+	"antigravity-rotator-v2/internal/google"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Polling tiers of an account.
+const (
+	TierHot       = "hot"       // active account or next candidate
+	TierIdle      = "idle"      // healthy, not about to be used
+	TierExhausted = "exhausted" // waits for its quota reset
+)
+
+// pollTick is how often the poller looks for accounts that are due.
+const pollTick = 15 * time.Second
+
+// AccountPoll is the polling schedule of one account.
+type AccountPoll struct {
+	Email      string    `json:"email"`
+	Tier       string    `json:"tier"`
+	LastPolled time.Time `json:"lastPolled"`
+	NextPoll   time.Time `json:"nextPoll"`
+	ResetAt    time.Time `json:"resetAt"` // earliest known quota reset
+	resets     map[string]time.Time
+}
+
+// pollState returns the schedule entry of email. Callers hold pollMu.
+func (e *RotatorEngine) pollState(email string) *AccountPoll {
+	if e.polls == nil {
+		e.polls = make(map[string]*AccountPoll)
+	}
+	st, ok := e.polls[email]
+	if !ok {
+		st = &AccountPoll{Email: email, Tier: TierHot}
+		e.polls[email] = st
+	}
+	return st
+}
+
+// recordPoll notes a successful quota fetch and its reset times.
+func (e *RotatorEngine) recordPoll(email string, quotas map[string]google.ModelQuota) {
+	e.pollMu.Lock()
+	defer e.pollMu.Unlock()
+	st := e.pollState(email)
+	st.LastPolled = e.now()
+	st.resets = make(map[string]time.Time, len(quotas))
+	for model, q := range quotas {
+		if !q.ResetTime.IsZero() {
+			st.resets[model] = q.ResetTime
+		}
+	}
+}
+
+// due reports whether an account's next poll time has come. Callers hold
+// pollMu.
+func (e *RotatorEngine) due(email string, now time.Time) bool {
+	st, ok := e.polls[email]
+	return !ok || !now.Before(st.NextPoll)
+}
+
+// setActive remembers the account at the front of auth.order.
+func (e *RotatorEngine) setActive(email string) {
+	e.pollMu.Lock()
+	e.active = email
+	e.pollMu.Unlock()
+}
+
+// schedulePolls sorts the accounts into tiers and sets their next poll
+// times from the current status. Callers hold e.mu.
+func (e *RotatorEngine) schedulePolls() {
+	rc := e.Config().Rotator
+	p := rc.Polling
+	policy := e.policy()
+	hot := time.Duration(p.HotSeconds) * time.Second
+	idle := time.Duration(p.IdleMinutes) * time.Minute
+
+	e.pollMu.Lock()
+	defer e.pollMu.Unlock()
+
+	type scored struct {
+		email string
+		best  int
+	}
+	var ranked []scored
+	enabled := make(map[string]bool)
+	for _, email := range rc.Accounts {
+		if e.isDisabled(email) {
+			continue
+		}
+		enabled[email] = true
+		st := e.pollState(email)
+
+		best, known := -1, false
+		for _, m := range policy.ModelPriority {
+			if q, ok := e.Status[email+":"+m]; ok {
+				known = true
+				if q > best {
+					best = q
+				}
+			}
+		}
+		st.ResetAt = time.Time{}
+		for _, m := range policy.ModelPriority {
+			if r, ok := st.resets[m]; ok && r.After(st.LastPolled) && (st.ResetAt.IsZero() || r.Before(st.ResetAt)) {
+				st.ResetAt = r
+			}
+		}
+
+		if known && best <= policy.Low {
+			st.Tier = TierExhausted
+			if !st.ResetAt.IsZero() {
+				st.NextPoll = st.ResetAt.Add(time.Minute)
+			} else {
+				st.NextPoll = st.LastPolled.Add(idle)
+			}
+			continue
+		}
+		ranked = append(ranked, scored{email, best})
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].best > ranked[j].best })
+
+	candidates := 0
+	for _, r := range ranked {
+		st := e.polls[r.email]
+		switch {
+		case r.email == e.active:
+			st.Tier = TierHot
+		case candidates < p.Candidates:
+			st.Tier = TierHot
+			candidates++
+		default:
+			st.Tier = TierIdle
+		}
+		if st.Tier == TierHot {
+			st.NextPoll = st.LastPolled.Add(hot)
+		} else {
+			st.NextPoll = st.LastPolled.Add(idle)
+		}
+	}
+
+	for email := range e.polls {
+		if !enabled[email] {
+			delete(e.polls, email)
+		}
+	}
+}
+
+// refreshDue refreshes only the accounts whose next poll time has come and
+// reschedules them. Callers must not hold e.mu.
+func (e *RotatorEngine) refreshDue() {
+	e.pollMu.Lock()
+	active := e.active
+	e.pollMu.Unlock()
+	if active == "" {
+		e.setActive(e.GetVipEmail())
+	}
+
+	profiles, err := e.loadProfiles()
+	if err != nil {
+		return
+	}
+	now := e.now()
+	var tasks []accountTask
+	e.pollMu.Lock()
+	for _, email := range e.Config().Rotator.Accounts {
+		if e.isDisabled(email) {
+			continue
+		}
+		if !e.due(email, now) {
+			continue
+		}
+		if t, ok := accountTaskFor(profiles, email); ok {
+			tasks = append(tasks, t)
+		}
+	}
+	e.pollMu.Unlock()
+
+	if len(tasks) > 0 {
+		var wg sync.WaitGroup
+		for _, task := range tasks {
+			wg.Add(1)
+			go func(t accountTask) {
+				defer wg.Done()
+				if e.refreshAccount(t) != nil {
+					// Retry a failing account on its tier's schedule
+					// rather than on every tick.
+					e.pollMu.Lock()
+					e.pollState(t.email).LastPolled = e.now()
+					e.pollMu.Unlock()
+				}
+			}(task)
+		}
+		wg.Wait()
+		e.emitStatus()
+	}
+	e.mu.Lock()
+	e.schedulePolls()
+	e.mu.Unlock()
+	e.emit("poll_schedule", e.PollSchedule())
+}
+
+// refreshForCycle brings the status up to date before a rotation decision.
+// Callers must not hold e.mu.
+func (e *RotatorEngine) refreshForCycle() {
+	if e.Config().Rotator.Polling.Tiered {
+		e.refreshDue()
+		return
+	}
+	e.refreshStatus()
+}
+
+// startPoller runs tiered polling until stop is closed. It pauses while
+// tiered polling is off; ApplyConfig restarts the loop without a poller
+// then. Called with loopMu held.
+func (e *RotatorEngine) startPoller(stop chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(pollTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if !e.Config().Rotator.Polling.Tiered {
+					continue
+				}
+				e.refreshDue()
+			}
+		}
+	}()
+}
+
+// PollSchedule returns the polling tier and next poll time of every enabled
+// account, in config order.
+func (e *RotatorEngine) PollSchedule() []AccountPoll {
+	e.pollMu.Lock()
+	defer e.pollMu.Unlock()
+	out := []AccountPoll{}
+	for _, email := range e.Config().Rotator.Accounts {
+		if st, ok := e.polls[email]; ok {
+			out = append(out, *st)
+		}
+	}
+	return out
+}
@@ -0,0 +1,154 @@
+package engine
+
+import (
+	"antigravity-rotator-v2/internal/config"
+	"antigravity-rotator-v2/internal/google"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ConfigChange describes what ApplyConfig changed. It is emitted to the
+// frontend as the payload of the config_applied event.
+type ConfigChange struct {
+	ProxyChanged     bool     `json:"proxyChanged"`
+	AccountsAdded    []string `json:"accountsAdded"`
+	AccountsRemoved  []string `json:"accountsRemoved"`
+	ThresholdChanged bool     `json:"thresholdChanged"`
+	// ScheduleChanged covers everything the auto loop and the poller run
+	// with: auto-rotation, interval, triggers, schedules and polling.
+	ScheduleChanged bool `json:"scheduleChanged"`
+	BalanceChanged  bool `json:"balanceChanged"`
+	BinaryChanged   bool `json:"binaryChanged"`
+	// InstallationsChanged covers openclawHome and the installation list.
+	InstallationsChanged bool `json:"installationsChanged"`
+}
+
+func diffConfig(oldCfg, newCfg *config.AppConfig) ConfigChange {
+	o, n := oldCfg.Rotator, newCfg.Rotator
+	change := ConfigChange{
+		ProxyChanged:     o.Proxy != n.Proxy || !sameJSON(o.AccountProxies, n.AccountProxies),
+		ThresholdChanged: o.Quotas.Low != n.Quotas.Low,
+		ScheduleChanged: o.AutoRotate != n.AutoRotate || o.RotateInterval != n.RotateInterval ||
+			!sameJSON(o.Triggers, n.Triggers) || !sameJSON(o.Schedules, n.Schedules) ||
+			o.Polling != n.Polling,
+		BalanceChanged: o.Balance != n.Balance,
+		BinaryChanged:  o.OpenClawBin != n.OpenClawBin,
+		InstallationsChanged: o.OpenClawHome != n.OpenClawHome ||
+			!sameJSON(o.Installations, n.Installations),
+	}
+
+	oldSet := make(map[string]bool)
+	for _, a := range o.Accounts {
+		oldSet[a] = true
+	}
+	newSet := make(map[string]bool)
+	for _, a := range n.Accounts {
+		newSet[a] = true
+		if !oldSet[a] {
+			change.AccountsAdded = append(change.AccountsAdded, a)
+		}
+	}
+	for _, a := range o.Accounts {
+		if !newSet[a] {
+			change.AccountsRemoved = append(change.AccountsRemoved, a)
+		}
+	}
+	return change
+}
+
+// ApplyConfig switches the running engine over to cfg. It rebuilds the
+// Google client when proxy settings change, drops status entries of removed
+// accounts (everything else is kept) and restarts the auto loop when its
+// schedule changed. cfg is validated first; the caller is responsible for
+// persisting it.
+func (e *RotatorEngine) ApplyConfig(cfg *config.AppConfig) (ConfigChange, error) {
+	if cfg == nil {
+		return ConfigChange{}, fmt.Errorf("配置为空")
+	}
+	if err := cfg.Validate(); err != nil {
+		return ConfigChange{}, err
+	}
+
+	e.cfgMu.Lock()
+	change := diffConfig(e.cfg, cfg)
+	e.cfg = cfg
+	if change.ProxyChanged {
+		e.client = &google.Client{Proxy: cfg.Rotator.Proxy}
+	}
+	e.cfgMu.Unlock()
+
+	if len(change.AccountsRemoved) > 0 {
+		e.pruneStatus(change.AccountsRemoved)
+	}
+	if change.BalanceChanged {
+		// Slices and credits of the old mode do not carry over.
+		e.balanceMu.Lock()
+		e.balance = balanceState{}
+		e.balanceMu.Unlock()
+	}
+	if change.ScheduleChanged {
+		e.StartAutoLoop()
+	}
+	if change.InstallationsChanged && e.watching() {
+		e.StartWatcher()
+	}
+
+	e.emit("config_applied", change)
+	return change, nil
+}
+
+// sameJSON reports whether a and b encode to the same JSON once null values
+// and empty lists and objects are dropped, so that nil and empty maps and
+// slices compare equal.
+func sameJSON(a, b interface{}) bool {
+	return reflect.DeepEqual(normalizedJSON(a), normalizedJSON(b))
+}
+
+func normalizedJSON(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var doc interface{}
+	json.Unmarshal(data, &doc)
+	return dropEmpty(doc)
+}
+
+// dropEmpty removes null values and empty lists and objects from a decoded
+// JSON document, returning nil when nothing is left.
+func dropEmpty(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			if t[k] = dropEmpty(child); t[k] == nil {
+				delete(t, k)
+			}
+		}
+		if len(t) == 0 {
+			return nil
+		}
+	case []interface{}:
+		if len(t) == 0 {
+			return nil
+		}
+		for i, child := range t {
+			t[i] = dropEmpty(child)
+		}
+	}
+	return v
+}
+
+func (e *RotatorEngine) pruneStatus(emails []string) {
+	e.mu.Lock()
+	for key := range e.Status {
+		for _, email := range emails {
+			if strings.HasPrefix(key, email+":") {
+				delete(e.Status, key)
+			}
+		}
+	}
+	e.mu.Unlock()
+	e.emitStatus()
+}
@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"antigravity-rotator-v2/internal/config"
+)
+
+// Decision is the model and account a rotation cycle would switch to.
+type Decision struct {
+	Model   string `json:"model"`
+	Account string `json:"account"`
+	Quota   int    `json:"quota"`
+	// Order is the auth.order to apply when balancing: Account, then the
+	// other healthy accounts by quota left.
+	Order []string `json:"order,omitempty"`
+
+	credits map[string]int // weighted mode counters after this pick
+}
+
+// order returns the accounts to put at the front of auth.order.
+func (d Decision) order() []string {
+	if len(d.Order) > 0 {
+		return d.Order
+	}
+	return []string{d.Account}
+}
+
+// policy returns the rotation settings in effect now, after schedules.
+func (e *RotatorEngine) policy() config.Policy {
+	return e.Config().Rotator.PolicyAt(e.now())
+}
+
+// CurrentPolicy returns the settings in effect now and emits
+// schedule_changed when a different schedule has taken effect since the
+// last call.
+func (e *RotatorEngine) CurrentPolicy() config.Policy {
+	p := e.policy()
+	e.scheduleMu.Lock()
+	changed := p.Schedule != e.lastSchedule
+	e.lastSchedule = p.Schedule
+	e.scheduleMu.Unlock()
+	if changed {
+		e.emit("schedule_changed", p)
+	}
+	return p
+}
+
+// decide picks the first priority model that has a usable account above
+// the quota threshold, and for it the highest-tier account above the
+// threshold, breaking ties by quota left. With balancing on, the account is
+// picked by the balancing mode instead. Callers hold e.mu.
+func (e *RotatorEngine) decide() (Decision, bool) {
+	p := e.policy()
+	threshold := p.Low
+	balance := e.Config().Rotator.Balance
+
+	for _, modelID := range p.ModelPriority {
+		if _, maxQuota := e.bestAccount(modelID); maxQuota > threshold {
+			if balance.Mode != config.BalanceOff {
+				return e.balancedDecision(balance, modelID, threshold), true
+			}
+			account, quota := e.preferredAccount(modelID, threshold)
+			return Decision{Model: modelID, Account: account, Quota: quota}, true
+		}
+	}
+	return Decision{}, false
+}
+
+// preferredAccount returns the account above threshold for modelID with
+// the highest tier, and the most quota among those.
+func (e *RotatorEngine) preferredAccount(modelID string, threshold int) (string, int) {
+	best, bestRank, bestQuota := "", -1, -1
+	for _, email := range e.Config().Rotator.Accounts {
+		if !e.usable(email) {
+			continue
+		}
+		q, ok := e.Status[email+":"+modelID]
+		if !ok || q <= threshold {
+			continue
+		}
+		rank := e.tierRank(email)
+		if rank > bestRank || (rank == bestRank && q > bestQuota) {
+			best, bestRank, bestQuota = email, rank, q
+		}
+	}
+	return best, bestQuota
+}
+
+// bestAccount returns the usable account with the most quota left for
+// modelID and that quota, or -1 if no account reports the model.
+func (e *RotatorEngine) bestAccount(modelID string) (string, int) {
+	bestAccount := ""
+	maxQuota := -1
+	for _, email := range e.Config().Rotator.Accounts {
+		if !e.usable(email) {
+			continue
+		}
+		if q, ok := e.Status[email+":"+modelID]; ok && q > maxQuota {
+			maxQuota = q
+			bestAccount = email
+		}
+	}
+	return bestAccount, maxQuota
+}
+
+// PlanRotation refreshes quotas and reports what RunCycle would switch to,
+// without touching the gateway.
+func (e *RotatorEngine) PlanRotation() (Decision, bool) {
+	e.onboardMissing()
+	e.refreshStatus()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.decide()
+}
@@ -0,0 +1,341 @@
+package engine
+
+import (
+	"antigravity-rotator-v2/internal/config"
+	"antigravity-rotator-v2/internal/google"
+	"antigravity-rotator-v2/internal/trace"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// gatewayCallsPerCycle is what applying one decision costs per
+// installation: config.get, then config.get and config.patch, once for the
+// model and once for the account.
+const gatewayCallsPerCycle = 6
+
+// SimOptions controls a simulation run.
+type SimOptions struct {
+	// Start and End bound the run; zero values default to the trace's span.
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Step is the virtual clock resolution, one minute by default.
+	Step time.Duration `json:"step"`
+	// Load drains the active account's active model by this many percent
+	// per hour. Use it with synthetic traces, which have no consumption of
+	// their own; recorded traces already contain it.
+	Load float64 `json:"load"`
+	// ResetWindow, if set, models rolling quota windows: a model's window
+	// opens when it is first used and refills it to 100% this long after.
+	ResetWindow time.Duration `json:"resetWindow"`
+}
+
+// SimSwitch is a change of model or account made during a simulation.
+type SimSwitch struct {
+	Time    time.Time `json:"time"`
+	Model   string    `json:"model"`
+	Account string    `json:"account"`
+	Quota   int       `json:"quota"`
+}
+
+// SimRefill is a quota window that ran out and refilled during a
+// simulation with a reset window.
+type SimRefill struct {
+	Time    time.Time `json:"time"`
+	Account string    `json:"account"`
+	Model   string    `json:"model"`
+}
+
+// SimCalls counts the calls a simulated rotator would have made.
+type SimCalls struct {
+	Token   int `json:"token"`   // OAuth token refreshes
+	Quota   int `json:"quota"`   // fetchAvailableModels
+	Gateway int `json:"gateway"` // openclaw gateway calls
+}
+
+// SimReport is the outcome of a simulation. Durations are in virtual time.
+type SimReport struct {
+	Start           time.Time   `json:"start"`
+	End             time.Time   `json:"end"`
+	Cycles          int         `json:"cycles"`
+	Switches        []SimSwitch `json:"switches"`
+	ModelSwitches   int         `json:"modelSwitches"`
+	AccountSwitches int         `json:"accountSwitches"`
+	// BelowThreshold is how long the active model on the active account
+	// was at or below the threshold, or nothing was active.
+	BelowThreshold time.Duration `json:"belowThreshold"`
+	// NoHealthy is how long no priority model had any account above the
+	// threshold.
+	NoHealthy time.Duration `json:"noHealthy"`
+	Calls     SimCalls      `json:"calls"`
+	// Active is how long each account was the active one.
+	Active map[string]time.Duration `json:"active"`
+	// Spread is the gap between the most and the least quota left for the
+	// active model across the usable accounts, averaged over time. It is
+	// low when the accounts degrade together.
+	Spread  float64     `json:"spread"`
+	Refills []SimRefill `json:"refills"`
+}
+
+// Simulate replays a quota trace against the rotation settings rc in
+// virtual time: auto-rotation cycles every RotateInterval, polling as
+// configured, and the same decision logic as RunCycle. Schedules apply by
+// virtual time; auto-rotation is assumed on unless a schedule pauses it.
+func Simulate(rc config.RotatorConfig, samples []trace.Sample, opts SimOptions) (SimReport, error) {
+	if len(samples) == 0 {
+		return SimReport{}, fmt.Errorf("配额轨迹为空")
+	}
+	if rc.RotateInterval <= 0 {
+		return SimReport{}, fmt.Errorf("rotateInterval 必须大于 0")
+	}
+	if opts.Step <= 0 {
+		opts.Step = time.Minute
+	}
+	if opts.Start.IsZero() {
+		opts.Start = samples[0].Time
+	}
+	if opts.End.IsZero() {
+		opts.End = samples[len(samples)-1].Time
+	}
+	if !opts.End.After(opts.Start) {
+		return SimReport{}, fmt.Errorf("模拟结束时间必须晚于开始时间")
+	}
+
+	rc.AutoRotate = true
+	if len(rc.Accounts) == 0 {
+		rc.Accounts = traceAccounts(samples)
+	}
+	now := opts.Start
+	e := &RotatorEngine{
+		cfg:    &config.AppConfig{Rotator: rc},
+		Status: make(map[string]int),
+		clock:  func() time.Time { return now },
+	}
+	s := &simulation{e: e, opts: opts, samples: samples, quota: make(map[string]float64), resets: make(map[string]time.Time), windows: make(map[string]time.Time)}
+	s.report = SimReport{Start: opts.Start, End: opts.End, Switches: []SimSwitch{}, Active: make(map[string]time.Duration), Refills: []SimRefill{}}
+	installations := len(rc.Installations)
+	if installations == 0 {
+		installations = 1
+	}
+
+	interval := time.Duration(rc.RotateInterval) * time.Minute
+	nextCycle := opts.Start
+	for ; now.Before(opts.End); now = now.Add(opts.Step) {
+		s.applySamples(now)
+		s.refill(now)
+
+		if rc.Polling.Tiered {
+			s.pollDue(now)
+		}
+		if !now.Before(nextCycle) {
+			nextCycle = nextCycle.Add(interval)
+			if e.policy().AutoRotate {
+				s.cycle(now, installations)
+			}
+		}
+
+		s.measure()
+		s.consume(now)
+	}
+	if s.measured > 0 {
+		s.report.Spread = s.spread / float64(s.measured)
+	}
+	return s.report, nil
+}
+
+// traceAccounts returns the accounts of a trace in order of appearance.
+func traceAccounts(samples []trace.Sample) []string {
+	var accounts []string
+	seen := make(map[string]bool)
+	for _, sm := range samples {
+		if !seen[sm.Account] {
+			seen[sm.Account] = true
+			accounts = append(accounts, sm.Account)
+		}
+	}
+	return accounts
+}
+
+// simulation is the state of one Simulate run. quota holds the true quota
+// per email:model, which the engine only sees when it polls.
+type simulation struct {
+	e       *RotatorEngine
+	opts    SimOptions
+	samples []trace.Sample
+	next    int
+	quota   map[string]float64
+	resets  map[string]time.Time
+	windows map[string]time.Time // open reset windows by email:model
+	models  map[string][]string
+	model   string
+	account string
+	report  SimReport
+	// spread sums the quota gap over measured steps.
+	spread   float64
+	measured int
+}
+
+// applySamples sets the true quotas to the trace's samples up to now.
+func (s *simulation) applySamples(now time.Time) {
+	for ; s.next < len(s.samples) && !s.samples[s.next].Time.After(now); s.next++ {
+		sm := s.samples[s.next]
+		key := sm.Account + ":" + sm.Model
+		if _, ok := s.quota[key]; !ok {
+			if s.models == nil {
+				s.models = make(map[string][]string)
+			}
+			s.models[sm.Account] = append(s.models[sm.Account], sm.Model)
+		}
+		s.quota[key] = float64(sm.Percent)
+		s.resets[key] = sm.ResetTime
+	}
+}
+
+// refill resets the quotas whose reset window has run out.
+func (s *simulation) refill(now time.Time) {
+	for key, end := range s.windows {
+		if now.Before(end) {
+			continue
+		}
+		s.quota[key] = 100
+		s.resets[key] = time.Time{}
+		delete(s.windows, key)
+		i := strings.Index(key, ":")
+		s.report.Refills = append(s.report.Refills, SimRefill{Time: end, Account: key[:i], Model: key[i+1:]})
+	}
+	sort.Slice(s.report.Refills, func(i, j int) bool {
+		a, b := s.report.Refills[i], s.report.Refills[j]
+		if !a.Time.Equal(b.Time) {
+			return a.Time.Before(b.Time)
+		}
+		return a.Account < b.Account
+	})
+}
+
+// poll shows the engine an account's true quotas, as refreshAccount would.
+func (s *simulation) poll(email string) {
+	models := s.models[email]
+	if len(models) == 0 {
+		return
+	}
+	s.report.Calls.Token++
+	s.report.Calls.Quota++
+	quotas := make(map[string]google.ModelQuota, len(models))
+	for _, m := range models {
+		key := email + ":" + m
+		q := google.ModelQuota{Percent: int(s.quota[key]), ResetTime: s.resets[key]}
+		quotas[m] = q
+		s.e.Status[key] = q.Percent
+	}
+	s.e.recordPoll(email, quotas)
+}
+
+// pollDue polls the accounts tiered polling would poll now.
+func (s *simulation) pollDue(now time.Time) {
+	var due []string
+	s.e.pollMu.Lock()
+	for _, email := range s.e.Config().Rotator.Accounts {
+		if !s.e.isDisabled(email) && s.e.due(email, now) {
+			due = append(due, email)
+		}
+	}
+	s.e.pollMu.Unlock()
+	for _, email := range due {
+		s.poll(email)
+	}
+	if len(due) > 0 {
+		s.e.schedulePolls()
+	}
+}
+
+// cycle runs one rotation cycle as runCycle would.
+func (s *simulation) cycle(now time.Time, installations int) {
+	e := s.e
+	s.report.Cycles++
+	if !e.Config().Rotator.Polling.Tiered {
+		for _, email := range e.Config().Rotator.Accounts {
+			if !e.isDisabled(email) {
+				s.poll(email)
+			}
+		}
+		e.schedulePolls()
+	}
+
+	d, ok := e.decide()
+	if !ok {
+		return
+	}
+	s.report.Calls.Gateway += gatewayCallsPerCycle * installations
+	if d.Model != s.model {
+		s.report.ModelSwitches++
+	}
+	if d.Account != s.account {
+		s.report.AccountSwitches++
+	}
+	if d.Model != s.model || d.Account != s.account {
+		s.report.Switches = append(s.report.Switches, SimSwitch{Time: now, Model: d.Model, Account: d.Account, Quota: d.Quota})
+	}
+	s.model, s.account = d.Model, d.Account
+	e.setActive(d.Account)
+	e.commitBalance(d)
+	e.schedulePolls()
+}
+
+// measure accounts the coming step to the current state.
+func (s *simulation) measure() {
+	step := s.opts.Step
+	p := s.e.policy()
+	if s.account != "" {
+		s.report.Active[s.account] += step
+	}
+	if q, ok := s.quota[s.account+":"+s.model]; !ok || q <= float64(p.Low) {
+		s.report.BelowThreshold += step
+	}
+	if s.model != "" {
+		lo, hi, n := 0.0, 0.0, 0
+		for _, email := range s.e.Config().Rotator.Accounts {
+			q, ok := s.quota[email+":"+s.model]
+			if !ok || !s.e.usable(email) {
+				continue
+			}
+			if n == 0 || q < lo {
+				lo = q
+			}
+			if n == 0 || q > hi {
+				hi = q
+			}
+			n++
+		}
+		s.spread += hi - lo
+		s.measured++
+	}
+	for _, m := range p.ModelPriority {
+		for _, email := range s.e.Config().Rotator.Accounts {
+			if q, ok := s.quota[email+":"+m]; ok && s.e.usable(email) && q > float64(p.Low) {
+				return
+			}
+		}
+	}
+	s.report.NoHealthy += step
+}
+
+// consume drains the active model on the active account for one step,
+// opening its reset window on first use.
+func (s *simulation) consume(now time.Time) {
+	if s.opts.Load <= 0 || s.account == "" {
+		return
+	}
+	key := s.account + ":" + s.model
+	if q, ok := s.quota[key]; ok {
+		if _, open := s.windows[key]; !open && s.opts.ResetWindow > 0 {
+			s.windows[key] = now.Add(s.opts.ResetWindow)
+			s.resets[key] = s.windows[key]
+		}
+		q -= s.opts.Load * s.opts.Step.Hours()
+		if q < 0 {
+			q = 0
+		}
+		s.quota[key] = q
+	}
+}
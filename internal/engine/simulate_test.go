@@ -0,0 +1,180 @@
+package engine
+
+import (
+	"antigravity-rotator-v2/internal/config"
+	"antigravity-rotator-v2/internal/trace"
+	"strings"
+	"testing"
+	"time"
+)
+
+var simStart = time.Date(2026, 1, 5, 8, 0, 0, 0, time.UTC)
+
+func simConfig() config.RotatorConfig {
+	rc := config.DefaultConfig().Rotator
+	rc.Polling.Tiered = false
+	return rc
+}
+
+func TestSimulateReplay(t *testing.T) {
+	at := func(min int) time.Time { return simStart.Add(time.Duration(min) * time.Minute) }
+	samples := []trace.Sample{
+		{Time: at(0), Account: "a", Model: pro, Percent: 90},
+		{Time: at(0), Account: "b", Model: pro, Percent: 50},
+		{Time: at(0), Account: "a", Model: flash, Percent: 80},
+		{Time: at(15), Account: "a", Model: pro, Percent: 10}, // a runs out of pro
+		{Time: at(35), Account: "b", Model: pro, Percent: 5},  // pro is gone
+		{Time: at(60), Account: "a", Model: flash, Percent: 80},
+	}
+	report, err := Simulate(simConfig(), samples, SimOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []SimSwitch{
+		{Time: at(0), Model: pro, Account: "a", Quota: 90},
+		{Time: at(20), Model: pro, Account: "b", Quota: 50},
+		{Time: at(40), Model: flash, Account: "a", Quota: 80},
+	}
+	if len(report.Switches) != len(want) {
+		t.Fatalf("switches = %+v, want %+v", report.Switches, want)
+	}
+	for i := range want {
+		if report.Switches[i] != want[i] {
+			t.Errorf("switch %d = %+v, want %+v", i, report.Switches[i], want[i])
+		}
+	}
+	if report.Cycles != 6 || report.ModelSwitches != 2 || report.AccountSwitches != 3 {
+		t.Errorf("cycles %d, switches %d/%d", report.Cycles, report.ModelSwitches, report.AccountSwitches)
+	}
+	// a's pro is below the threshold from 15 until the cycle at 20, b's
+	// from 35 until the cycle at 40.
+	if report.BelowThreshold != 10*time.Minute {
+		t.Errorf("below threshold %s, want 10m", report.BelowThreshold)
+	}
+	if report.NoHealthy != 0 {
+		t.Errorf("no healthy %s, want 0", report.NoHealthy)
+	}
+	// Every cycle refreshes both accounts and applies a decision.
+	if report.Calls.Quota != 12 || report.Calls.Token != 12 || report.Calls.Gateway != 6*gatewayCallsPerCycle {
+		t.Errorf("calls = %+v", report.Calls)
+	}
+	if report.Active["a"] != 40*time.Minute || report.Active["b"] != 20*time.Minute {
+		t.Errorf("active = %v", report.Active)
+	}
+}
+
+func TestSimulateLoadAndThreshold(t *testing.T) {
+	samples := trace.Synthetic{
+		Accounts:   []string{"a", "b"},
+		Models:     []string{pro},
+		Start:      simStart,
+		Duration:   10 * time.Hour,
+		ResetEvery: 5 * time.Hour,
+	}.Generate()
+	run := func(low int) SimReport {
+		rc := simConfig()
+		rc.ModelPriority = []string{pro}
+		rc.Quotas.Low = low
+		report, err := Simulate(rc, samples, SimOptions{End: simStart.Add(10 * time.Hour), Load: 60})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return report
+	}
+
+	// 60%/h on two accounts lasts about 3h of each 5h window, minus the
+	// quota kept back by the threshold.
+	low, high := run(10), run(40)
+	if low.NoHealthy == 0 || high.NoHealthy <= low.NoHealthy {
+		t.Errorf("no healthy: low threshold %s, high threshold %s", low.NoHealthy, high.NoHealthy)
+	}
+	if low.BelowThreshold < low.NoHealthy {
+		t.Errorf("below threshold %s shorter than no healthy %s", low.BelowThreshold, low.NoHealthy)
+	}
+}
+
+func TestSimulateTieredPolling(t *testing.T) {
+	samples := []trace.Sample{
+		{Time: simStart, Account: "a", Model: pro, Percent: 100},
+		{Time: simStart, Account: "b", Model: pro, Percent: 100},
+		{Time: simStart, Account: "c", Model: pro, Percent: 100},
+		{Time: simStart, Account: "d", Model: pro, Percent: 5, ResetTime: simStart.Add(10 * time.Hour)},
+	}
+	rc := simConfig()
+	opts := SimOptions{End: simStart.Add(2 * time.Hour)}
+	full, err := Simulate(rc, samples, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc.Polling.Tiered = true
+	tiered, err := Simulate(rc, samples, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A full refresh polls all four accounts every 10 minutes.
+	if full.Calls.Quota != 4*12 {
+		t.Errorf("full refresh made %d quota calls, want 48", full.Calls.Quota)
+	}
+	// Tiered polling polls the active account and two candidates every two
+	// minutes, and d, exhausted until its reset, only once.
+	if tiered.Calls.Quota != 3*60+1 {
+		t.Errorf("tiered polling made %d quota calls, want 181", tiered.Calls.Quota)
+	}
+}
+
+func TestSimulateBalance(t *testing.T) {
+	samples := trace.Synthetic{Accounts: []string{"a", "b", "c"}, Models: []string{pro}, Start: simStart, Duration: time.Hour}.Generate()
+	run := func(balance config.BalanceConfig) SimReport {
+		rc := simConfig()
+		rc.ModelPriority = []string{pro}
+		rc.Balance = balance
+		report, err := Simulate(rc, samples, SimOptions{End: simStart.Add(7 * time.Hour), Load: 60, ResetWindow: 5 * time.Hour})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return report
+	}
+
+	// 60%/h drains 10% per cycle; a 30% slice moves on every third cycle,
+	// so the accounts are first used, and refill, 30 minutes apart.
+	slice := run(config.BalanceConfig{Mode: config.BalanceSlice, Slice: 30})
+	var refills []string
+	for _, r := range slice.Refills {
+		refills = append(refills, r.Account+r.Time.Format(" 15:04"))
+	}
+	if strings.Join(refills, ", ") != "a 13:00, b 13:30, c 14:00" {
+		t.Errorf("refills = %v", refills)
+	}
+	if slice.Switches[1].Time != simStart.Add(30*time.Minute) || slice.Switches[1].Account != "b" {
+		t.Errorf("second switch = %+v, want b at 08:30", slice.Switches[1])
+	}
+	if slice.Spread > 30 {
+		t.Errorf("slice spread %.1f above the slice", slice.Spread)
+	}
+
+	weighted := run(config.BalanceConfig{Mode: config.BalanceWeighted})
+	for _, account := range []string{"a", "b", "c"} {
+		if weighted.Active[account] < time.Hour {
+			t.Errorf("weighted: %s active for %s only", account, weighted.Active[account])
+		}
+	}
+	if weighted.Spread > 30 {
+		t.Errorf("weighted spread %.1f", weighted.Spread)
+	}
+}
+
+func TestSimulateErrors(t *testing.T) {
+	if _, err := Simulate(simConfig(), nil, SimOptions{}); err == nil {
+		t.Errorf("empty trace accepted")
+	}
+	rc := simConfig()
+	rc.RotateInterval = 0
+	samples := []trace.Sample{{Time: simStart, Account: "a", Model: pro, Percent: 50}}
+	if _, err := Simulate(rc, samples, SimOptions{End: simStart.Add(time.Hour)}); err == nil {
+		t.Errorf("zero rotate interval accepted")
+	}
+	if _, err := Simulate(simConfig(), samples, SimOptions{}); err == nil {
+		t.Errorf("trace without duration accepted")
+	}
+}
@@ -0,0 +1,218 @@
+package engine
+
+import (
+	"antigravity-rotator-v2/internal/config"
+	"antigravity-rotator-v2/internal/fsutil"
+	"antigravity-rotator-v2/internal/scanner"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Profile merge actions reported in a WorkspaceSyncPlan.
+const (
+	SyncAdd    = "add"    // profile missing in the workspace, copied over
+	SyncUpdate = "update" // workspace copy is older, replaced
+	SyncKeep   = "keep"   // workspace copy kept (newer token or agent-only)
+)
+
+type ProfileChange struct {
+	Profile string `json:"profile"`
+	Action  string `json:"action"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// WorkspaceSyncPlan is the per-workspace result of merging the primary
+// auth-profiles.json into a workspace, used both as preview and as report.
+type WorkspaceSyncPlan struct {
+	Installation string          `json:"installation"`
+	Path         string          `json:"path"`
+	Excluded     bool            `json:"excluded"`
+	Changes      []ProfileChange `json:"changes"`
+	Error        string          `json:"error,omitempty"`
+}
+
+// Writes reports whether applying the plan modifies the workspace file.
+func (p WorkspaceSyncPlan) Writes() bool {
+	if p.Excluded || p.Error != "" {
+		return false
+	}
+	for _, c := range p.Changes {
+		if c.Action != SyncKeep {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeProfiles merges source profiles into target. Profiles only in the
+// target are preserved; for profiles in both, the winner is picked by
+// scanner.WorkspaceWins, as in drift checks.
+func mergeProfiles(source, target map[string]interface{}) (map[string]interface{}, []ProfileChange) {
+	merged := make(map[string]interface{}, len(target))
+	for k, v := range target {
+		merged[k] = v
+	}
+
+	var changes []ProfileChange
+	for key, sp := range source {
+		tp, exists := target[key]
+		switch {
+		case !exists:
+			merged[key] = sp
+			changes = append(changes, ProfileChange{Profile: key, Action: SyncAdd})
+		case reflect.DeepEqual(sp, tp):
+		case scanner.WorkspaceWins(scanner.ProfileExpiry(sp), scanner.ProfileExpiry(tp)):
+			changes = append(changes, ProfileChange{Profile: key, Action: SyncKeep, Reason: "工作区令牌更新"})
+		default:
+			merged[key] = sp
+			changes = append(changes, ProfileChange{Profile: key, Action: SyncUpdate})
+		}
+	}
+	for key := range target {
+		if _, ok := source[key]; !ok {
+			changes = append(changes, ProfileChange{Profile: key, Action: SyncKeep, Reason: "仅存在于该工作区"})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Profile < changes[j].Profile })
+	return merged, changes
+}
+
+func (e *RotatorEngine) syncExcluded(workspacePath string) bool {
+	agentName := filepath.Base(filepath.Dir(workspacePath))
+	for _, ex := range e.Config().Rotator.SyncExclude {
+		if ex == workspacePath || ex == agentName {
+			return true
+		}
+	}
+	return false
+}
+
+// syncWorkspaces merges the installation's auth file into every other
+// workspace of that installation, or only into the workspace at only when it
+// is non-empty. With apply false it only computes the plans.
+func (e *RotatorEngine) syncWorkspaces(inst config.Installation, only string, apply bool) ([]WorkspaceSyncPlan, error) {
+	sourcePath := inst.AuthPath()
+	workspaces, err := scanner.ScanWorkspaces(inst.Home)
+	if err != nil {
+		return nil, err
+	}
+	sourceData, err := e.readJSON(sourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取凭据失败: %v", err)
+	}
+	sourceProfiles, _ := sourceData["profiles"].(map[string]interface{})
+	absSource, _ := filepath.Abs(sourcePath)
+
+	var plans []WorkspaceSyncPlan
+	for _, wp := range workspaces {
+		target := filepath.Join(wp.Path, "auth-profiles.json")
+		absTarget, _ := filepath.Abs(target)
+		if absTarget == absSource || (only != "" && wp.Path != only) {
+			continue
+		}
+
+		plan := WorkspaceSyncPlan{Installation: inst.Name, Path: wp.Path, Excluded: e.syncExcluded(wp.Path)}
+		if plan.Excluded {
+			plans = append(plans, plan)
+			continue
+		}
+
+		merge := func(doc map[string]interface{}) bool {
+			targetProfiles, _ := doc["profiles"].(map[string]interface{})
+			merged, changes := mergeProfiles(sourceProfiles, targetProfiles)
+			plan.Changes = changes
+			if !plan.Writes() {
+				return false
+			}
+			doc["profiles"] = merged
+			for k, v := range sourceData {
+				if _, ok := doc[k]; !ok && k != "profiles" {
+					doc[k] = v
+				}
+			}
+			return true
+		}
+
+		if apply {
+			err = fsutil.WithLock(target, func() error {
+				doc, err := e.readJSON(target)
+				if os.IsNotExist(err) {
+					doc, err = make(map[string]interface{}), nil
+				}
+				if err != nil {
+					return err
+				}
+				if !merge(doc) {
+					return nil
+				}
+				return e.writeJSON(target, doc)
+			})
+		} else {
+			var doc map[string]interface{}
+			doc, err = e.readJSON(target)
+			if os.IsNotExist(err) {
+				doc, err = make(map[string]interface{}), nil
+			}
+			if err == nil {
+				merge(doc)
+			}
+		}
+		if err != nil {
+			plan.Error = err.Error()
+		}
+		plans = append(plans, plan)
+	}
+	return plans, nil
+}
+
+// PreviewSync shows what SyncAuth would change in each workspace.
+func (e *RotatorEngine) PreviewSync() ([]WorkspaceSyncPlan, error) {
+	var all []WorkspaceSyncPlan
+	for _, inst := range e.installations() {
+		plans, err := e.syncWorkspaces(inst, "", false)
+		if err != nil {
+			return all, fmt.Errorf("[%s] %v", inst.Name, err)
+		}
+		all = append(all, plans...)
+	}
+	return all, nil
+}
+
+// SyncAuth merges each installation's primary auth file into its agent
+// workspaces.
+func (e *RotatorEngine) SyncAuth() ([]WorkspaceSyncPlan, error) {
+	var all []WorkspaceSyncPlan
+	for _, inst := range e.installations() {
+		plans, err := e.syncInstallation(inst)
+		if err != nil {
+			return all, fmt.Errorf("[%s] %v", inst.Name, err)
+		}
+		all = append(all, plans...)
+	}
+	return all, nil
+}
+
+// syncInstallation merges the installation's auth profiles into every agent
+// workspace that is not excluded, leaving workspace-only profiles and newer
+// workspace tokens intact.
+func (e *RotatorEngine) syncInstallation(inst config.Installation) ([]WorkspaceSyncPlan, error) {
+	return e.syncWorkspaces(inst, "", true)
+}
+
+// FixDrift re-syncs a single workspace from its installation's primary auth
+// file, or every workspace when path is empty.
+func (e *RotatorEngine) FixDrift(path string) ([]WorkspaceSyncPlan, error) {
+	if path == "" {
+		return e.SyncAuth()
+	}
+	for _, inst := range e.installations() {
+		if path == inst.Home || strings.HasPrefix(path, inst.Home+string(filepath.Separator)) {
+			return e.syncWorkspaces(inst, path, true)
+		}
+	}
+	return nil, fmt.Errorf("工作区不属于任何已管理的安装实例: %s", path)
+}
@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"antigravity-rotator-v2/internal/google"
+	"time"
+)
+
+// tierRecheck is how long a loadCodeAssist result is reused before the
+// account's tier is fetched again.
+const tierRecheck = 6 * time.Hour
+
+// AccountTier is the Code Assist entitlement of an account.
+type AccountTier struct {
+	Email string `json:"email"`
+	google.CodeAssistInfo
+	// Tier is the effective tier ID (the paid tier when there is one).
+	Tier      string    `json:"tier"`
+	TierName  string    `json:"tierName"`
+	Rank      int       `json:"rank"`
+	Onboarded bool      `json:"onboarded"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// recordTier stores the loadCodeAssist result of an account.
+func (e *RotatorEngine) recordTier(email string, info *google.CodeAssistInfo) *AccountTier {
+	at := &AccountTier{
+		Email:          email,
+		CodeAssistInfo: *info,
+		Rank:           google.TierRank(info.Tier()),
+		Onboarded:      info.Onboarded(),
+		CheckedAt:      e.now(),
+	}
+	if t := info.Tier(); t != nil {
+		at.Tier, at.TierName = t.ID, t.Name
+	}
+
+	e.tierMu.Lock()
+	defer e.tierMu.Unlock()
+	if e.tiers == nil {
+		e.tiers = make(map[string]*AccountTier)
+	}
+	e.tiers[email] = at
+	return at
+}
+
+// tierOf returns the stored tier of an account and whether it is recent
+// enough to skip loadCodeAssist.
+func (e *RotatorEngine) tierOf(email string) (*AccountTier, bool) {
+	e.tierMu.Lock()
+	defer e.tierMu.Unlock()
+	at, ok := e.tiers[email]
+	if !ok {
+		return nil, false
+	}
+	return at, e.now().Sub(at.CheckedAt) < tierRecheck
+}
+
+// tierRank is the rotation preference of an account; unknown tiers rank 0.
+func (e *RotatorEngine) tierRank(email string) int {
+	if at, _ := e.tierOf(email); at != nil {
+		return at.Rank
+	}
+	return 0
+}
+
+// usable reports whether rotation may pick an account: it is enabled and
+// not known to be without onboarding.
+func (e *RotatorEngine) usable(email string) bool {
+	if e.isDisabled(email) {
+		return false
+	}
+	at, _ := e.tierOf(email)
+	return at == nil || at.Onboarded
+}
+
+// AccountTiers returns the stored tier of every managed account that has
+// been checked, in config order.
+func (e *RotatorEngine) AccountTiers() []AccountTier {
+	out := []AccountTier{}
+	for _, email := range e.Config().Rotator.Accounts {
+		if at, _ := e.tierOf(email); at != nil {
+			out = append(out, *at)
+		}
+	}
+	return out
+}
@@ -0,0 +1,43 @@
+package engine
+
+import (
+	"antigravity-rotator-v2/internal/google"
+	"antigravity-rotator-v2/internal/trace"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// tracePath is the quota trace of the primary installation.
+func (e *RotatorEngine) tracePath() string {
+	return trace.Path(e.primary().Home)
+}
+
+// recordTrace appends the quotas of one poll to the quota trace when
+// recordTrace is on. Failures are only logged.
+func (e *RotatorEngine) recordTrace(email string, quotas map[string]google.ModelQuota) {
+	if !e.Config().Rotator.RecordTrace {
+		return
+	}
+	now := e.now()
+	samples := make([]trace.Sample, 0, len(quotas))
+	for model, q := range quotas {
+		samples = append(samples, trace.Sample{Time: now, Account: email, Model: model, Percent: q.Percent, ResetTime: q.ResetTime})
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Model < samples[j].Model })
+	if err := trace.Append(e.tracePath(), samples); err != nil {
+		fmt.Printf("Failed to record quota trace: %v\n", err)
+	}
+}
+
+// QuotaTrace returns the recorded quota trace, oldest first.
+func (e *RotatorEngine) QuotaTrace() ([]trace.Sample, error) {
+	data, err := os.ReadFile(e.tracePath())
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("尚未记录配额轨迹，请先开启 recordTrace")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return trace.Parse(e.tracePath(), data)
+}
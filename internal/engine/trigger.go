@@ -0,0 +1,295 @@
+package engine
+
+import (
+	"antigravity-rotator-v2/internal/config"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// triggerDebounce collects a burst of signals into one rotation.
+const triggerDebounce = 3 * time.Second
+
+// triggerState debounces event-driven rotations.
+type triggerState struct {
+	mu      sync.Mutex
+	pending *time.Timer
+	reasons []string
+	last    time.Time
+	// webhook and its listener run until the auto loop stops.
+	webhook   *http.Server
+	webhookLn net.Listener
+}
+
+// TriggerRotation requests a rotation cycle because of an external signal:
+// a quota error in the gateway log, a webhook call, the active-account check
+// or a manual request. Bursts are collapsed into one cycle, and triggered
+// cycles are at least the configured cooldown apart. It fails when
+// auto-rotation is off or paused by a schedule.
+func (e *RotatorEngine) TriggerRotation(reason string) error {
+	if err := e.TriggerPaused(); err != nil {
+		return err
+	}
+	t := &e.trig
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e.emit("rotation_triggered", reason)
+	if t.pending != nil {
+		t.reasons = append(t.reasons, reason)
+		return nil
+	}
+	delay := triggerDebounce
+	cooldown := time.Duration(e.Config().Rotator.Triggers.Cooldown()) * time.Second
+	if wait := t.last.Add(cooldown).Sub(e.now()); wait > delay {
+		delay = wait
+	}
+	t.reasons = []string{reason}
+	t.pending = time.AfterFunc(delay, e.runTriggered)
+	return nil
+}
+
+// TriggerPaused explains why triggered rotations are refused right now, or
+// is nil. Every trigger path checks it, also the CLI rotating without a
+// running instance.
+func (e *RotatorEngine) TriggerPaused() error {
+	return pausedError(e.CurrentPolicy())
+}
+
+// pausedError explains why a policy does not allow rotations, or is nil.
+func pausedError(p config.Policy) error {
+	switch {
+	case p.AutoRotate:
+		return nil
+	case p.Schedule != "":
+		return fmt.Errorf("自动轮换已被计划 %s 暂停", p.Schedule)
+	}
+	return fmt.Errorf("自动轮换未开启")
+}
+
+func (e *RotatorEngine) runTriggered() {
+	t := &e.trig
+	t.mu.Lock()
+	reasons := t.reasons
+	t.pending, t.reasons = nil, nil
+	t.mu.Unlock()
+	if e.Standby() {
+		return
+	}
+	// The policy may have changed while the trigger was debounced.
+	if err := pausedError(e.CurrentPolicy()); err != nil {
+		fmt.Printf("Triggered rotation skipped: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Triggered rotation: %s\n", strings.Join(reasons, "; "))
+	result := e.runCycle("trigger: " + strings.Join(reasons, "; "))
+	e.emit("rotation_completed", map[string]interface{}{"reasons": reasons, "result": result})
+}
+
+// markRotated starts the trigger cooldown; RunCycle calls it after every
+// cycle, however it was started.
+func (e *RotatorEngine) markRotated() {
+	e.trig.mu.Lock()
+	e.trig.last = e.now()
+	e.trig.mu.Unlock()
+}
+
+// startTriggers starts the configured signal sources; they stop with the
+// auto loop. Called with loopMu held.
+func (e *RotatorEngine) startTriggers(tc config.TriggerConfig, stop chan struct{}) {
+	if tc.GatewayLog != "" {
+		if pattern, err := tc.Pattern(); err == nil {
+			go e.tailLog(tc.GatewayLog, pattern, stop)
+		}
+	}
+	if tc.WebhookAddr != "" {
+		if err := e.serveWebhook(tc); err != nil {
+			err = fmt.Errorf("触发器 webhook 启动失败: %v", err)
+			fmt.Println(err)
+			e.emit("trigger_error", err.Error())
+		}
+	}
+	if tc.ActiveCheckSeconds > 0 {
+		go func() {
+			ticker := time.NewTicker(time.Duration(tc.ActiveCheckSeconds) * time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					e.checkActive()
+				}
+			}
+		}()
+	}
+}
+
+// logPath expands a {date} placeholder, for gateways that log to one file
+// per day.
+func logPath(pattern string) string {
+	return strings.ReplaceAll(pattern, "{date}", time.Now().Format("2006-01-02"))
+}
+
+// tailLog polls the gateway log for appended lines and triggers a rotation
+// when one matches pattern. It starts at the end of the file and follows
+// truncation and daily rotation.
+func (e *RotatorEngine) tailLog(pathPattern string, pattern *regexp.Regexp, stop chan struct{}) {
+	const maxChunk = 1 << 20
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	path := ""
+	var offset int64
+	var partial string
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		current := logPath(pathPattern)
+		fi, err := os.Stat(current)
+		if err != nil {
+			continue
+		}
+		size := fi.Size()
+		switch {
+		case path == "":
+			offset = size // only new lines count
+		case current != path || size < offset:
+			offset, partial = 0, "" // rotated or truncated
+		}
+		path = current
+		if size == offset {
+			continue
+		}
+		if size-offset > maxChunk {
+			offset, partial = size-maxChunk, ""
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		buf := make([]byte, size-offset)
+		n, _ := f.ReadAt(buf, offset)
+		f.Close()
+		offset += int64(n)
+
+		lines := strings.Split(partial+string(buf[:n]), "\n")
+		partial = lines[len(lines)-1]
+		for _, line := range lines[:len(lines)-1] {
+			if pattern.MatchString(line) {
+				line = strings.TrimSpace(line)
+				if len(line) > 200 {
+					line = line[:200]
+				}
+				e.TriggerRotation("gateway log: " + line)
+				break
+			}
+		}
+	}
+}
+
+// serveWebhook accepts POST /trigger with an optional {"reason": "..."} body.
+// The server runs until stopWebhook.
+func (e *RotatorEngine) serveWebhook(tc config.TriggerConfig) error {
+	ln, err := net.Listen("tcp", tc.WebhookAddr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/trigger", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if tc.WebhookToken != "" && r.Header.Get("Authorization") != "Bearer "+tc.WebhookToken {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		var req struct {
+			Reason string `json:"reason"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Reason == "" {
+			req.Reason = "quota error reported"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := e.TriggerRotation("webhook: " + req.Reason); err != nil {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"ok":true}` + "\n"))
+	})
+
+	srv := &http.Server{Handler: mux}
+	e.trig.mu.Lock()
+	e.trig.webhook, e.trig.webhookLn = srv, ln
+	e.trig.mu.Unlock()
+	go srv.Serve(ln)
+	return nil
+}
+
+// stopWebhook closes the webhook server and its listener before returning,
+// so a restarted loop can listen on the same address at once.
+func (e *RotatorEngine) stopWebhook() {
+	e.trig.mu.Lock()
+	srv, ln := e.trig.webhook, e.trig.webhookLn
+	e.trig.webhook, e.trig.webhookLn = nil, nil
+	e.trig.mu.Unlock()
+	if srv != nil {
+		// Serve may not have taken over the listener yet.
+		ln.Close()
+		srv.Close()
+	}
+}
+
+// checkActive refreshes only the active account and triggers a rotation
+// when its quota for the default model is at or below the threshold.
+func (e *RotatorEngine) checkActive() {
+	ocData, err := e.gatewayConfig(e.primary())
+	if err != nil {
+		return
+	}
+	email := activeAccount(ocData)
+	model := modelPrimary(defaultsModel(ocData))
+	e.setActive(email)
+	if email == "" || model == "" || e.isDisabled(email) {
+		return
+	}
+	profiles, err := e.loadProfiles()
+	if err != nil {
+		return
+	}
+	task, ok := accountTaskFor(profiles, email)
+	if !ok {
+		return
+	}
+
+	if err := e.refreshAccount(task); err != nil {
+		return
+	}
+	e.mu.Lock()
+	q, known := e.Status[email+":"+model]
+	e.mu.Unlock()
+	if !known {
+		return
+	}
+	e.emitStatus()
+
+	if q <= e.policy().Low {
+		e.TriggerRotation(fmt.Sprintf("active account %s at %d%% on %s", email, q, model))
+	}
+}
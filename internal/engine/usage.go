@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"antigravity-rotator-v2/internal/usage"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// UsageIngest is what IngestUsage read.
+type UsageIngest struct {
+	Files  int `json:"files"`
+	Events int `json:"events"`
+	// Added counts events not seen by earlier ingests.
+	Added int `json:"added"`
+	// Foreign counts requests to other providers, which are not stored.
+	Foreign int `json:"foreign"`
+}
+
+// UsageQuery selects a usage report. Zero values default to daily usage per
+// account over the last seven days up to the end of today (four weeks for
+// weekly reports).
+type UsageQuery struct {
+	By     string    `json:"by"`
+	Period string    `json:"period"`
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+}
+
+// usagePath is the usage store of the primary installation.
+func (e *RotatorEngine) usagePath() string {
+	return usage.Path(e.primary().Home)
+}
+
+// usageSources returns the logs ingested by default: the session
+// transcripts of every agent on every installation, and the gateway log
+// watched by the triggers.
+func (e *RotatorEngine) usageSources() []string {
+	var paths []string
+	for _, inst := range e.installations() {
+		found, _ := filepath.Glob(filepath.Join(inst.Home, "agents", "*", "sessions", "*.jsonl"))
+		paths = append(paths, found...)
+	}
+	if p := e.Config().Rotator.Triggers.GatewayLog; p != "" {
+		found, _ := filepath.Glob(strings.ReplaceAll(p, "{date}", "*"))
+		paths = append(paths, found...)
+	}
+	return paths
+}
+
+// sessionAgent returns the agent of an agents/<id>/sessions/ transcript, or
+// "" for other files.
+func sessionAgent(path string) string {
+	dir := filepath.Dir(path)
+	if filepath.Base(dir) != "sessions" {
+		return ""
+	}
+	return filepath.Base(filepath.Dir(dir))
+}
+
+// IngestUsage reads usage events from paths, or from the default sources
+// when paths is empty, into the usage store. Events already stored are
+// skipped, so logs can be ingested repeatedly.
+func (e *RotatorEngine) IngestUsage(paths []string) (UsageIngest, error) {
+	explicit := len(paths) > 0
+	if !explicit {
+		paths = e.usageSources()
+	}
+
+	var res UsageIngest
+	var events []usage.Event
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if explicit {
+				return res, fmt.Errorf("读取日志失败: %v", err)
+			}
+			continue
+		}
+		parsed := usage.Parse(data, sessionAgent(path))
+		res.Files++
+		res.Events += len(parsed.Events)
+		res.Foreign += parsed.Foreign
+		events = append(events, parsed.Events...)
+	}
+	added, err := usage.Merge(e.usagePath(), events)
+	if err != nil {
+		return res, fmt.Errorf("保存用量数据失败: %v", err)
+	}
+	res.Added = added
+	return res, nil
+}
+
+// UsageReport aggregates the stored usage, attributing requests that do not
+// name their account or model to what the rotation history says was active
+// at the time.
+func (e *RotatorEngine) UsageReport(q UsageQuery) (usage.Report, error) {
+	if q.By == "" {
+		q.By = usage.ByAccount
+	}
+	if q.Period == "" {
+		q.Period = usage.Day
+	}
+	if q.To.IsZero() {
+		q.To = usage.PeriodStart(time.Now(), usage.Day).AddDate(0, 0, 1)
+	}
+	if q.From.IsZero() {
+		start := usage.PeriodStart(q.To.Add(-time.Nanosecond), q.Period)
+		if q.Period == usage.Week {
+			q.From = start.AddDate(0, 0, -3*7)
+		} else {
+			q.From = start.AddDate(0, 0, -6)
+		}
+	}
+
+	events, err := usage.Read(e.usagePath())
+	if err != nil {
+		return usage.Report{}, fmt.Errorf("读取用量数据失败: %v", err)
+	}
+	entries, err := e.History()
+	if err != nil {
+		return usage.Report{}, fmt.Errorf("读取轮换历史失败: %v", err)
+	}
+	usage.Attribute(events, entries)
+	return usage.Build(events, q.By, q.Period, q.From, q.To, time.Local)
+}
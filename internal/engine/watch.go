@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"antigravity-rotator-v2/internal/config"
+	"antigravity-rotator-v2/internal/watcher"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const watchDebounce = 750 * time.Millisecond
+
+// StartWatcher begins watching every installation's home for new
+// workspaces and external edits of credential and config files.
+func (e *RotatorEngine) StartWatcher() error {
+	e.watchMu.Lock()
+	defer e.watchMu.Unlock()
+	e.stopWatchersLocked()
+	for _, inst := range e.installations() {
+		inst := inst
+		w, err := watcher.New(inst.Home, watchDebounce, func(change watcher.Change) {
+			e.onFilesChanged(inst, change)
+		})
+		if err != nil {
+			return fmt.Errorf("[%s] 启动文件监听失败: %v", inst.Name, err)
+		}
+		e.watchers = append(e.watchers, w)
+	}
+	return nil
+}
+
+// StopWatcher stops the file watchers if they are running.
+func (e *RotatorEngine) StopWatcher() {
+	e.watchMu.Lock()
+	defer e.watchMu.Unlock()
+	e.stopWatchersLocked()
+}
+
+func (e *RotatorEngine) stopWatchersLocked() {
+	for _, w := range e.watchers {
+		w.Close()
+	}
+	e.watchers = nil
+}
+
+// watching reports whether the file watchers are running.
+func (e *RotatorEngine) watching() bool {
+	e.watchMu.Lock()
+	defer e.watchMu.Unlock()
+	return len(e.watchers) > 0
+}
+
+func (e *RotatorEngine) emit(event string, data ...interface{}) {
+	if e.ctx != nil {
+		runtime.EventsEmit(e.ctx, event, data...)
+	}
+}
+
+func (e *RotatorEngine) onFilesChanged(inst config.Installation, change watcher.Change) {
+	if change.Workspaces {
+		e.emit("workspaces_changed", e.GetWorkspaces())
+	}
+
+	if len(change.Credentials) > 0 {
+		e.emit("credentials_changed", change.Credentials)
+		authPath := inst.AuthPath()
+		for _, p := range change.Credentials {
+			if p == authPath && e.Config().Rotator.AutoSync && !e.Standby() {
+				// Merge sync only writes workspaces that actually differ,
+				// so the events caused by this sync settle on their own.
+				if plans, err := e.syncInstallation(inst); err == nil {
+					e.emit("sync_completed", plans)
+				}
+			}
+		}
+	}
+
+	for _, p := range change.Configs {
+		if p == config.GetConfigPath() {
+			if err := e.ReloadConfig(); err != nil {
+				e.emit("config_load_error", err.Error())
+			}
+		} else {
+			e.emit("gateway_config_changed", p)
+		}
+	}
+}
+
+// ReloadConfig picks up edits of the rotator config made outside this
+// process (by hand, the CLI or a client instance). The config is applied
+// even when its migration changed settings; the MigrationNotices are
+// returned so they can be shown.
+func (e *RotatorEngine) ReloadConfig() error {
+	cfg, err := config.LoadConfig()
+	var notices config.MigrationNotices
+	if err != nil && !errors.As(err, &notices) {
+		return err
+	}
+	if !sameJSON(cfg, e.Config()) {
+		if _, err := e.ApplyConfig(cfg); err != nil {
+			return err
+		}
+	}
+	if len(notices) > 0 {
+		return notices
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package fake
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// EnvGateway makes a binary that calls RunGatewayIfRequested act as the
+// openclaw CLI.
+const EnvGateway = "ANTIGRAVITY_ROTATOR_FAKE_OPENCLAW"
+
+const (
+	callLog  = "fake-openclaw.log"
+	failFile = "fake-openclaw.fail"
+)
+
+// Gateway is a fake openclaw gateway keeping its config in the OpenClaw
+// home's openclaw.json. config.get returns it with a content hash and
+// config.patch merges a patch into it, rejecting stale base hashes.
+type Gateway struct {
+	// Bin is the openclaw binary to configure: the test binary itself.
+	Bin  string
+	Home string
+}
+
+// NewGateway writes cfg as the gateway config of home and makes the test
+// binary act as openclaw in child processes. The test's TestMain must call
+// RunGatewayIfRequested.
+func NewGateway(t testing.TB, home string, cfg map[string]interface{}) *Gateway {
+	t.Helper()
+	data, _ := json.MarshalIndent(cfg, "", "  ")
+	if err := os.WriteFile(filepath.Join(home, "openclaw.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvGateway, "1")
+	return &Gateway{Bin: os.Args[0], Home: home}
+}
+
+// Config returns the current gateway config.
+func (g *Gateway) Config(t testing.TB) map[string]interface{} {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(g.Home, "openclaw.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg map[string]interface{}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// Calls returns the gateway methods called so far, e.g. "config.patch".
+func (g *Gateway) Calls() []string {
+	data, _ := os.ReadFile(filepath.Join(g.Home, callLog))
+	return strings.Fields(string(data))
+}
+
+// FailNext makes the next call of method fail.
+func (g *Gateway) FailNext(method string) {
+	os.WriteFile(filepath.Join(g.Home, failFile), []byte(method), 0644)
+}
+
+// RunGatewayIfRequested runs the fake openclaw CLI and exits when the
+// process was started by the engine as the gateway binary. Call it first
+// in TestMain.
+func RunGatewayIfRequested() {
+	if os.Getenv(EnvGateway) == "" {
+		return
+	}
+	os.Exit(runGateway(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func runGateway(args []string, stdout, stderr io.Writer) int {
+	home := os.Getenv("OPENCLAW_STATE_DIR")
+	path := os.Getenv("OPENCLAW_CONFIG_PATH")
+	if path == "" {
+		path = filepath.Join(home, "openclaw.json")
+	} else {
+		home = filepath.Dir(path)
+	}
+	if len(args) < 3 || args[0] != "gateway" || args[1] != "call" {
+		if len(args) == 2 && args[0] == "gateway" && args[1] == "restart" {
+			return 0
+		}
+		fmt.Fprintf(stderr, "fake openclaw: unsupported command %v\n", args)
+		return 2
+	}
+	method := args[2]
+
+	f, _ := os.OpenFile(filepath.Join(home, callLog), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	fmt.Fprintln(f, method)
+	f.Close()
+	if data, err := os.ReadFile(filepath.Join(home, failFile)); err == nil && string(data) == method {
+		os.Remove(filepath.Join(home, failFile))
+		fmt.Fprintf(stderr, "Error: gateway unavailable\n")
+		return 1
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	var cfg map[string]interface{}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		fmt.Fprintf(stderr, "Error: invalid config: %v\n", err)
+		return 1
+	}
+
+	switch method {
+	case "config.get":
+		// Real gateways print plugin logs before the JSON.
+		fmt.Fprintln(stdout, "[plugins] loaded 0 plugins")
+		out, _ := json.Marshal(map[string]interface{}{"hash": hash(data), "config": cfg})
+		fmt.Fprintln(stdout, string(out))
+		return 0
+	case "config.patch":
+		if len(args) < 5 || args[3] != "--params" {
+			fmt.Fprintln(stderr, "Error: missing --params")
+			return 2
+		}
+		var params struct {
+			Raw      string `json:"raw"`
+			BaseHash string `json:"baseHash"`
+		}
+		if err := json.Unmarshal([]byte(args[4]), &params); err != nil {
+			fmt.Fprintf(stderr, "Error: invalid params: %v\n", err)
+			return 2
+		}
+		if params.BaseHash != hash(data) {
+			fmt.Fprintln(stderr, "Error: config changed since last load; re-run config.get")
+			return 1
+		}
+		var patch interface{}
+		if err := json.Unmarshal([]byte(params.Raw), &patch); err != nil {
+			fmt.Fprintf(stderr, "Error: invalid patch: %v\n", err)
+			return 1
+		}
+		merged, _ := json.MarshalIndent(mergePatch(cfg, patch), "", "  ")
+		if err := os.WriteFile(path, merged, 0644); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, `{"ok":true}`)
+		return 0
+	}
+	fmt.Fprintf(stderr, "Error: unknown method %s\n", method)
+	return 1
+}
+
+func hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// mergePatch applies a JSON merge patch (RFC 7386): objects merge, null
+// deletes, everything else replaces.
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{})
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = mergePatch(t[k], v)
+	}
+	return t
+}
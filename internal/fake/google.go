@@ -0,0 +1,318 @@
+// Package fake provides in-process stand-ins for the Google endpoints and
+// the openclaw gateway, so the engine can be tested without network access
+// or a real OpenClaw install.
+package fake
+
+import (
+	"antigravity-rotator-v2/internal/google"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Endpoint names accepted by Google.Fail and Google.Calls.
+const (
+	EndpointAuth           = "auth"
+	EndpointToken          = "token"
+	EndpointUserInfo       = "userinfo"
+	EndpointLoadCodeAssist = "loadCodeAssist"
+	EndpointOnboardUser    = "onboardUser"
+	EndpointModels         = "fetchAvailableModels"
+)
+
+// Quota is the quota of one upstream model.
+type Quota struct {
+	Fraction float64
+	Reset    time.Time // zero: no reset time reported
+}
+
+// Account is a Google account known to the fake.
+type Account struct {
+	Email   string
+	Refresh string
+	// Project is empty until the account is onboarded.
+	Project string
+	// Tier defaults to free-tier.
+	Tier string
+	// Quotas are keyed by raw model ID, e.g. gemini-3-flash.
+	Quotas map[string]Quota
+}
+
+type failure struct {
+	status int
+	body   string
+	times  int // <0: until cleared
+}
+
+// Google fakes the OAuth, userinfo and Code Assist endpoints.
+type Google struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	accounts  map[string]*Account // by email
+	access    map[string]string   // access token -> email
+	codes     map[string]string   // auth code -> email
+	challenge map[string]string   // auth code -> PKCE challenge
+	loginAs   string
+	fails     map[string]*failure
+	calls     map[string]int
+	polls     map[string]int // onboardUser calls per account
+
+	// OnboardPolls is how many onboardUser calls report the operation as
+	// still running before the project is ready.
+	OnboardPolls int
+}
+
+// NewGoogle starts a fake Google server. Close it when done.
+func NewGoogle() *Google {
+	g := &Google{
+		accounts:  make(map[string]*Account),
+		access:    make(map[string]string),
+		codes:     make(map[string]string),
+		challenge: make(map[string]string),
+		fails:     make(map[string]*failure),
+		calls:     make(map[string]int),
+		polls:     make(map[string]int),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/auth", g.handle(EndpointAuth, g.auth))
+	mux.HandleFunc("/token", g.handle(EndpointToken, g.token))
+	mux.HandleFunc("/userinfo", g.handle(EndpointUserInfo, g.userInfo))
+	mux.HandleFunc("/v1internal:loadCodeAssist", g.handle(EndpointLoadCodeAssist, g.loadCodeAssist))
+	mux.HandleFunc("/v1internal:onboardUser", g.handle(EndpointOnboardUser, g.onboardUser))
+	mux.HandleFunc("/v1internal:fetchAvailableModels", g.handle(EndpointModels, g.models))
+	g.Server = httptest.NewServer(mux)
+	return g
+}
+
+// Endpoints points a google.Client at the fake.
+func (g *Google) Endpoints() google.Endpoints {
+	return google.Endpoints{
+		Auth:                 g.URL + "/auth",
+		Token:                g.URL + "/token",
+		UserInfo:             g.URL + "/userinfo",
+		LoadCodeAssist:       g.URL + "/v1internal:loadCodeAssist",
+		OnboardUser:          g.URL + "/v1internal:onboardUser",
+		FetchAvailableModels: g.URL + "/v1internal:fetchAvailableModels",
+	}
+}
+
+// AddAccount adds or replaces an account.
+func (g *Google) AddAccount(a Account) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if a.Tier == "" {
+		a.Tier = google.TierFree
+	}
+	if a.Quotas == nil {
+		a.Quotas = make(map[string]Quota)
+	}
+	g.accounts[a.Email] = &a
+}
+
+// SetQuota sets the remaining fraction of one upstream model of an account.
+func (g *Google) SetQuota(email, model string, fraction float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	a := g.accounts[email]
+	q := a.Quotas[model]
+	q.Fraction = fraction
+	a.Quotas[model] = q
+}
+
+// Project returns the project of an account, empty if not onboarded.
+func (g *Google) Project(email string) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.accounts[email].Project
+}
+
+// LoginAs makes the consent page sign in as email.
+func (g *Google) LoginAs(email string) {
+	g.mu.Lock()
+	g.loginAs = email
+	g.mu.Unlock()
+}
+
+// Fail makes the next times calls to an endpoint answer with status and
+// body; times < 0 fails until Fail is called again with times 0.
+func (g *Google) Fail(endpoint string, status int, body string, times int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if times == 0 {
+		delete(g.fails, endpoint)
+		return
+	}
+	g.fails[endpoint] = &failure{status: status, body: body, times: times}
+}
+
+// Calls returns how often an endpoint has been called.
+func (g *Google) Calls(endpoint string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.calls[endpoint]
+}
+
+func (g *Google) handle(name string, fn func(w http.ResponseWriter, r *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		g.mu.Lock()
+		g.calls[name]++
+		f := g.fails[name]
+		if f != nil && f.times > 0 {
+			if f.times--; f.times == 0 {
+				delete(g.fails, name)
+			}
+		}
+		g.mu.Unlock()
+		if f != nil {
+			http.Error(w, f.body, f.status)
+			return
+		}
+		fn(w, r)
+	}
+}
+
+// account returns the account of the bearer token, or answers 401.
+func (g *Google) account(w http.ResponseWriter, r *http.Request) *Account {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if email, ok := g.access[token]; ok {
+		return g.accounts[email]
+	}
+	http.Error(w, `{"error":{"code":401,"status":"UNAUTHENTICATED"}}`, http.StatusUnauthorized)
+	return nil
+}
+
+func (g *Google) auth(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	g.mu.Lock()
+	email := g.loginAs
+	code := fmt.Sprintf("code-%d", len(g.codes)+1)
+	g.codes[code] = email
+	g.challenge[code] = q.Get("code_challenge")
+	g.mu.Unlock()
+	if email == "" {
+		http.Redirect(w, r, q.Get("redirect_uri")+"?error=access_denied&state="+q.Get("state"), http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, q.Get("redirect_uri")+"?code="+code+"&state="+q.Get("state"), http.StatusFound)
+}
+
+func (g *Google) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var a *Account
+	switch r.Form.Get("grant_type") {
+	case "refresh_token":
+		for _, acc := range g.accounts {
+			if acc.Refresh == r.Form.Get("refresh_token") {
+				a = acc
+			}
+		}
+	case "authorization_code":
+		code := r.Form.Get("code")
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(sum[:]) == g.challenge[code] {
+			a = g.accounts[g.codes[code]]
+		}
+		delete(g.codes, code)
+	}
+	if a == nil {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	access := fmt.Sprintf("access-%s-%d", a.Email, len(g.access)+1)
+	g.access[access] = a.Email
+	resp := map[string]interface{}{"access_token": access, "expires_in": 3599, "token_type": "Bearer"}
+	if r.Form.Get("grant_type") == "authorization_code" {
+		resp["refresh_token"] = a.Refresh
+	}
+	writeJSON(w, resp)
+}
+
+func (g *Google) userInfo(w http.ResponseWriter, r *http.Request) {
+	if a := g.account(w, r); a != nil {
+		writeJSON(w, map[string]string{"email": a.Email})
+	}
+}
+
+func (g *Google) loadCodeAssist(w http.ResponseWriter, r *http.Request) {
+	a := g.account(w, r)
+	if a == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	tier := map[string]interface{}{"id": a.Tier, "name": a.Tier}
+	resp := map[string]interface{}{
+		"allowedTiers": []interface{}{map[string]interface{}{"id": a.Tier, "isDefault": true}},
+	}
+	if a.Project != "" {
+		resp["currentTier"] = tier
+		resp["cloudaicompanionProject"] = a.Project
+	}
+	writeJSON(w, resp)
+}
+
+// onboardUser provisions the project once OnboardPolls calls have reported
+// the operation as running.
+func (g *Google) onboardUser(w http.ResponseWriter, r *http.Request) {
+	a := g.account(w, r)
+	if a == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if a.Project == "" {
+		if g.polls[a.Email] < g.OnboardPolls {
+			g.polls[a.Email]++
+			writeJSON(w, map[string]interface{}{"name": "operations/onboard", "done": false})
+			return
+		}
+		a.Project = "project-" + strings.SplitN(a.Email, "@", 2)[0]
+	}
+	writeJSON(w, map[string]interface{}{
+		"done":     true,
+		"response": map[string]interface{}{"cloudaicompanionProject": map[string]string{"id": a.Project}},
+	})
+}
+
+func (g *Google) models(w http.ResponseWriter, r *http.Request) {
+	a := g.account(w, r)
+	if a == nil {
+		return
+	}
+	var req struct{ Project string }
+	json.NewDecoder(r.Body).Decode(&req)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if req.Project == "" || req.Project != a.Project {
+		http.Error(w, `{"error":{"code":403,"status":"PERMISSION_DENIED"}}`, http.StatusForbidden)
+		return
+	}
+
+	models := make(map[string]interface{})
+	for id, q := range a.Quotas {
+		info := map[string]interface{}{"remainingFraction": q.Fraction}
+		if !q.Reset.IsZero() {
+			info["resetTime"] = q.Reset.UTC().Format(time.RFC3339)
+		}
+		models[id] = map[string]interface{}{"displayName": id, "quotaInfo": info}
+	}
+	writeJSON(w, map[string]interface{}{"models": models})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
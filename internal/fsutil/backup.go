@@ -0,0 +1,126 @@
+package fsutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	backupDirName   = ".rotator-backups"
+	backupSuffix    = ".bak"
+	backupTimestamp = "20060102T150405.000000"
+	// MaxBackups is how many backups are kept per file.
+	MaxBackups = 20
+)
+
+// BackupInfo describes one backup of a file.
+type BackupInfo struct {
+	Path     string    `json:"path"`
+	Original string    `json:"original"`
+	Time     time.Time `json:"time"`
+	Size     int64     `json:"size"`
+}
+
+// Backup copies path into the .rotator-backups directory next to it under a
+// timestamped name and prunes old backups. A missing file is not an error
+// and yields an empty backup path.
+func Backup(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(filepath.Dir(path), backupDirName)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	name := filepath.Base(path) + "." + time.Now().Format(backupTimestamp) + backupSuffix
+	target := filepath.Join(dir, name)
+	if err := WriteFileAtomic(target, data, 0600); err != nil {
+		return "", err
+	}
+
+	if backups, err := ListBackups(path); err == nil && len(backups) > MaxBackups {
+		for _, b := range backups[MaxBackups:] {
+			os.Remove(b.Path)
+		}
+	}
+	return target, nil
+}
+
+// ListBackups returns the backups of path, newest first.
+func ListBackups(path string) ([]BackupInfo, error) {
+	dir := filepath.Join(filepath.Dir(path), backupDirName)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []BackupInfo
+	for _, entry := range entries {
+		original, ts, ok := parseBackupName(entry.Name())
+		if !ok || original != filepath.Base(path) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, BackupInfo{
+			Path:     filepath.Join(dir, entry.Name()),
+			Original: path,
+			Time:     ts,
+			Size:     info.Size(),
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Time.After(backups[j].Time) })
+	return backups, nil
+}
+
+// Restore writes a backup back over the file it was taken from. The current
+// content is itself backed up first, so a restore can be undone.
+func Restore(backupPath string) (string, error) {
+	original, _, ok := parseBackupName(filepath.Base(backupPath))
+	if !ok || filepath.Base(filepath.Dir(backupPath)) != backupDirName {
+		return "", fmt.Errorf("不是有效的备份文件: %s", backupPath)
+	}
+	target := filepath.Join(filepath.Dir(filepath.Dir(backupPath)), original)
+
+	data, err := os.ReadFile(backupPath)
+	if err != nil {
+		return "", err
+	}
+	err = WithLock(target, func() error {
+		if _, err := Backup(target); err != nil {
+			return err
+		}
+		return WriteFileAtomic(target, data, 0644)
+	})
+	return target, err
+}
+
+func parseBackupName(name string) (string, time.Time, bool) {
+	if !strings.HasSuffix(name, backupSuffix) {
+		return "", time.Time{}, false
+	}
+	name = strings.TrimSuffix(name, backupSuffix)
+	if len(name) < len(backupTimestamp)+2 {
+		return "", time.Time{}, false
+	}
+	cut := len(name) - len(backupTimestamp)
+	ts, err := time.ParseInLocation(backupTimestamp, name[cut:], time.Local)
+	if err != nil || name[cut-1] != '.' {
+		return "", time.Time{}, false
+	}
+	return name[:cut-1], ts, true
+}
@@ -0,0 +1,73 @@
+package fsutil
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Locks follow the convention of Node's proper-lockfile: the lock for
+// <file> is the directory <file>.lock, whose mtime is refreshed while held
+// and which is considered stale once it has not been touched for staleAfter.
+const (
+	staleAfter  = 10 * time.Second
+	lockTimeout = 15 * time.Second
+)
+
+// Lock acquires the advisory lock for path and returns its release function.
+func Lock(path string) (func(), error) {
+	lockDir := path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	wait := 25 * time.Millisecond
+
+	for {
+		err := os.Mkdir(lockDir, 0755)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("创建文件锁失败: %v", err)
+		}
+		if info, serr := os.Stat(lockDir); serr == nil && time.Since(info.ModTime()) > staleAfter {
+			// Holder died without releasing; take the lock over.
+			os.Remove(lockDir)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("等待文件锁超时: %s", lockDir)
+		}
+		time.Sleep(wait)
+		if wait < 500*time.Millisecond {
+			wait *= 2
+		}
+	}
+
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(staleAfter / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case t := <-ticker.C:
+				os.Chtimes(lockDir, t, t)
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		os.Remove(lockDir)
+	}, nil
+}
+
+// WithLock runs fn while holding the advisory lock for path.
+func WithLock(path string, fn func() error) error {
+	unlock, err := Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return fn()
+}
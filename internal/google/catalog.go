@@ -0,0 +1,134 @@
+package google
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CatalogModel is one upstream model as listed by fetchAvailableModels.
+type CatalogModel struct {
+	// ID is the raw model ID, e.g. gemini-3-pro-high.
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+	// Display is the rotator model ID the quota counts towards, if any.
+	Display          string `json:"display,omitempty"`
+	APIProvider      string `json:"apiProvider,omitempty"`
+	ModelProvider    string `json:"modelProvider,omitempty"`
+	MaxTokens        int    `json:"maxTokens,omitempty"`
+	MaxOutputTokens  int    `json:"maxOutputTokens,omitempty"`
+	SupportsImages   bool   `json:"supportsImages"`
+	SupportsThinking bool   `json:"supportsThinking"`
+	ThinkingBudget   int    `json:"thinkingBudget,omitempty"`
+	Recommended      bool   `json:"recommended"`
+	// Available is false for models listed without quota information,
+	// which the account cannot use.
+	Available bool      `json:"available"`
+	Percent   int       `json:"percent"`
+	ResetTime time.Time `json:"resetTime"` // zero if unknown
+}
+
+// Catalog is the model catalog of one account.
+type Catalog struct {
+	FetchedAt time.Time      `json:"fetchedAt"`
+	Models    []CatalogModel `json:"models"` // sorted by ID
+	// Display is the quota per rotator model: the lowest of the available
+	// upstream models counted towards it.
+	Display map[string]ModelQuota `json:"display"`
+}
+
+// DisplayModel maps a raw model ID to the rotator model its quota counts
+// towards, or "" for models the rotator does not manage.
+func DisplayModel(rawID string) string {
+	id := strings.ToLower(rawID)
+	switch {
+	case strings.Contains(id, "gemini-3-pro"):
+		return "google-antigravity/gemini-3-pro-high"
+	case strings.Contains(id, "gemini-3-flash"):
+		return "google-antigravity/gemini-3-flash"
+	case strings.Contains(id, "gemini-3-image"):
+		return "google-antigravity/gemini-3-image"
+	case strings.Contains(id, "claude") && strings.Contains(id, "thinking"):
+		return "google-antigravity/claude-sonnet-4-5-thinking"
+	}
+	return ""
+}
+
+// FetchModelCatalog fetches every model the account can see with its limits
+// and quota.
+func (c *Client) FetchModelCatalog(accessToken, projectId string) (*Catalog, error) {
+	payload, _ := json.Marshal(map[string]string{"project": projectId})
+
+	req, _ := http.NewRequest("POST", c.endpoints().FetchAvailableModels, bytes.NewBuffer(payload))
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "antigravity/1.15.8 linux/x64")
+
+	httpClient, err := c.getHttpClient()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("配额 API 请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("配额 API 响应异常 %d: %s", resp.StatusCode, string(body))
+	}
+
+	var raw QuotaResponse
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("无法解析配额数据: %v", err)
+	}
+	cat := NewCatalog(raw)
+	log.Printf("[Google] 获取到 %d 个模型 (%d 个配额)", len(cat.Models), len(cat.Display))
+	return cat, nil
+}
+
+// NewCatalog builds a catalog from a decoded fetchAvailableModels response.
+func NewCatalog(raw QuotaResponse) *Catalog {
+	cat := &Catalog{FetchedAt: time.Now(), Models: []CatalogModel{}, Display: make(map[string]ModelQuota)}
+	for id, info := range raw.Models {
+		m := CatalogModel{
+			ID:               id,
+			DisplayName:      info.DisplayName,
+			Display:          DisplayModel(id),
+			APIProvider:      info.APIProvider,
+			ModelProvider:    info.ModelProvider,
+			MaxTokens:        info.MaxTokens,
+			MaxOutputTokens:  info.MaxOutputTokens,
+			SupportsImages:   info.SupportsImages,
+			SupportsThinking: info.SupportsThinking,
+			ThinkingBudget:   info.ThinkingBudget,
+			Recommended:      info.Recommended,
+			Available:        info.QuotaInfo != nil,
+		}
+		if info.QuotaInfo != nil {
+			m.Percent = int(info.QuotaInfo.RemainingFraction * 100)
+			m.ResetTime, _ = time.Parse(time.RFC3339, info.QuotaInfo.ResetTime)
+		}
+		cat.Models = append(cat.Models, m)
+	}
+	sort.Slice(cat.Models, func(i, j int) bool { return cat.Models[i].ID < cat.Models[j].ID })
+
+	// Several upstream models can count towards one display model; the
+	// one with the least quota left decides.
+	for _, m := range cat.Models {
+		if m.Display == "" || !m.Available {
+			continue
+		}
+		if cur, ok := cat.Display[m.Display]; !ok || m.Percent < cur.Percent {
+			cat.Display[m.Display] = ModelQuota{Percent: m.Percent, ResetTime: m.ResetTime, LimitedBy: m.ID}
+		}
+	}
+	return cat
+}
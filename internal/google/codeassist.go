@@ -0,0 +1,268 @@
+package google
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Tier is a Code Assist user tier as reported by loadCodeAssist.
+type Tier struct {
+	ID                     string `json:"id"`
+	Name                   string `json:"name,omitempty"`
+	Description            string `json:"description,omitempty"`
+	IsDefault              bool   `json:"isDefault,omitempty"`
+	UserDefinedProject     bool   `json:"userDefinedCloudaicompanionProject,omitempty"`
+	HasAcceptedTos         bool   `json:"hasAcceptedTos,omitempty"`
+	HasOnboardedPreviously bool   `json:"hasOnboardedPreviously,omitempty"`
+}
+
+// IneligibleTier is a tier the account cannot use, and why.
+type IneligibleTier struct {
+	TierID        string `json:"tierId"`
+	TierName      string `json:"tierName,omitempty"`
+	ReasonCode    string `json:"reasonCode"`
+	ReasonMessage string `json:"reasonMessage,omitempty"`
+}
+
+// Well-known tier IDs.
+const (
+	TierFree     = "free-tier"
+	TierLegacy   = "legacy-tier"
+	TierStandard = "standard-tier"
+)
+
+// CodeAssistInfo is the parsed loadCodeAssist response of an account.
+type CodeAssistInfo struct {
+	ProjectID       string           `json:"projectId,omitempty"`
+	CurrentTier     *Tier            `json:"currentTier,omitempty"`
+	PaidTier        *Tier            `json:"paidTier,omitempty"`
+	AllowedTiers    []Tier           `json:"allowedTiers"`
+	IneligibleTiers []IneligibleTier `json:"ineligibleTiers"`
+}
+
+// Onboarded reports whether the account has a tier and a project, i.e. can
+// be used for requests.
+func (i *CodeAssistInfo) Onboarded() bool {
+	return i.CurrentTier != nil && i.ProjectID != ""
+}
+
+// Tier returns the tier the account effectively has: the paid subscription
+// when there is one, else the current tier. Nil if not onboarded.
+func (i *CodeAssistInfo) Tier() *Tier {
+	if i.PaidTier != nil && i.PaidTier.ID != "" {
+		return i.PaidTier
+	}
+	return i.CurrentTier
+}
+
+// DefaultTier returns the allowed tier marked as default, used when
+// onboarding.
+func (i *CodeAssistInfo) DefaultTier() *Tier {
+	for k := range i.AllowedTiers {
+		if i.AllowedTiers[k].IsDefault {
+			return &i.AllowedTiers[k]
+		}
+	}
+	return nil
+}
+
+// TierRank orders tiers for rotation: higher is preferred. Unknown paid
+// tiers rank above the free ones.
+func TierRank(t *Tier) int {
+	if t == nil {
+		return 0
+	}
+	switch t.ID {
+	case TierFree:
+		return 1
+	case TierLegacy:
+		return 2
+	case TierStandard:
+		return 3
+	}
+	return 4 // subscription tiers such as Google AI Pro / Ultra
+}
+
+// LoadCodeAssist fetches the tier, onboarding state and project of an
+// account.
+func (c *Client) LoadCodeAssist(accessToken string) (*CodeAssistInfo, error) {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"metadata": map[string]string{"ideType": "ANTIGRAVITY"},
+	})
+
+	req, _ := http.NewRequest("POST", c.endpoints().LoadCodeAssist, bytes.NewBuffer(payload))
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	httpClient, err := c.getHttpClient()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("loadCodeAssist 请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("loadCodeAssist 响应异常 %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result LoadProjectResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("无法解析 loadCodeAssist 响应: %v", err)
+	}
+
+	info := &CodeAssistInfo{
+		CurrentTier:     result.CurrentTier,
+		PaidTier:        result.PaidTier,
+		AllowedTiers:    result.AllowedTiers,
+		IneligibleTiers: result.IneligibleTiers,
+	}
+	if info.AllowedTiers == nil {
+		info.AllowedTiers = []Tier{}
+	}
+	if info.IneligibleTiers == nil {
+		info.IneligibleTiers = []IneligibleTier{}
+	}
+	switch v := result.CloudaicompanionProject.(type) {
+	case nil:
+	case string:
+		info.ProjectID = v
+	case map[string]interface{}:
+		info.ProjectID, _ = v["id"].(string)
+	default:
+		return nil, fmt.Errorf("无法识别的 projectId 格式")
+	}
+	return info, nil
+}
+
+// onboardPollInterval is the default wait between onboardUser polls.
+const onboardPollInterval = 5 * time.Second
+
+// onboardResponse is the long-running operation returned by onboardUser.
+type onboardResponse struct {
+	Name     string `json:"name"`
+	Done     bool   `json:"done"`
+	Response struct {
+		CloudaicompanionProject interface{} `json:"cloudaicompanionProject"`
+	} `json:"response"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (c *Client) pollInterval() time.Duration {
+	if c.PollInterval > 0 {
+		return c.PollInterval
+	}
+	return onboardPollInterval
+}
+
+// OnboardUser provisions a Code Assist project for an account on the given
+// tier, repeating the call until the operation is done like the official
+// client does. It returns the new project ID.
+func (c *Client) OnboardUser(ctx context.Context, accessToken, tierID string) (string, error) {
+	body := map[string]interface{}{
+		"tierId":   tierID,
+		"metadata": map[string]string{"ideType": "ANTIGRAVITY"},
+	}
+	payload, _ := json.Marshal(body)
+
+	httpClient, err := c.getHttpClient()
+	if err != nil {
+		return "", err
+	}
+	for {
+		req, _ := http.NewRequestWithContext(ctx, "POST", c.endpoints().OnboardUser, bytes.NewReader(payload))
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("onboardUser 请求失败: %v", err)
+		}
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != 200 {
+			return "", fmt.Errorf("onboardUser 响应异常 %d: %s", resp.StatusCode, string(data))
+		}
+
+		var op onboardResponse
+		if err := json.Unmarshal(data, &op); err != nil {
+			return "", fmt.Errorf("无法解析 onboardUser 响应: %v", err)
+		}
+		if op.Error != nil {
+			return "", fmt.Errorf("开通失败: %s", op.Error.Message)
+		}
+		if op.Done {
+			switch v := op.Response.CloudaicompanionProject.(type) {
+			case string:
+				if v != "" {
+					return v, nil
+				}
+			case map[string]interface{}:
+				if id, _ := v["id"].(string); id != "" {
+					return id, nil
+				}
+			}
+			return "", fmt.Errorf("开通完成但未返回项目")
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("等待开通超时: %v", ctx.Err())
+		case <-time.After(c.pollInterval()):
+		}
+	}
+}
+
+// EnsureProject returns the account's Code Assist info, onboarding it onto
+// its default tier first when it has no project yet.
+func (c *Client) EnsureProject(ctx context.Context, accessToken string) (*CodeAssistInfo, error) {
+	info, err := c.LoadCodeAssist(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	if info.ProjectID != "" {
+		return info, nil
+	}
+
+	tier := info.CurrentTier
+	if tier == nil {
+		tier = info.DefaultTier()
+	}
+	if tier == nil {
+		if len(info.IneligibleTiers) > 0 {
+			r := info.IneligibleTiers[0]
+			return info, fmt.Errorf("账号无法开通 %s: %s", r.TierID, r.ReasonMessage)
+		}
+		tier = &Tier{ID: TierFree}
+	}
+	if tier.UserDefinedProject {
+		return info, fmt.Errorf("等级 %s 需要自行指定 GCP 项目，无法自动开通", tier.ID)
+	}
+
+	projectID, err := c.OnboardUser(ctx, accessToken, tier.ID)
+	if err != nil {
+		return info, err
+	}
+	// Re-read so the tier reflects the onboarded state; the response can
+	// lag behind the operation, so fill in what it already told us.
+	if fresh, err := c.LoadCodeAssist(accessToken); err == nil {
+		info = fresh
+	}
+	if info.ProjectID == "" {
+		info.ProjectID = projectID
+	}
+	if info.CurrentTier == nil {
+		info.CurrentTier = tier
+	}
+	return info, nil
+}
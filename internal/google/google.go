@@ -1,35 +1,65 @@
 package google
 
 import (
-	"bytes"
+	"antigravity-rotator-v2/internal/config"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
-	"strings"
 	"time"
 )
 
+// Endpoints are the Google URLs the client talks to. Tests point them at
+// local fakes.
+type Endpoints struct {
+	Auth                 string
+	Token                string
+	UserInfo             string
+	LoadCodeAssist       string
+	OnboardUser          string
+	FetchAvailableModels string
+}
+
+// DefaultEndpoints are used by clients without their own Endpoints.
+var DefaultEndpoints = Endpoints{
+	Auth:                 "https://accounts.google.com/o/oauth2/v2/auth",
+	Token:                "https://oauth2.googleapis.com/token",
+	UserInfo:             "https://www.googleapis.com/oauth2/v2/userinfo",
+	LoadCodeAssist:       "https://daily-cloudcode-pa.sandbox.googleapis.com/v1internal:loadCodeAssist",
+	OnboardUser:          "https://daily-cloudcode-pa.sandbox.googleapis.com/v1internal:onboardUser",
+	FetchAvailableModels: "https://daily-cloudcode-pa.sandbox.googleapis.com/v1internal:fetchAvailableModels",
+}
+
+// The OAuth client of Antigravity, used for refreshing and for logins.
 const (
-	quotaAPIURL    = "https://daily-cloudcode-pa.sandbox.googleapis.com/v1internal:fetchAvailableModels"
-	loadProjectURL = "https://daily-cloudcode-pa.sandbox.googleapis.com/v1internal:loadCodeAssist"
+	oauthClientID     = "1071006060591-tmhssin2h21lcre235vtolojh4g403ep.apps.googleusercontent.com"
+	oauthClientSecret = "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"
 )
 
 type Client struct {
-	Proxy string
+	Proxy config.ProxyConfig
+	// Endpoints overrides DefaultEndpoints when set.
+	Endpoints *Endpoints
+	// PollInterval overrides the wait between onboarding polls.
+	PollInterval time.Duration
+}
+
+func (c *Client) endpoints() Endpoints {
+	if c.Endpoints != nil {
+		return *c.Endpoints
+	}
+	return DefaultEndpoints
 }
 
-func (c *Client) getHttpClient() *http.Client {
-	proxyStr := c.Proxy
-	if proxyStr == "" {
-		proxyStr = "http://127.0.0.1:7890"
+func (c *Client) getHttpClient() (*http.Client, error) {
+	proxy, err := proxyFunc(c.Proxy)
+	if err != nil {
+		return nil, err
 	}
-	proxyURL, _ := url.Parse(proxyStr)
-	
+
 	transport := &http.Transport{
-		Proxy: http.ProxyURL(proxyURL),
+		Proxy: proxy,
 		// 优化连接稳定性
 		DisableKeepAlives: true,
 	}
@@ -37,7 +67,7 @@ func (c *Client) getHttpClient() *http.Client {
 	return &http.Client{
 		Transport: transport,
 		Timeout:   30 * time.Second,
-	}
+	}, nil
 }
 
 // QuotaResponse matches the actual API response format from daily-cloudcode-pa
@@ -46,7 +76,17 @@ type QuotaResponse struct {
 }
 
 type ModelInfo struct {
-	QuotaInfo *QuotaInfo `json:"quotaInfo"`
+	DisplayName      string     `json:"displayName"`
+	Model            string     `json:"model"`
+	APIProvider      string     `json:"apiProvider"`
+	ModelProvider    string     `json:"modelProvider"`
+	MaxTokens        int        `json:"maxTokens"`
+	MaxOutputTokens  int        `json:"maxOutputTokens"`
+	SupportsImages   bool       `json:"supportsImages"`
+	SupportsThinking bool       `json:"supportsThinking"`
+	ThinkingBudget   int        `json:"thinkingBudget"`
+	Recommended      bool       `json:"recommended"`
+	QuotaInfo        *QuotaInfo `json:"quotaInfo"`
 }
 
 type QuotaInfo struct {
@@ -55,20 +95,25 @@ type QuotaInfo struct {
 }
 
 type LoadProjectResponse struct {
-	CloudaicompanionProject interface{} `json:"cloudaicompanionProject"`
+	CloudaicompanionProject interface{}      `json:"cloudaicompanionProject"`
+	CurrentTier             *Tier            `json:"currentTier"`
+	PaidTier                *Tier            `json:"paidTier"`
+	AllowedTiers            []Tier           `json:"allowedTiers"`
+	IneligibleTiers         []IneligibleTier `json:"ineligibleTiers"`
 }
 
 func (c *Client) RefreshAccessToken(refreshToken string) (string, error) {
-	clientID := "1071006060591-tmhssin2h21lcre235vtolojh4g403ep.apps.googleusercontent.com"
-	clientSecret := "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"
-
 	data := url.Values{}
-	data.Set("client_id", clientID)
-	data.Set("client_secret", clientSecret)
+	data.Set("client_id", oauthClientID)
+	data.Set("client_secret", oauthClientSecret)
 	data.Set("refresh_token", refreshToken)
 	data.Set("grant_type", "refresh_token")
 
-	resp, err := c.getHttpClient().PostForm("https://oauth2.googleapis.com/token", data)
+	httpClient, err := c.getHttpClient()
+	if err != nil {
+		return "", err
+	}
+	resp, err := httpClient.PostForm(c.endpoints().Token, data)
 	if err != nil {
 		return "", fmt.Errorf("Google 认证请求失败: %v", err)
 	}
@@ -94,100 +139,75 @@ func (c *Client) RefreshAccessToken(refreshToken string) (string, error) {
 	return result.AccessToken, nil
 }
 
-// FetchProjectID dynamically fetches the project ID via loadCodeAssist
-func (c *Client) FetchProjectID(accessToken string) (string, error) {
-	payload, _ := json.Marshal(map[string]interface{}{
-		"metadata": map[string]string{"ideType": "ANTIGRAVITY"},
-	})
-
-	req, _ := http.NewRequest("POST", loadProjectURL, bytes.NewBuffer(payload))
+// FetchUserEmail returns the email address of the account an access token
+// belongs to.
+func (c *Client) FetchUserEmail(accessToken string) (string, error) {
+	req, _ := http.NewRequest("GET", c.endpoints().UserInfo, nil)
 	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.getHttpClient().Do(req)
+	httpClient, err := c.getHttpClient()
+	if err != nil {
+		return "", err
+	}
+	resp, err := httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("loadCodeAssist 请求失败: %v", err)
+		return "", fmt.Errorf("用户信息请求失败: %v", err)
 	}
 	defer resp.Body.Close()
 
 	body, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("loadCodeAssist 响应异常 %d: %s", resp.StatusCode, string(body))
+		return "", fmt.Errorf("用户信息响应异常 %d: %s", resp.StatusCode, string(body))
 	}
-
-	var result LoadProjectResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return "", fmt.Errorf("无法解析 loadCodeAssist 响应: %v", err)
+	var info struct {
+		Email string `json:"email"`
 	}
-
-	if result.CloudaicompanionProject == nil {
-		return "", fmt.Errorf("未获取到 cloudaicompanionProject")
+	if err := json.Unmarshal(body, &info); err != nil || info.Email == "" {
+		return "", fmt.Errorf("无法获取账号邮箱: %s", string(body))
 	}
+	return info.Email, nil
+}
 
-	switch v := result.CloudaicompanionProject.(type) {
-	case string:
-		return v, nil
-	case map[string]interface{}:
-		if id, ok := v["id"].(string); ok {
-			return id, nil
-		}
+// FetchProjectID dynamically fetches the project ID via loadCodeAssist
+func (c *Client) FetchProjectID(accessToken string) (string, error) {
+	info, err := c.LoadCodeAssist(accessToken)
+	if err != nil {
+		return "", err
 	}
+	if info.ProjectID == "" {
+		return "", fmt.Errorf("未获取到 cloudaicompanionProject")
+	}
+	return info.ProjectID, nil
+}
 
-	return "", fmt.Errorf("无法识别的 projectId 格式")
+// ModelQuota is the remaining quota of one display model and when it resets.
+type ModelQuota struct {
+	Percent   int       `json:"percent"`
+	ResetTime time.Time `json:"resetTime"` // zero if unknown
+	// LimitedBy is the upstream model with the lowest quota among those
+	// counted towards the display model.
+	LimitedBy string `json:"limitedBy,omitempty"`
 }
 
 // FetchAccountQuota fetches the model quota for an account using the correct API
 func (c *Client) FetchAccountQuota(accessToken, projectId string) (map[string]int, error) {
-	payload, _ := json.Marshal(map[string]string{"project": projectId})
-
-	req, _ := http.NewRequest("POST", quotaAPIURL, bytes.NewBuffer(payload))
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "antigravity/1.15.8 linux/x64")
-
-	resp, err := c.getHttpClient().Do(req)
+	details, err := c.FetchAccountQuotaDetails(accessToken, projectId)
 	if err != nil {
-		return nil, fmt.Errorf("配额 API 请求失败: %v", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("配额 API 响应异常 %d: %s", resp.StatusCode, string(body))
-	}
-
-	var raw QuotaResponse
-	if err := json.Unmarshal(body, &raw); err != nil {
-		return nil, fmt.Errorf("无法解析配额数据: %v", err)
+	quotas := make(map[string]int, len(details))
+	for model, q := range details {
+		quotas[model] = q.Percent
 	}
+	return quotas, nil
+}
 
-	quotas := make(map[string]int)
-	for name, info := range raw.Models {
-		if info.QuotaInfo == nil {
-			continue
-		}
-
-		var displayName string
-		nameLower := strings.ToLower(name)
-
-		if strings.Contains(nameLower, "gemini-3-pro") {
-			displayName = "google-antigravity/gemini-3-pro-high"
-		} else if strings.Contains(nameLower, "gemini-3-flash") {
-			displayName = "google-antigravity/gemini-3-flash"
-		} else if strings.Contains(nameLower, "gemini-3-image") {
-			displayName = "google-antigravity/gemini-3-image"
-		} else if strings.Contains(nameLower, "claude") && strings.Contains(nameLower, "thinking") {
-			displayName = "google-antigravity/claude-sonnet-4-5-thinking"
-		} else {
-			continue 
-		}
-
-		percentage := int(info.QuotaInfo.RemainingFraction * 100)
-		if current, exists := quotas[displayName]; !exists || percentage < current {
-			quotas[displayName] = percentage
-		}
+// FetchAccountQuotaDetails is FetchAccountQuota with the reset times and
+// the upstream model limiting each display model.
+func (c *Client) FetchAccountQuotaDetails(accessToken, projectId string) (map[string]ModelQuota, error) {
+	cat, err := c.FetchModelCatalog(accessToken, projectId)
+	if err != nil {
+		return nil, err
 	}
-
-	log.Printf("[Google] 获取到 %d 个模型配额", len(quotas))
-	return quotas, nil
+	return cat.Display, nil
 }
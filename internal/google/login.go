@@ -0,0 +1,202 @@
+package google
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// loginScopes are the scopes Antigravity itself requests.
+var loginScopes = []string{
+	"https://www.googleapis.com/auth/cloud-platform",
+	"https://www.googleapis.com/auth/userinfo.email",
+	"https://www.googleapis.com/auth/userinfo.profile",
+	"https://www.googleapis.com/auth/cclog",
+	"https://www.googleapis.com/auth/experimentsandconfigs",
+}
+
+const callbackPath = "/oauth-callback"
+
+// LoginResult is the account obtained by an interactive login.
+type LoginResult struct {
+	Email        string
+	RefreshToken string
+	AccessToken  string
+	Expires      time.Time
+	// ProjectID is empty when loadCodeAssist did not return one.
+	ProjectID string
+	// ProjectError explains an empty ProjectID.
+	ProjectError string
+}
+
+// LoginSession is an interactive OAuth login waiting for the browser to
+// come back to its loopback redirect listener.
+type LoginSession struct {
+	// URL is the consent page to open in the browser.
+	URL string
+
+	c        *Client
+	verifier string
+	state    string
+	redirect string
+	ln       net.Listener
+	srv      *http.Server
+	done     chan loginCallback
+}
+
+type loginCallback struct {
+	code string
+	err  error
+}
+
+// StartLogin starts an authorization-code login with PKCE, listening on a
+// random loopback port for the redirect. Call Wait, then Close.
+func (c *Client) StartLogin() (*LoginSession, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("无法启动本地回调监听: %v", err)
+	}
+	s := &LoginSession{
+		c:        c,
+		verifier: randomString(32),
+		state:    randomString(16),
+		redirect: fmt.Sprintf("http://%s%s", ln.Addr().String(), callbackPath),
+		ln:       ln,
+		done:     make(chan loginCallback, 1),
+	}
+
+	challenge := sha256.Sum256([]byte(s.verifier))
+	q := url.Values{}
+	q.Set("client_id", oauthClientID)
+	q.Set("redirect_uri", s.redirect)
+	q.Set("response_type", "code")
+	q.Set("scope", strings.Join(loginScopes, " "))
+	q.Set("access_type", "offline")
+	q.Set("prompt", "consent") // always issue a refresh token
+	q.Set("state", s.state)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+	s.URL = c.endpoints().Auth + "?" + q.Encode()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(callbackPath, s.handleCallback)
+	s.srv = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go s.srv.Serve(ln)
+	return s, nil
+}
+
+func (s *LoginSession) handleCallback(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var cb loginCallback
+	switch {
+	case q.Get("state") != s.state:
+		http.Error(w, "state mismatch", http.StatusBadRequest)
+		return // not our redirect; keep waiting
+	case q.Get("error") != "":
+		cb.err = fmt.Errorf("Google 登录被拒绝: %s", q.Get("error"))
+	case q.Get("code") == "":
+		cb.err = fmt.Errorf("回调缺少授权码")
+	default:
+		cb.code = q.Get("code")
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if cb.err != nil {
+		fmt.Fprintf(w, "<html><body><h3>登录失败</h3><p>%s</p></body></html>", html.EscapeString(cb.err.Error()))
+	} else {
+		fmt.Fprint(w, "<html><body><h3>登录成功</h3><p>可以关闭此页面并返回 Antigravity Rotator。</p></body></html>")
+	}
+	select {
+	case s.done <- cb:
+	default:
+	}
+}
+
+// Wait blocks until the browser is redirected back (or ctx ends), then
+// exchanges the code and discovers the email and project.
+func (s *LoginSession) Wait(ctx context.Context) (*LoginResult, error) {
+	var cb loginCallback
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("等待 Google 登录超时或已取消")
+	case cb = <-s.done:
+	}
+	if cb.err != nil {
+		return nil, cb.err
+	}
+
+	res, err := s.c.exchangeCode(cb.code, s.verifier, s.redirect)
+	if err != nil {
+		return nil, err
+	}
+	if res.Email, err = s.c.FetchUserEmail(res.AccessToken); err != nil {
+		return nil, err
+	}
+	if res.ProjectID, err = s.c.FetchProjectID(res.AccessToken); err != nil {
+		res.ProjectError = err.Error()
+	}
+	return res, nil
+}
+
+// Close stops the redirect listener.
+func (s *LoginSession) Close() {
+	s.srv.Close()
+}
+
+// exchangeCode redeems an authorization code for tokens.
+func (c *Client) exchangeCode(code, verifier, redirect string) (*LoginResult, error) {
+	data := url.Values{}
+	data.Set("client_id", oauthClientID)
+	data.Set("client_secret", oauthClientSecret)
+	data.Set("code", code)
+	data.Set("code_verifier", verifier)
+	data.Set("redirect_uri", redirect)
+	data.Set("grant_type", "authorization_code")
+
+	httpClient, err := c.getHttpClient()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.PostForm(c.endpoints().Token, data)
+	if err != nil {
+		return nil, fmt.Errorf("Google 认证请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("OAuth 状态异常 %d: %s", resp.StatusCode, string(body))
+	}
+	var result struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("无法解析 OAuth 响应: %v (原始响应: %s)", err, string(body))
+	}
+	if result.RefreshToken == "" {
+		return nil, fmt.Errorf("Google 未返回 refresh token")
+	}
+	return &LoginResult{
+		AccessToken:  result.AccessToken,
+		RefreshToken: result.RefreshToken,
+		Expires:      time.Now().Add(time.Duration(result.ExpiresIn) * time.Second),
+	}, nil
+}
+
+func randomString(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,128 @@
+package google_test
+
+import (
+	"antigravity-rotator-v2/internal/fake"
+	"antigravity-rotator-v2/internal/google"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newClient(t *testing.T) (*google.Client, *fake.Google) {
+	t.Helper()
+	g := fake.NewGoogle()
+	t.Cleanup(g.Close)
+	ep := g.Endpoints()
+	return &google.Client{Endpoints: &ep, PollInterval: time.Millisecond}, g
+}
+
+func TestLogin(t *testing.T) {
+	c, g := newClient(t)
+	g.AddAccount(fake.Account{Email: "a@example.com", Refresh: "ra", Project: "pa"})
+	g.LoginAs("a@example.com")
+
+	s, err := c.StartLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	// Stand in for the browser: follow the consent redirect to the loopback.
+	resp, err := http.Get(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	res, err := s.Wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Email != "a@example.com" || res.RefreshToken != "ra" || res.ProjectID != "pa" {
+		t.Errorf("login result = %+v", res)
+	}
+}
+
+func TestLoginDenied(t *testing.T) {
+	c, _ := newClient(t)
+	s, err := c.StartLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	// Redirect straight to the loopback with an error carrying markup.
+	consent, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb := url.Values{"state": {consent.Query().Get("state")}, "error": {"<script>x</script>"}}
+	resp, err := http.Get(consent.Query().Get("redirect_uri") + "?" + cb.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if strings.Contains(string(body), "<script>") {
+		t.Errorf("callback page echoes the error unescaped: %s", body)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := s.Wait(ctx); err == nil {
+		t.Errorf("denied login succeeded")
+	}
+}
+
+func TestEnsureProjectOnboards(t *testing.T) {
+	c, g := newClient(t)
+	g.AddAccount(fake.Account{Email: "new@example.com", Refresh: "rn", Tier: google.TierStandard})
+	g.OnboardPolls = 2
+
+	access, err := c.RefreshAccessToken("rn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := c.EnsureProject(context.Background(), access)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ProjectID == "" || info.ProjectID != g.Project("new@example.com") {
+		t.Errorf("project = %q, want %q", info.ProjectID, g.Project("new@example.com"))
+	}
+	if tier := info.Tier(); tier == nil || tier.ID != google.TierStandard {
+		t.Errorf("tier = %+v, want %s", tier, google.TierStandard)
+	}
+	if n := g.Calls(fake.EndpointOnboardUser); n != 3 {
+		t.Errorf("onboardUser called %d times, want 3", n)
+	}
+}
+
+func TestFetchModelCatalogErrors(t *testing.T) {
+	c, g := newClient(t)
+	g.AddAccount(fake.Account{Email: "a@example.com", Refresh: "ra", Project: "pa",
+		Quotas: map[string]fake.Quota{"gemini-3-flash": {Fraction: 0.5}}})
+	access, err := c.RefreshAccessToken("ra")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g.Fail(fake.EndpointModels, http.StatusTooManyRequests, "rate limited", 1)
+	if _, err := c.FetchModelCatalog(access, "pa"); err == nil {
+		t.Errorf("FetchModelCatalog succeeded on 429")
+	}
+	if _, err := c.FetchModelCatalog(access, "other"); err == nil {
+		t.Errorf("FetchModelCatalog succeeded for a foreign project")
+	}
+	cat, err := c.FetchModelCatalog(access, "pa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q := cat.Display["google-antigravity/gemini-3-flash"]; q.Percent != 50 {
+		t.Errorf("flash quota = %d, want 50", q.Percent)
+	}
+}
@@ -0,0 +1,84 @@
+package google
+
+import (
+	"antigravity-rotator-v2/internal/config"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// proxyFunc maps a ProxyConfig onto an http.Transport proxy selector.
+// A nil function means a direct connection.
+func proxyFunc(p config.ProxyConfig) (func(*http.Request) (*url.URL, error), error) {
+	switch p.Mode {
+	case "", config.ProxySystem:
+		return http.ProxyFromEnvironment, nil
+	case config.ProxyNone:
+		return nil, nil
+	case config.ProxyHTTP, config.ProxySOCKS5:
+		u, err := p.ProxyURL()
+		if err != nil {
+			return nil, err
+		}
+		return http.ProxyURL(u), nil
+	}
+	return nil, fmt.Errorf("未知的代理模式: %s", p.Mode)
+}
+
+// ProbeResult reports whether a Google endpoint is reachable through the
+// client's proxy and how long the round trip took.
+type ProbeResult struct {
+	Endpoint  string `json:"endpoint"`
+	URL       string `json:"url"`
+	Reachable bool   `json:"reachable"`
+	Status    int    `json:"status"`
+	LatencyMs int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ProbeEndpoints sends an unauthenticated request to every Google endpoint the
+// rotator talks to. Any HTTP response, even an error status, counts as reachable.
+func (c *Client) ProbeEndpoints() []ProbeResult {
+	ep := c.endpoints()
+	targets := []struct{ name, url string }{
+		{"oauth", ep.Token},
+		{"loadCodeAssist", ep.LoadCodeAssist},
+		{"fetchAvailableModels", ep.FetchAvailableModels},
+	}
+
+	results := make([]ProbeResult, len(targets))
+	httpClient, err := c.getHttpClient()
+	if err != nil {
+		for i, t := range targets {
+			results[i] = ProbeResult{Endpoint: t.name, URL: t.url, Error: err.Error()}
+		}
+		return results
+	}
+	httpClient.Timeout = 10 * time.Second
+
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, name, target string) {
+			defer wg.Done()
+			res := ProbeResult{Endpoint: name, URL: target}
+			req, _ := http.NewRequestWithContext(context.Background(), "GET", target, nil)
+			start := time.Now()
+			resp, err := httpClient.Do(req)
+			res.LatencyMs = time.Since(start).Milliseconds()
+			if err != nil {
+				res.Error = err.Error()
+			} else {
+				resp.Body.Close()
+				res.Reachable = true
+				res.Status = resp.StatusCode
+			}
+			results[i] = res
+		}(i, t.name, t.url)
+	}
+	wg.Wait()
+	return results
+}
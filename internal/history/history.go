@@ -0,0 +1,110 @@
+// Package history keeps an append-only log of account and model switches,
+// so exports and usage reports can tell what was active when.
+package history
+
+import (
+	"antigravity-rotator-v2/internal/fsutil"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const fileName = "antigravity-rotator-history.jsonl"
+
+// maxSize is the file size after which Append drops the older half.
+const maxSize = 4 << 20
+
+// Entry is one switch. Account and Model are set for what changed.
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Account string    `json:"account,omitempty"`
+	Model   string    `json:"model,omitempty"`
+	Quota   int       `json:"quota,omitempty"`
+	// Reason is "auto", "manual", "trigger: ..." or "restore".
+	Reason string `json:"reason,omitempty"`
+}
+
+// Path returns the history file of an OpenClaw home.
+func Path(home string) string {
+	return filepath.Join(home, fileName)
+}
+
+// Append adds an entry to the history file.
+func Append(path string, entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return fsutil.WithLock(path, func() error {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(append(line, '\n'))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+		if fi, err := os.Stat(path); err == nil && fi.Size() > maxSize {
+			return trim(path)
+		}
+		return nil
+	})
+}
+
+// trim keeps the newer half of the history. Callers hold the lock.
+func trim(path string) error {
+	entries, err := read(path)
+	if err != nil {
+		return err
+	}
+	return write(path, entries[len(entries)/2:])
+}
+
+// Read returns all entries, oldest first. A missing file is an empty
+// history.
+func Read(path string) ([]Entry, error) {
+	return read(path)
+}
+
+func read(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return []Entry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries := []Entry{}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for sc.Scan() {
+		var e Entry
+		if json.Unmarshal(sc.Bytes(), &e) == nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries, sc.Err()
+}
+
+// Replace overwrites the history, e.g. when restoring a bundle.
+func Replace(path string, entries []Entry) error {
+	return fsutil.WithLock(path, func() error {
+		return write(path, entries)
+	})
+}
+
+func write(path string, entries []Entry) error {
+	var buf bytes.Buffer
+	for _, e := range entries {
+		line, _ := json.Marshal(e)
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return fsutil.WriteFileAtomic(path, buf.Bytes(), 0600)
+}
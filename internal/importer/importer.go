@@ -0,0 +1,310 @@
+// Package importer reads account credentials from the file formats people
+// have lying around: the rotator's own JSON list, CSV, "email:token" text,
+// OpenClaw auth-profiles.json, Antigravity account files and zip archives of
+// any of these. Gemini CLI oauth_creds.json files are recognised and
+// rejected: their tokens belong to another OAuth client.
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Entry is one credential found in an import source.
+type Entry struct {
+	Source       string `json:"source"` // file and row it came from
+	Email        string `json:"email"`
+	RefreshToken string `json:"-"`
+	ProjectID    string `json:"projectId,omitempty"`
+}
+
+// Problem is a row that could not be read as a credential.
+type Problem struct {
+	Source string `json:"source"`
+	Reason string `json:"reason"`
+}
+
+// maxZipEntry bounds how much of a single archive member is read.
+const maxZipEntry = 16 << 20
+
+// Parse detects the format of data (named name) and returns the credentials
+// it contains, and the rows it had to skip. An error means the whole file
+// was unreadable.
+func Parse(name string, data []byte) ([]Entry, []Problem, error) {
+	ext := strings.ToLower(path.Ext(name))
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case ext == ".zip" || bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return parseZip(name, data)
+	case ext == ".csv":
+		return parseCSV(name, data)
+	case ext == ".json" || bytes.HasPrefix(trimmed, []byte("{")) || bytes.HasPrefix(trimmed, []byte("[")):
+		return parseJSON(name, trimmed)
+	case looksLikeCSV(trimmed):
+		return parseCSV(name, data)
+	default:
+		return parseText(name, data)
+	}
+}
+
+func parseZip(name string, data []byte) ([]Entry, []Problem, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("无法读取压缩包 %s: %v", name, err)
+	}
+	var entries []Entry
+	var problems []Problem
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || strings.HasPrefix(path.Base(f.Name), ".") {
+			continue
+		}
+		source := name + "/" + f.Name
+		rc, err := f.Open()
+		if err != nil {
+			problems = append(problems, Problem{source, err.Error()})
+			continue
+		}
+		inner, err := io.ReadAll(io.LimitReader(rc, maxZipEntry))
+		rc.Close()
+		if err != nil {
+			problems = append(problems, Problem{source, err.Error()})
+			continue
+		}
+		e, p, err := Parse(source, inner)
+		if err != nil {
+			problems = append(problems, Problem{source, err.Error()})
+			continue
+		}
+		entries = append(entries, e...)
+		problems = append(problems, p...)
+	}
+	return entries, problems, nil
+}
+
+// looksLikeCSV reports whether the first line of pasted or unnamed data is
+// comma separated rather than an "email:token" line.
+func looksLikeCSV(data []byte) bool {
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	comma, colon := bytes.IndexByte(line, ','), bytes.IndexByte(line, ':')
+	return comma >= 0 && (colon < 0 || comma < colon)
+}
+
+// parseCSV reads email and token columns, found by header name or, without
+// a header, taken as the first two columns.
+func parseCSV(name string, data []byte) ([]Entry, []Problem, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("无法解析 CSV %s: %v", name, err)
+	}
+
+	emailCol, tokenCol, projectCol, start := 0, 1, -1, 0
+	if len(rows) > 0 {
+		header := map[string]int{}
+		for i, h := range rows[0] {
+			header[strings.ToLower(strings.TrimSpace(h))] = i
+		}
+		if i, ok := header["email"]; ok {
+			emailCol, start = i, 1
+			tokenCol = firstColumn(header, "refresh_token", "refreshtoken", "refresh", "token")
+			projectCol = firstColumn(header, "project_id", "projectid", "project")
+		}
+	}
+
+	var entries []Entry
+	var problems []Problem
+	for i := start; i < len(rows); i++ {
+		row := rows[i]
+		source := fmt.Sprintf("%s:%d", name, i+1)
+		if len(row) == 1 && strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+		if tokenCol < 0 || emailCol >= len(row) || tokenCol >= len(row) {
+			problems = append(problems, Problem{source, "缺少邮箱或 refresh token 列"})
+			continue
+		}
+		e := Entry{Source: source, Email: strings.TrimSpace(row[emailCol]), RefreshToken: strings.TrimSpace(row[tokenCol])}
+		if projectCol >= 0 && projectCol < len(row) {
+			e.ProjectID = strings.TrimSpace(row[projectCol])
+		}
+		entries = append(entries, e)
+	}
+	return entries, problems, nil
+}
+
+func firstColumn(header map[string]int, names ...string) int {
+	for _, n := range names {
+		if i, ok := header[n]; ok {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseText reads "email:token" lines; blank lines and # comments are
+// ignored.
+func parseText(name string, data []byte) ([]Entry, []Problem, error) {
+	var entries []Entry
+	var problems []Problem
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		source := fmt.Sprintf("%s:%d", name, i+1)
+		email, token, ok := strings.Cut(line, ":")
+		if !ok {
+			problems = append(problems, Problem{source, "格式应为 email:token"})
+			continue
+		}
+		entries = append(entries, Entry{Source: source, Email: strings.TrimSpace(email), RefreshToken: strings.TrimSpace(token)})
+	}
+	return entries, problems, nil
+}
+
+func parseJSON(name string, data []byte) ([]Entry, []Problem, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("无法解析 JSON %s: %v", name, err)
+	}
+
+	switch v := doc.(type) {
+	case []interface{}:
+		// The rotator's own format: [{"email", "refresh_token"}, ...]
+		var entries []Entry
+		var problems []Problem
+		for i, item := range v {
+			source := fmt.Sprintf("%s[%d]", name, i)
+			obj, ok := item.(map[string]interface{})
+			if !ok {
+				problems = append(problems, Problem{source, "不是对象"})
+				continue
+			}
+			if geminiCLICreds(obj) {
+				problems = append(problems, Problem{source, errGeminiCLI})
+				continue
+			}
+			e := credentialObject(obj)
+			e.Source = source
+			entries = append(entries, e)
+		}
+		return entries, problems, nil
+
+	case map[string]interface{}:
+		if profiles, ok := v["profiles"].(map[string]interface{}); ok {
+			return authProfiles(name, profiles), nil, nil
+		}
+		if accounts, ok := v["accounts"].([]interface{}); ok {
+			data, _ := json.Marshal(accounts)
+			return parseJSON(name, data)
+		}
+		if geminiCLICreds(v) {
+			return nil, []Problem{{name, errGeminiCLI}}, nil
+		}
+		e := credentialObject(v)
+		e.Source = name
+		return []Entry{e}, nil, nil
+	}
+	return nil, nil, fmt.Errorf("无法识别的 JSON 格式: %s", name)
+}
+
+// authProfiles reads the google-antigravity profiles of an OpenClaw
+// auth-profiles.json.
+func authProfiles(name string, profiles map[string]interface{}) []Entry {
+	keys := make([]string, 0, len(profiles))
+	for key := range profiles {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var entries []Entry
+	for _, key := range keys {
+		profile, ok := profiles[key].(map[string]interface{})
+		if !ok || !strings.HasPrefix(key, "google-antigravity:") {
+			continue
+		}
+		e := Entry{Source: name + "#" + key, Email: strings.TrimPrefix(key, "google-antigravity:")}
+		e.RefreshToken, _ = profile["refresh"].(string)
+		e.ProjectID, _ = profile["projectId"].(string)
+		if email, ok := profile["email"].(string); ok && email != "" {
+			e.Email = email
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// errGeminiCLI is the reason given for Gemini CLI credential files.
+const errGeminiCLI = "Gemini CLI 的 oauth_creds.json 由另一个 OAuth 客户端签发，无法用于 Antigravity，请改用 Google 登录"
+
+// geminiCLICreds reports whether obj is a Gemini CLI oauth_creds.json
+// ({access_token, refresh_token, id_token, expiry_date, ...}). Its refresh
+// token was issued to the Gemini CLI OAuth client and cannot be refreshed
+// with the Antigravity one, and OpenClaw's google-antigravity provider only
+// refreshes with the latter. Such files are therefore rejected on purpose
+// rather than imported; the account has to sign in with LoginAccount.
+func geminiCLICreds(obj map[string]interface{}) bool {
+	if str(obj, "refresh_token") == "" || str(obj, "email") != "" {
+		return false
+	}
+	_, expiry := obj["expiry_date"]
+	return expiry || str(obj, "id_token") != ""
+}
+
+// credentialObject reads one credential object: a rotator list item or an
+// Antigravity account file ({email, token: {refresh_token, project_id}}).
+func credentialObject(obj map[string]interface{}) Entry {
+	var e Entry
+	e.Email = str(obj, "email")
+	e.RefreshToken = str(obj, "refresh_token", "refreshToken", "refresh")
+	e.ProjectID = str(obj, "project_id", "projectId")
+	if token, ok := obj["token"].(map[string]interface{}); ok {
+		if e.RefreshToken == "" {
+			e.RefreshToken = str(token, "refresh_token", "refreshToken")
+		}
+		if e.ProjectID == "" {
+			e.ProjectID = str(token, "project_id", "projectId")
+		}
+	}
+	if e.Email == "" {
+		e.Email = idTokenEmail(str(obj, "id_token"))
+	}
+	return e
+}
+
+func str(obj map[string]interface{}, keys ...string) string {
+	for _, k := range keys {
+		if s, ok := obj[k].(string); ok && s != "" {
+			return strings.TrimSpace(s)
+		}
+	}
+	return ""
+}
+
+// idTokenEmail reads the email claim of an OpenID id_token without
+// verifying it; the refresh token is validated separately.
+func idTokenEmail(idToken string) string {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return ""
+	}
+	var claims struct {
+		Email string `json:"email"`
+	}
+	json.Unmarshal(payload, &claims)
+	return claims.Email
+}
@@ -0,0 +1,45 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRejectsGeminiCLICreds(t *testing.T) {
+	geminiCLI := `{"access_token":"ya29.x","refresh_token":"1//g","scope":"https://www.googleapis.com/auth/cloud-platform","token_type":"Bearer","id_token":"a.b.c","expiry_date":1767600000000}`
+
+	entries, problems, err := Parse("oauth_creds.json", []byte(geminiCLI))
+	if err != nil || len(entries) != 0 || len(problems) != 1 || !strings.Contains(problems[0].Reason, "Gemini CLI") {
+		t.Fatalf("entries %+v, problems %+v, %v", entries, problems, err)
+	}
+
+	list := `[{"email":"a@example.com","refresh_token":"ra"},` + geminiCLI + `,{"email":"b@example.com","token":{"refresh_token":"rb","project_id":"pb"}}]`
+	entries, problems, err = Parse("accounts.json", []byte(list))
+	if err != nil || len(entries) != 2 || len(problems) != 1 || problems[0].Source != "accounts.json[1]" {
+		t.Fatalf("entries %+v, problems %+v, %v", entries, problems, err)
+	}
+	if entries[1].Email != "b@example.com" || entries[1].RefreshToken != "rb" || entries[1].ProjectID != "pb" {
+		t.Errorf("Antigravity account file = %+v", entries[1])
+	}
+}
+
+func TestParseDetectsPastedCSV(t *testing.T) {
+	for name, data := range map[string]string{
+		"header":    "email,refresh_token,project_id\na@example.com,1//ra,pa\nb@example.com,1//rb,\n",
+		"no header": "a@example.com, 1//ra\r\nb@example.com, 1//rb\r\n",
+	} {
+		entries, problems, err := Parse("pasted", []byte(data))
+		if err != nil || len(problems) != 0 || len(entries) != 2 {
+			t.Errorf("%s: entries %+v, problems %+v, %v", name, entries, problems, err)
+			continue
+		}
+		if entries[0].Email != "a@example.com" || entries[0].RefreshToken != "1//ra" || entries[1].Email != "b@example.com" {
+			t.Errorf("%s: entries = %+v", name, entries)
+		}
+	}
+
+	entries, problems, err := Parse("pasted", []byte("a@example.com:1//ra,x\n"))
+	if err != nil || len(problems) != 0 || len(entries) != 1 || entries[0].RefreshToken != "1//ra,x" {
+		t.Errorf("email:token line = %+v, %+v, %v", entries, problems, err)
+	}
+}
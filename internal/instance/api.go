@@ -0,0 +1,257 @@
+package instance
+
+import (
+	"antigravity-rotator-v2/internal/engine"
+	"antigravity-rotator-v2/internal/google"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// API is what the leader exposes to other instances. *engine.RotatorEngine
+// provides it through EngineAPI.
+type API interface {
+	Status(refresh bool) map[string]int
+	Rotate() (engine.Decision, bool, error)
+	PlanRotation() (engine.Decision, bool)
+	SwitchModel(modelID string) error
+	SwitchAccount(email string) error
+	ReloadConfig() error
+	TriggerRotation(reason string) error
+	PollSchedule() []engine.AccountPoll
+	ModelCatalogs() map[string]*google.Catalog
+	AccountTiers() []engine.AccountTier
+}
+
+const tokenHeader = "X-Rotator-Token"
+
+type rotateResult struct {
+	Message  string           `json:"message"`
+	Decision *engine.Decision `json:"decision,omitempty"`
+	Error    string           `json:"error,omitempty"`
+}
+
+func newHandler(token string, api API) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]bool{"ok": true})
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, api.Status(r.URL.Query().Get("refresh") != "0"))
+	})
+	mux.HandleFunc("/rotate", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("dryRun") == "1" {
+			d, ok := api.PlanRotation()
+			if !ok {
+				writeJSON(w, rotateResult{Message: "No healthy models found above threshold"})
+				return
+			}
+			writeJSON(w, rotateResult{Message: "dry run", Decision: &d})
+			return
+		}
+		d, ok, err := api.Rotate()
+		res := rotateResult{Message: engine.CycleMessage(d, ok, err)}
+		if ok {
+			res.Decision = &d
+		}
+		if err != nil {
+			res.Error = err.Error()
+		}
+		writeJSON(w, res)
+	})
+	mux.HandleFunc("/switch-model", func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Model string }
+		json.NewDecoder(r.Body).Decode(&req)
+		writeResult(w, api.SwitchModel(req.Model))
+	})
+	mux.HandleFunc("/switch-account", func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Email string }
+		json.NewDecoder(r.Body).Decode(&req)
+		writeResult(w, api.SwitchAccount(req.Email))
+	})
+	mux.HandleFunc("/poll-schedule", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, api.PollSchedule())
+	})
+	mux.HandleFunc("/catalogs", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, api.ModelCatalogs())
+	})
+	mux.HandleFunc("/tiers", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, api.AccountTiers())
+	})
+	mux.HandleFunc("/trigger", func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Reason string }
+		json.NewDecoder(r.Body).Decode(&req)
+		writeResult(w, api.TriggerRotation(req.Reason))
+	})
+	mux.HandleFunc("/reload-config", func(w http.ResponseWriter, r *http.Request) {
+		writeResult(w, api.ReloadConfig())
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(tokenHeader) != token {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeResult(w http.ResponseWriter, err error) {
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		writeJSON(w, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, map[string]bool{"ok": true})
+}
+
+// Client talks to the leader's API.
+type Client struct {
+	Info
+}
+
+func (c *Client) call(path string, body, out interface{}, timeout time.Duration) error {
+	var payload io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		payload = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest("POST", "http://"+c.Addr+path, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(tokenHeader, c.Token)
+	resp, err := (&http.Client{Timeout: timeout}).Do(req)
+	if err != nil {
+		return fmt.Errorf("无法连接主实例 (pid %d): %v", c.PID, err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		var e struct{ Error string }
+		if json.Unmarshal(data, &e) == nil && e.Error != "" {
+			return fmt.Errorf("%s", e.Error)
+		}
+		return fmt.Errorf("主实例响应异常 %d: %s", resp.StatusCode, string(data))
+	}
+	if out != nil {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+// Ping checks that the leader is alive.
+func (c *Client) Ping() error {
+	return c.call("/ping", nil, nil, 2*time.Second)
+}
+
+// Status returns the leader's quota status, refreshed first if asked to.
+func (c *Client) Status(refresh bool) (map[string]int, error) {
+	path := "/status"
+	if !refresh {
+		path += "?refresh=0"
+	}
+	var status map[string]int
+	err := c.call(path, nil, &status, 2*time.Minute)
+	return status, err
+}
+
+// RunCycle runs a rotation cycle on the leader and returns its message.
+func (c *Client) RunCycle() (string, error) {
+	var res rotateResult
+	err := c.call("/rotate", nil, &res, 5*time.Minute)
+	return res.Message, err
+}
+
+// Rotate runs a rotation cycle on the leader and returns the decision it
+// applied, like engine.RotatorEngine.Rotate.
+func (c *Client) Rotate() (engine.Decision, bool, error) {
+	var res rotateResult
+	if err := c.call("/rotate", nil, &res, 5*time.Minute); err != nil {
+		return engine.Decision{}, false, err
+	}
+	if res.Decision == nil {
+		return engine.Decision{}, false, nil
+	}
+	if res.Error != "" {
+		return *res.Decision, true, errors.New(res.Error)
+	}
+	return *res.Decision, true, nil
+}
+
+// PlanRotation asks the leader what a cycle would switch to.
+func (c *Client) PlanRotation() (engine.Decision, bool, error) {
+	var res rotateResult
+	if err := c.call("/rotate?dryRun=1", nil, &res, 5*time.Minute); err != nil {
+		return engine.Decision{}, false, err
+	}
+	if res.Decision == nil {
+		return engine.Decision{}, false, nil
+	}
+	return *res.Decision, true, nil
+}
+
+// SwitchModel switches the primary model through the leader.
+func (c *Client) SwitchModel(modelID string) error {
+	return c.call("/switch-model", map[string]string{"model": modelID}, nil, 2*time.Minute)
+}
+
+// SwitchAccount switches the active account through the leader.
+func (c *Client) SwitchAccount(email string) error {
+	return c.call("/switch-account", map[string]string{"email": email}, nil, 2*time.Minute)
+}
+
+// PollSchedule returns the leader's per-account polling schedule.
+func (c *Client) PollSchedule() ([]engine.AccountPoll, error) {
+	var polls []engine.AccountPoll
+	err := c.call("/poll-schedule", nil, &polls, 10*time.Second)
+	return polls, err
+}
+
+// ModelCatalogs returns the leader's last model catalog per account.
+func (c *Client) ModelCatalogs() (map[string]*google.Catalog, error) {
+	var cats map[string]*google.Catalog
+	err := c.call("/catalogs", nil, &cats, 10*time.Second)
+	return cats, err
+}
+
+// AccountTiers returns the leader's Code Assist tier per account.
+func (c *Client) AccountTiers() ([]engine.AccountTier, error) {
+	var tiers []engine.AccountTier
+	err := c.call("/tiers", nil, &tiers, 10*time.Second)
+	return tiers, err
+}
+
+// TriggerRotation asks the leader for a debounced rotation.
+func (c *Client) TriggerRotation(reason string) error {
+	return c.call("/trigger", map[string]string{"reason": reason}, nil, 10*time.Second)
+}
+
+// ReloadConfig makes the leader re-read the config file after a client
+// saved it.
+func (c *Client) ReloadConfig() error {
+	return c.call("/reload-config", nil, nil, 30*time.Second)
+}
+
+// EngineAPI serves the leader API from a local engine.
+type EngineAPI struct {
+	*engine.RotatorEngine
+}
+
+// Status optionally refreshes quotas and returns them, like the GUI does.
+func (a EngineAPI) Status(refresh bool) map[string]int {
+	if refresh {
+		a.RefreshStatus()
+	}
+	return a.StatusSnapshot()
+}
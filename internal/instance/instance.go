@@ -0,0 +1,127 @@
+// Package instance makes sure only one rotator engine (GUI or daemon) runs
+// the auto-rotation loop per OpenClaw home. The first process becomes the
+// leader and serves a small local HTTP API; later GUI, daemon and CLI
+// processes find it through the leader file and act as its clients.
+package instance
+
+import (
+	"antigravity-rotator-v2/internal/fsutil"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const leaderFileName = "antigravity-rotator.leader"
+
+// Info is the content of the leader file.
+type Info struct {
+	PID       int       `json:"pid"`
+	Addr      string    `json:"addr"`
+	Token     string    `json:"token"`
+	Role      string    `json:"role"` // "gui" or "daemon"
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// Leader is held by the process that owns the auto loop.
+type Leader struct {
+	Info
+	path     string
+	server   *http.Server
+	listener net.Listener
+}
+
+// LeaderPath returns the leader file of an OpenClaw home.
+func LeaderPath(home string) string {
+	return filepath.Join(home, leaderFileName)
+}
+
+// Acquire tries to become the leader for home. If another live leader
+// exists, it returns a Client for it instead. Exactly one of the results is
+// non-nil on success. The API is not served until Leader.Serve is called.
+func Acquire(home, role string) (*Leader, *Client, error) {
+	path := LeaderPath(home)
+	var leader *Leader
+	var client *Client
+
+	err := fsutil.WithLock(path, func() error {
+		if c, err := Find(home); err == nil {
+			client = c
+			return nil
+		}
+
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return err
+		}
+		token := make([]byte, 16)
+		rand.Read(token)
+		info := Info{
+			PID:       os.Getpid(),
+			Addr:      ln.Addr().String(),
+			Token:     hex.EncodeToString(token),
+			Role:      role,
+			StartedAt: time.Now(),
+		}
+		data, _ := json.MarshalIndent(info, "", "  ")
+		if err := fsutil.WriteFileAtomic(path, data, 0600); err != nil {
+			ln.Close()
+			return err
+		}
+		leader = &Leader{Info: info, path: path, listener: ln}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("实例协调失败: %v", err)
+	}
+	return leader, client, nil
+}
+
+// Find returns a client for the running leader of home, or an error if
+// there is none (no leader file, or the recorded leader does not answer).
+func Find(home string) (*Client, error) {
+	data, err := os.ReadFile(LeaderPath(home))
+	if err != nil {
+		return nil, err
+	}
+	var info Info
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+	c := &Client{Info: info}
+	if err := c.Ping(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Serve starts the leader API backed by api.
+func (l *Leader) Serve(api API) {
+	l.server = &http.Server{Handler: newHandler(l.Token, api)}
+	go l.server.Serve(l.listener)
+}
+
+// Release stops the API and removes the leader file if it is still ours.
+func (l *Leader) Release() {
+	if l.server != nil {
+		l.server.Close()
+	} else {
+		l.listener.Close()
+	}
+	fsutil.WithLock(l.path, func() error {
+		data, err := os.ReadFile(l.path)
+		if err != nil {
+			return nil
+		}
+		var info Info
+		if json.Unmarshal(data, &info) == nil && info.Token == l.Token {
+			os.Remove(l.path)
+		}
+		return nil
+	})
+}
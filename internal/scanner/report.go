@@ -0,0 +1,70 @@
+package scanner
+
+import "sort"
+
+// Cell states of the consistency matrix.
+const (
+	CellPrimary = "primary" // the primary auth file itself
+	CellOK      = "ok"
+	CellMissing = "missing"
+	CellDiffers = "differs"
+	CellExtra   = "extra" // only in this workspace and kept by a sync
+	CellNewer   = "newer" // workspace token is newer and kept by a sync
+)
+
+// ConsistencyReport is an accounts × workspaces matrix of credential state.
+// Cells[account][workspace path] holds one of the Cell* states.
+type ConsistencyReport struct {
+	Accounts   []string                     `json:"accounts"`
+	Workspaces []string                     `json:"workspaces"`
+	Cells      map[string]map[string]string `json:"cells"`
+	InSync     bool                         `json:"inSync"`
+}
+
+// BuildConsistencyReport builds the matrix from workspaces that have been
+// through CheckDrift.
+func BuildConsistencyReport(workspaces []WorkspaceInfo) ConsistencyReport {
+	report := ConsistencyReport{Cells: make(map[string]map[string]string), InSync: true}
+	set := func(account, path, state string) {
+		if report.Cells[account] == nil {
+			report.Cells[account] = make(map[string]string)
+			report.Accounts = append(report.Accounts, account)
+		}
+		report.Cells[account][path] = state
+	}
+
+	for _, wp := range workspaces {
+		if !wp.HasAuthProfiles {
+			continue
+		}
+		report.Workspaces = append(report.Workspaces, wp.Path)
+
+		state := CellOK
+		if wp.Primary {
+			state = CellPrimary
+		}
+		for _, email := range wp.Accounts {
+			set(email, wp.Path, state)
+		}
+		if wp.Drift == nil {
+			continue
+		}
+		if !wp.Drift.InSync() {
+			report.InSync = false
+		}
+		for _, email := range wp.Drift.MissingAccounts {
+			set(email, wp.Path, CellMissing)
+		}
+		for _, email := range wp.Drift.DifferentTokens {
+			set(email, wp.Path, CellDiffers)
+		}
+		for _, email := range wp.Drift.NewerTokens {
+			set(email, wp.Path, CellNewer)
+		}
+		for _, email := range wp.Drift.ExtraAccounts {
+			set(email, wp.Path, CellExtra)
+		}
+	}
+	sort.Strings(report.Accounts)
+	return report
+}
@@ -1,54 +1,280 @@
 package scanner
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 )
 
+const antigravityPrefix = "google-antigravity:"
+
+// FileInfo describes one of the files OpenClaw keeps in a workspace.
+type FileInfo struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	Mode    string    `json:"mode"`
+}
+
+// Drift describes how a workspace's google-antigravity profiles differ from
+// the installation's primary auth file.
+type Drift struct {
+	MissingAccounts []string `json:"missingAccounts"` // in primary, not here
+	ExtraAccounts   []string `json:"extraAccounts"`   // here, not in primary; kept by a sync
+	DifferentTokens []string `json:"differentTokens"` // refresh token differs, primary newer
+	// NewerTokens have a different refresh token that expires later than
+	// the primary one. A sync keeps them, so they are not drift.
+	NewerTokens []string `json:"newerTokens,omitempty"`
+}
+
+// InSync reports whether a sync would leave the workspace unchanged. Extra
+// accounts and newer tokens are reported but kept by a sync, so they do not
+// count.
+func (d *Drift) InSync() bool {
+	return len(d.MissingAccounts) == 0 && len(d.DifferentTokens) == 0
+}
+
 type WorkspaceInfo struct {
-	Path            string `json:"path"`
-	HasConfig       bool   `json:"hasConfig"`
-	HasAuthProfiles bool   `json:"hasAuthProfiles"`
+	Path            string     `json:"path"`
+	AgentID         string     `json:"agentId"` // empty for the home itself
+	HasConfig       bool       `json:"hasConfig"`
+	HasAuthProfiles bool       `json:"hasAuthProfiles"`
+	ProfileCount    int        `json:"profileCount"`
+	Accounts        []string   `json:"accounts"` // google-antigravity emails
+	Files           []FileInfo `json:"files"`
+	Primary         bool       `json:"primary"`
+	Drift           *Drift     `json:"drift,omitempty"`
+	ParseErrors     []string   `json:"parseErrors,omitempty"`
+
+	tokens map[string]Token
 }
 
-func ScanWorkspaces() ([]WorkspaceInfo, error) {
-	home, _ := os.UserHomeDir()
-	basePath := filepath.Join(home, ".openclaw")
-	
-	var workspaces []WorkspaceInfo
+// Token is the refresh token of a google-antigravity profile and the expiry
+// of its access token.
+type Token struct {
+	Refresh string
+	Expires float64
+}
 
-	// Check main workspace
-	mainWp := WorkspaceInfo{Path: basePath}
-	if _, err := os.Stat(filepath.Join(basePath, "openclaw.json")); err == nil {
-		mainWp.HasConfig = true
+// ProfileExpiry returns the access-token expiry OpenClaw records on a
+// profile, used to decide which copy of a profile is newer.
+func ProfileExpiry(profile interface{}) float64 {
+	if m, ok := profile.(map[string]interface{}); ok {
+		if v, ok := m["expires"].(float64); ok {
+			return v
+		}
 	}
-	if _, err := os.Stat(filepath.Join(basePath, "auth-profiles.json")); err == nil {
-		mainWp.HasAuthProfiles = true
+	return 0
+}
+
+// WorkspaceWins reports whether a workspace copy of a profile expiring at
+// theirs beats the primary copy expiring at primary. Ties go to the primary.
+// Syncing and drift checks both decide by this rule.
+func WorkspaceWins(primary, theirs float64) bool {
+	return theirs > primary
+}
+
+// ScanWorkspaces finds the workspaces of the OpenClaw installation rooted at
+// basePath: the home itself, the agentDir of every agent listed in its
+// openclaw.json and every directory under agents/ holding OpenClaw files,
+// whether that is agents/<name> or a subdirectory such as agents/<name>/agent.
+func ScanWorkspaces(basePath string) ([]WorkspaceInfo, error) {
+	var workspaces []WorkspaceInfo
+	seen := make(map[string]bool)
+	add := func(path, agentID string) {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			abs = path
+		}
+		if seen[abs] {
+			return
+		}
+		seen[abs] = true
+		if wp, ok := inspect(path, agentID); ok {
+			workspaces = append(workspaces, wp)
+		}
 	}
-	if mainWp.HasConfig || mainWp.HasAuthProfiles {
-		workspaces = append(workspaces, mainWp)
+
+	// Check main workspace
+	add(basePath, "")
+
+	// Agents relocated with agentDir in openclaw.json
+	for _, a := range configuredAgentDirs(basePath) {
+		add(a[1], a[0])
 	}
 
 	// Check agent workspaces
 	agentsPath := filepath.Join(basePath, "agents")
-	if _, err := os.Stat(agentsPath); err == nil {
-		entries, _ := os.ReadDir(agentsPath)
-		for _, entry := range entries {
-			if entry.IsDir() {
-				agentPath := filepath.Join(agentsPath, entry.Name(), "agent")
-				wp := WorkspaceInfo{Path: agentPath}
-				if _, err := os.Stat(filepath.Join(agentPath, "openclaw.json")); err == nil {
-					wp.HasConfig = true
-				}
-				if _, err := os.Stat(filepath.Join(agentPath, "auth-profiles.json")); err == nil {
-					wp.HasAuthProfiles = true
-				}
-				if wp.HasConfig || wp.HasAuthProfiles {
-					workspaces = append(workspaces, wp)
-				}
+	entries, _ := os.ReadDir(agentsPath)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		agentPath := filepath.Join(agentsPath, entry.Name())
+		add(agentPath, entry.Name())
+		subs, _ := os.ReadDir(agentPath)
+		for _, sub := range subs {
+			if sub.IsDir() {
+				add(filepath.Join(agentPath, sub.Name()), entry.Name())
 			}
 		}
 	}
 
 	return workspaces, nil
 }
+
+// configuredAgentDirs returns the id and directory of every agent in
+// agents.list of basePath/openclaw.json that sets agentDir. Relative
+// directories are under basePath.
+func configuredAgentDirs(basePath string) [][2]string {
+	var doc struct {
+		Agents struct {
+			List []struct {
+				ID       string `json:"id"`
+				AgentDir string `json:"agentDir"`
+			} `json:"list"`
+		} `json:"agents"`
+	}
+	if err := readJSON(filepath.Join(basePath, "openclaw.json"), &doc); err != nil {
+		return nil
+	}
+	var dirs [][2]string
+	for _, a := range doc.Agents.List {
+		dir := a.AgentDir
+		if dir == "" {
+			continue
+		}
+		if strings.HasPrefix(dir, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				dir = filepath.Join(home, dir[2:])
+			}
+		} else if !filepath.IsAbs(dir) {
+			dir = filepath.Join(basePath, dir)
+		}
+		dirs = append(dirs, [2]string{a.ID, dir})
+	}
+	return dirs
+}
+
+// inspect gathers file metadata and parses the credentials of a workspace.
+// It reports false for directories without any OpenClaw file.
+func inspect(path, agentID string) (WorkspaceInfo, bool) {
+	wp := WorkspaceInfo{Path: path, AgentID: agentID}
+	for _, name := range []string{"openclaw.json", "auth-profiles.json"} {
+		info, err := os.Stat(filepath.Join(path, name))
+		if err != nil {
+			continue
+		}
+		wp.Files = append(wp.Files, FileInfo{
+			Name:    name,
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+			Mode:    info.Mode().Perm().String(),
+		})
+		if name == "openclaw.json" {
+			wp.HasConfig = true
+		} else {
+			wp.HasAuthProfiles = true
+		}
+	}
+	if !wp.HasConfig && !wp.HasAuthProfiles {
+		return wp, false
+	}
+
+	if wp.HasConfig {
+		var doc map[string]interface{}
+		if err := readJSON(filepath.Join(path, "openclaw.json"), &doc); err != nil {
+			wp.ParseErrors = append(wp.ParseErrors, fmt.Sprintf("openclaw.json: %v", err))
+		}
+	}
+	if wp.HasAuthProfiles {
+		tokens, count, err := ReadAntigravityTokens(filepath.Join(path, "auth-profiles.json"))
+		if err != nil {
+			wp.ParseErrors = append(wp.ParseErrors, fmt.Sprintf("auth-profiles.json: %v", err))
+		}
+		wp.ProfileCount = count
+		wp.tokens = tokens
+		for email := range tokens {
+			wp.Accounts = append(wp.Accounts, email)
+		}
+		sort.Strings(wp.Accounts)
+	}
+	return wp, true
+}
+
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// ReadAntigravityTokens parses an auth-profiles.json and returns the token
+// of every google-antigravity account by email, plus the total number of
+// profiles in the file.
+func ReadAntigravityTokens(path string) (map[string]Token, int, error) {
+	var doc struct {
+		Profiles map[string]map[string]interface{} `json:"profiles"`
+	}
+	if err := readJSON(path, &doc); err != nil {
+		return nil, 0, err
+	}
+	tokens := make(map[string]Token)
+	for key, profile := range doc.Profiles {
+		if !strings.HasPrefix(key, antigravityPrefix) {
+			continue
+		}
+		refresh, _ := profile["refresh"].(string)
+		tokens[strings.TrimPrefix(key, antigravityPrefix)] = Token{Refresh: refresh, Expires: ProfileExpiry(profile)}
+	}
+	return tokens, len(doc.Profiles), nil
+}
+
+// CheckDrift compares every workspace with the primary auth file and fills
+// in Primary and Drift.
+func CheckDrift(workspaces []WorkspaceInfo, primaryAuthPath string) error {
+	primary, _, err := ReadAntigravityTokens(primaryAuthPath)
+	if err != nil {
+		return err
+	}
+	absPrimary, _ := filepath.Abs(primaryAuthPath)
+
+	for i := range workspaces {
+		wp := &workspaces[i]
+		absAuth, _ := filepath.Abs(filepath.Join(wp.Path, "auth-profiles.json"))
+		if absAuth == absPrimary {
+			wp.Primary = true
+			continue
+		}
+
+		drift := &Drift{}
+		for email, token := range primary {
+			theirs, ok := wp.tokens[email]
+			switch {
+			case !ok:
+				drift.MissingAccounts = append(drift.MissingAccounts, email)
+			case theirs.Refresh == token.Refresh:
+			case WorkspaceWins(token.Expires, theirs.Expires):
+				drift.NewerTokens = append(drift.NewerTokens, email)
+			default:
+				drift.DifferentTokens = append(drift.DifferentTokens, email)
+			}
+		}
+		for email := range wp.tokens {
+			if _, ok := primary[email]; !ok {
+				drift.ExtraAccounts = append(drift.ExtraAccounts, email)
+			}
+		}
+		sort.Strings(drift.MissingAccounts)
+		sort.Strings(drift.ExtraAccounts)
+		sort.Strings(drift.DifferentTokens)
+		sort.Strings(drift.NewerTokens)
+		wp.Drift = drift
+	}
+	return nil
+}
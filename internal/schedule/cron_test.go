@@ -0,0 +1,90 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMatches(t *testing.T) {
+	// 2026-01-05 is a Monday.
+	at := func(day, hour, min int) time.Time { return time.Date(2026, 1, day, hour, min, 0, 0, time.UTC) }
+	tests := []struct {
+		expr  string
+		time  time.Time
+		match bool
+	}{
+		{"* * * * *", at(5, 0, 0), true},
+
+		// Ranges
+		{"* 9-17 * * *", at(5, 9, 0), true},
+		{"* 9-17 * * *", at(5, 17, 59), true},
+		{"* 9-17 * * *", at(5, 18, 0), false},
+		{"* 9-17 * * *", at(5, 8, 59), false},
+		{"* * * * 1-5", at(9, 12, 0), true},   // Friday
+		{"* * * * 1-5", at(10, 12, 0), false}, // Saturday
+
+		// Steps
+		{"*/15 * * * *", at(5, 3, 45), true},
+		{"*/15 * * * *", at(5, 3, 50), false},
+		{"5/20 * * * *", at(5, 3, 45), true},
+		{"5/20 * * * *", at(5, 3, 40), false},
+		{"10-30/10 * * * *", at(5, 3, 20), true},
+		{"10-30/10 * * * *", at(5, 3, 40), false},
+
+		// Lists, also of ranges
+		{"0,30 * * * *", at(5, 3, 30), true},
+		{"0,30 * * * *", at(5, 3, 15), false},
+		{"* 0-6,22-23 * * *", at(5, 23, 0), true},
+		{"* 0-6,22-23 * * *", at(5, 12, 0), false},
+		{"* * * 1,7 *", at(5, 12, 0), true},
+
+		// Sunday is 0 or 7.
+		{"* * * * 0", at(4, 12, 0), true},
+		{"* * * * 7", at(4, 12, 0), true},
+		{"* * * * 7", at(5, 12, 0), false},
+
+		// With one day field restricted, it alone decides...
+		{"* * 5 * *", at(5, 12, 0), true},
+		{"* * 6 * *", at(5, 12, 0), false},
+		{"* * * * 2", at(5, 12, 0), false},
+		// ...with both restricted, either one matching is enough.
+		{"* * 6 * 1", at(5, 12, 0), true},  // Monday, not the 6th
+		{"* * 6 * 1", at(6, 12, 0), true},  // the 6th, a Tuesday
+		{"* * 6 * 1", at(7, 12, 0), false}, // neither
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := s.Matches(tt.time); got != tt.match {
+			t.Errorf("%q matches %s = %v, want %v", tt.expr, tt.time.Format("Mon 01-02 15:04"), got, tt.match)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"* 17-9 * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"a * * * *",
+		"1-b * * * *",
+		"-1 * * * *",
+		"1,,2 * * * *",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) accepted", expr)
+		}
+	}
+}
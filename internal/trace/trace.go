@@ -0,0 +1,229 @@
+// Package trace reads and writes quota time series: the quota left per
+// account and model over time, recorded by the rotator or made up, for the
+// rotation simulator.
+package trace
+
+import (
+	"antigravity-rotator-v2/internal/fsutil"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const fileName = "antigravity-rotator-trace.jsonl"
+
+// maxSize is the file size after which Append drops the older half.
+const maxSize = 16 << 20
+
+// Sample is the quota of one account's model at a point in time. A sample
+// holds until the next sample of the same account and model.
+type Sample struct {
+	Time    time.Time `json:"time"`
+	Account string    `json:"account"`
+	Model   string    `json:"model"`
+	Percent int       `json:"percent"`
+	// ResetTime is when the quota resets, zero if unknown.
+	ResetTime time.Time `json:"resetTime"`
+}
+
+// Path returns the trace file of an OpenClaw home.
+func Path(home string) string {
+	return filepath.Join(home, fileName)
+}
+
+// Append adds samples to a trace file.
+func Append(path string, samples []Sample) error {
+	var buf bytes.Buffer
+	for _, s := range samples {
+		line, err := json.Marshal(s)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return fsutil.WithLock(path, func() error {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(buf.Bytes())
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+		if fi, err := os.Stat(path); err == nil && fi.Size() > maxSize {
+			return trim(path)
+		}
+		return nil
+	})
+}
+
+// trim keeps the newer half of the trace. Callers hold the lock.
+func trim(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	samples, err := parseLines(path, data)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, s := range samples[len(samples)/2:] {
+		line, _ := json.Marshal(s)
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return fsutil.WriteFileAtomic(path, buf.Bytes(), 0600)
+}
+
+// Parse reads a trace from a JSON array, JSON lines, or CSV with the header
+// time,account,model,percent and an optional resetTime column. Times are
+// RFC 3339. The samples are returned in time order.
+func Parse(name string, data []byte) ([]Sample, error) {
+	trimmed := bytes.TrimSpace(data)
+	var samples []Sample
+	var err error
+	switch {
+	case strings.ToLower(path.Ext(name)) == ".csv":
+		samples, err = parseCSV(name, data)
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		if err = json.Unmarshal(trimmed, &samples); err != nil {
+			err = fmt.Errorf("%s: 无法解析 JSON: %v", name, err)
+		}
+	default:
+		samples, err = parseLines(name, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, s := range samples {
+		if s.Account == "" || s.Model == "" || s.Time.IsZero() {
+			return nil, fmt.Errorf("%s: 第 %d 条样本缺少 time、account 或 model", name, i+1)
+		}
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+	return samples, nil
+}
+
+func parseLines(name string, data []byte) ([]Sample, error) {
+	samples := []Sample{}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var s Sample
+		if err := json.Unmarshal(line, &s); err != nil {
+			return nil, fmt.Errorf("%s:%d: 无法解析样本: %v", name, n, err)
+		}
+		samples = append(samples, s)
+	}
+	return samples, sc.Err()
+}
+
+func parseCSV(name string, data []byte) ([]Sample, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: 无法读取 CSV 表头: %v", name, err)
+	}
+	col := make(map[string]int)
+	for i, h := range header {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, h := range []string{"time", "account", "model", "percent"} {
+		if _, ok := col[h]; !ok {
+			return nil, fmt.Errorf("%s: CSV 缺少 %s 列", name, h)
+		}
+	}
+	field := func(rec []string, h string) string {
+		if i, ok := col[h]; ok && i < len(rec) {
+			return strings.TrimSpace(rec[i])
+		}
+		return ""
+	}
+
+	samples := []Sample{}
+	for n := 2; ; n++ {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return samples, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", name, n, err)
+		}
+		s := Sample{Account: field(rec, "account"), Model: field(rec, "model")}
+		if s.Time, err = time.Parse(time.RFC3339, field(rec, "time")); err != nil {
+			return nil, fmt.Errorf("%s:%d: 无效的时间: %v", name, n, err)
+		}
+		if s.Percent, err = strconv.Atoi(field(rec, "percent")); err != nil {
+			return nil, fmt.Errorf("%s:%d: 无效的配额: %v", name, n, err)
+		}
+		if v := field(rec, "resettime"); v != "" {
+			if s.ResetTime, err = time.Parse(time.RFC3339, v); err != nil {
+				return nil, fmt.Errorf("%s:%d: 无效的重置时间: %v", name, n, err)
+			}
+		}
+		samples = append(samples, s)
+	}
+}
+
+// Synthetic describes a made-up trace: every account starts full and is
+// refilled every ResetEvery. It has no consumption of its own; the
+// simulator's load drains whichever account is active.
+type Synthetic struct {
+	Accounts   []string
+	Models     []string
+	Start      time.Time
+	Duration   time.Duration
+	ResetEvery time.Duration
+	// Stagger spreads the accounts' resets evenly over ResetEvery instead
+	// of lining them all up.
+	Stagger bool
+}
+
+// Generate returns the samples of a synthetic trace.
+func (s Synthetic) Generate() []Sample {
+	var samples []Sample
+	end := s.Start.Add(s.Duration)
+	for i, account := range s.Accounts {
+		offset := time.Duration(0)
+		if s.Stagger && len(s.Accounts) > 0 {
+			offset = s.ResetEvery * time.Duration(i) / time.Duration(len(s.Accounts))
+		}
+		at := s.Start
+		next := s.Start.Add(offset)
+		if offset == 0 {
+			next = s.Start.Add(s.ResetEvery)
+		}
+		for !at.After(end) {
+			for _, m := range s.Models {
+				samples = append(samples, Sample{Time: at, Account: account, Model: m, Percent: 100, ResetTime: next})
+			}
+			if s.ResetEvery <= 0 {
+				break
+			}
+			at, next = next, next.Add(s.ResetEvery)
+		}
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+	return samples
+}
@@ -0,0 +1,91 @@
+package trace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseFormats(t *testing.T) {
+	csv := "time,account,model,percent,resetTime\n" +
+		"2026-01-05T09:00:00Z,b@example.com,m,40,\n" +
+		"2026-01-05T08:00:00Z,a@example.com,m,90,2026-01-05T13:00:00Z\n"
+	jsonl := `{"time":"2026-01-05T09:00:00Z","account":"b@example.com","model":"m","percent":40}
+{"time":"2026-01-05T08:00:00Z","account":"a@example.com","model":"m","percent":90,"resetTime":"2026-01-05T13:00:00Z"}
+`
+	array := `[` + `{"time":"2026-01-05T09:00:00Z","account":"b@example.com","model":"m","percent":40},` +
+		`{"time":"2026-01-05T08:00:00Z","account":"a@example.com","model":"m","percent":90,"resetTime":"2026-01-05T13:00:00Z"}]`
+
+	for name, data := range map[string]string{"trace.csv": csv, "trace.jsonl": jsonl, "trace.json": array} {
+		samples, err := Parse(name, []byte(data))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(samples) != 2 || samples[0].Account != "a@example.com" || samples[0].Percent != 90 ||
+			samples[0].ResetTime.Hour() != 13 || !samples[1].ResetTime.IsZero() {
+			t.Errorf("%s: samples = %+v", name, samples)
+		}
+	}
+
+	if _, err := Parse("bad.csv", []byte("time,account\n")); err == nil {
+		t.Errorf("CSV without model and percent columns accepted")
+	}
+	if _, err := Parse("bad.jsonl", []byte(`{"account":"a","model":"m","percent":1}`)); err == nil {
+		t.Errorf("sample without time accepted")
+	}
+}
+
+func TestAppendRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+	at := time.Date(2026, 1, 5, 8, 0, 0, 0, time.UTC)
+	if err := Append(path, []Sample{{Time: at, Account: "a", Model: "m", Percent: 50}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Append(path, []Sample{{Time: at.Add(time.Minute), Account: "a", Model: "m", Percent: 49}}); err != nil {
+		t.Fatal(err)
+	}
+	samples, err := parseLines(path, mustRead(t, path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 2 || samples[1].Percent != 49 {
+		t.Errorf("samples = %+v", samples)
+	}
+}
+
+func TestSyntheticStagger(t *testing.T) {
+	start := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	s := Synthetic{Accounts: []string{"a", "b"}, Models: []string{"m"}, Start: start, Duration: 10 * time.Hour, ResetEvery: 4 * time.Hour}
+
+	resets := func(samples []Sample, account string) []int {
+		var hours []int
+		for _, sm := range samples {
+			if sm.Account == account {
+				hours = append(hours, int(sm.Time.Sub(start).Hours()))
+			}
+		}
+		return hours
+	}
+	aligned := s.Generate()
+	if got := resets(aligned, "b"); len(got) != 3 || got[1] != 4 || got[2] != 8 {
+		t.Errorf("aligned resets of b at %v, want 0 4 8", got)
+	}
+	s.Stagger = true
+	staggered := s.Generate()
+	if got := resets(staggered, "a"); len(got) != 3 || got[1] != 4 {
+		t.Errorf("staggered resets of a at %v, want 0 4 8", got)
+	}
+	if got := resets(staggered, "b"); len(got) != 4 || got[1] != 2 || got[2] != 6 {
+		t.Errorf("staggered resets of b at %v, want 0 2 6 10", got)
+	}
+}
+
+func mustRead(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -0,0 +1,164 @@
+package usage
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Report dimensions.
+const (
+	ByAccount = "account"
+	ByAgent   = "agent"
+	ByModel   = "model"
+)
+
+// Report periods.
+const (
+	Day  = "day"
+	Week = "week" // starting Monday
+)
+
+// Unknown is the key of events whose account, agent or model is not known.
+const Unknown = "(unknown)"
+
+// Row is the usage of one key in one period.
+type Row struct {
+	Period       time.Time `json:"period"` // start of the day or week
+	Key          string    `json:"key"`
+	Requests     int       `json:"requests"`
+	Errors       int       `json:"errors"`
+	InputTokens  int64     `json:"inputTokens"`
+	OutputTokens int64     `json:"outputTokens"`
+	CacheTokens  int64     `json:"cacheTokens"`
+	TotalTokens  int64     `json:"totalTokens"`
+}
+
+func (r *Row) add(ev Event) {
+	r.Requests++
+	if ev.Error {
+		r.Errors++
+	}
+	r.InputTokens += ev.InputTokens
+	r.OutputTokens += ev.OutputTokens
+	r.CacheTokens += ev.CacheTokens
+	r.TotalTokens += ev.TotalTokens
+}
+
+// Report is usage grouped by one dimension and period.
+type Report struct {
+	By     string    `json:"by"`
+	Period string    `json:"period"`
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	// Rows are sorted by period, then by total tokens, descending.
+	Rows []Row `json:"rows"`
+	// Totals has one row per key over the whole range, Period unset.
+	Totals []Row `json:"totals"`
+	Total  Row   `json:"total"`
+}
+
+// Build aggregates the events in [from, to) by dimension and period, with
+// periods starting at midnight in loc.
+func Build(events []Event, by, period string, from, to time.Time, loc *time.Location) (Report, error) {
+	if by != ByAccount && by != ByAgent && by != ByModel {
+		return Report{}, fmt.Errorf("未知的统计维度 %q (account、agent 或 model)", by)
+	}
+	if period != Day && period != Week {
+		return Report{}, fmt.Errorf("未知的统计周期 %q (day 或 week)", period)
+	}
+	r := Report{By: by, Period: period, From: from, To: to, Rows: []Row{}, Totals: []Row{}}
+
+	type cell struct {
+		period time.Time
+		key    string
+	}
+	rows := make(map[cell]*Row)
+	totals := make(map[string]*Row)
+	for _, ev := range events {
+		if ev.Time.Before(from) || !ev.Time.Before(to) {
+			continue
+		}
+		key := keyOf(ev, by)
+		c := cell{PeriodStart(ev.Time.In(loc), period), key}
+		if rows[c] == nil {
+			rows[c] = &Row{Period: c.period, Key: key}
+		}
+		rows[c].add(ev)
+		if totals[key] == nil {
+			totals[key] = &Row{Key: key}
+		}
+		totals[key].add(ev)
+		r.Total.add(ev)
+	}
+
+	for _, row := range rows {
+		r.Rows = append(r.Rows, *row)
+	}
+	sort.Slice(r.Rows, func(i, j int) bool {
+		a, b := r.Rows[i], r.Rows[j]
+		if !a.Period.Equal(b.Period) {
+			return a.Period.Before(b.Period)
+		}
+		if a.TotalTokens != b.TotalTokens {
+			return a.TotalTokens > b.TotalTokens
+		}
+		return a.Key < b.Key
+	})
+	for _, row := range totals {
+		r.Totals = append(r.Totals, *row)
+	}
+	sort.Slice(r.Totals, func(i, j int) bool {
+		if r.Totals[i].TotalTokens != r.Totals[j].TotalTokens {
+			return r.Totals[i].TotalTokens > r.Totals[j].TotalTokens
+		}
+		return r.Totals[i].Key < r.Totals[j].Key
+	})
+	return r, nil
+}
+
+func keyOf(ev Event, by string) string {
+	var key string
+	switch by {
+	case ByAccount:
+		key = ev.Account
+	case ByAgent:
+		key = ev.Agent
+	case ByModel:
+		key = ev.Model
+	}
+	if key == "" {
+		return Unknown
+	}
+	return key
+}
+
+// PeriodStart returns the start of the day or week containing t, in t's
+// location.
+func PeriodStart(t time.Time, period string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if period == Week {
+		offset := (int(day.Weekday()) + 6) % 7 // days since Monday
+		day = day.AddDate(0, 0, -offset)
+	}
+	return day
+}
+
+// WriteCSV writes the report's rows as CSV with a header.
+func WriteCSV(w io.Writer, r Report) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"period", r.By, "requests", "errors", "input_tokens", "output_tokens", "cache_tokens", "total_tokens"})
+	for _, row := range r.Rows {
+		cw.Write([]string{
+			row.Period.Format("2006-01-02"), row.Key,
+			strconv.Itoa(row.Requests), strconv.Itoa(row.Errors),
+			strconv.FormatInt(row.InputTokens, 10), strconv.FormatInt(row.OutputTokens, 10),
+			strconv.FormatInt(row.CacheTokens, 10), strconv.FormatInt(row.TotalTokens, 10),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
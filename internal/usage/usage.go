@@ -0,0 +1,320 @@
+// Package usage turns session transcripts and gateway logs into usage
+// events, keeps them in a store, and aggregates them per account, agent or
+// model over days or weeks.
+package usage
+
+import (
+	"antigravity-rotator-v2/internal/fsutil"
+	"antigravity-rotator-v2/internal/history"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const fileName = "antigravity-rotator-usage.jsonl"
+
+// maxSize is the store size after which Merge drops the older half.
+const maxSize = 32 << 20
+
+const provider = "google-antigravity"
+
+// Event is one model request and its token usage.
+type Event struct {
+	// ID identifies the event across repeated ingests of the same log.
+	ID    string    `json:"id"`
+	Time  time.Time `json:"time"`
+	Agent string    `json:"agent,omitempty"`
+	// Account is set when the log names the auth profile used; otherwise
+	// it is attributed from the rotation history when reporting.
+	Account      string `json:"account,omitempty"`
+	Model        string `json:"model,omitempty"`
+	InputTokens  int64  `json:"inputTokens"`
+	OutputTokens int64  `json:"outputTokens"`
+	CacheTokens  int64  `json:"cacheTokens,omitempty"`
+	TotalTokens  int64  `json:"totalTokens"`
+	// Error marks requests that failed, e.g. on quota errors.
+	Error bool `json:"error,omitempty"`
+}
+
+// ParseResult is what Parse found in one log.
+type ParseResult struct {
+	Events []Event
+	// Foreign counts usage of other providers, which no rotated account
+	// paid for.
+	Foreign int
+}
+
+// Path returns the usage store of an OpenClaw home.
+func Path(home string) string {
+	return filepath.Join(home, fileName)
+}
+
+// Parse reads usage events from JSON lines: OpenClaw session transcripts
+// (assistant messages with usage) or gateway logs and usage exports with
+// one request per line. Lines without token usage are ignored. agent is
+// used for events that do not name their agent.
+func Parse(data []byte, agent string) ParseResult {
+	var res ParseResult
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64*1024), 16<<20)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+		var obj map[string]interface{}
+		if json.Unmarshal(line, &obj) != nil {
+			continue
+		}
+		ev, foreign, ok := parseEvent(obj, agent)
+		if foreign {
+			res.Foreign++
+		}
+		if !ok {
+			continue
+		}
+		sum := sha256.Sum256(line)
+		ev.ID = hex.EncodeToString(sum[:12])
+		res.Events = append(res.Events, ev)
+	}
+	return res
+}
+
+// parseEvent extracts one event from a log line, accepting the field names
+// of OpenClaw transcripts, OpenAI- and Anthropic-style usage and Gemini
+// usageMetadata.
+func parseEvent(obj map[string]interface{}, agent string) (Event, bool, bool) {
+	msg, ok := obj["message"].(map[string]interface{})
+	if !ok {
+		msg = obj
+	}
+	if role, ok := msg["role"].(string); ok && role != "assistant" {
+		return Event{}, false, false
+	}
+	u := firstMap(msg, obj, "usage", "usageMetadata")
+	if u == nil {
+		return Event{}, false, false
+	}
+
+	model := firstString(msg, obj, "model", "modelId")
+	prov := firstString(msg, obj, "provider")
+	if i := strings.Index(model, "/"); i >= 0 && prov == "" {
+		prov = model[:i]
+	}
+	model = strings.TrimPrefix(model, provider+"/")
+	if prov != "" && prov != provider {
+		return Event{}, true, false
+	}
+
+	ev := Event{
+		Time:         firstTime(msg, obj, "timestamp", "time", "ts"),
+		Agent:        firstString(msg, obj, "agentId", "agent"),
+		InputTokens:  number(u, "input", "inputTokens", "input_tokens", "prompt_tokens", "promptTokenCount"),
+		OutputTokens: number(u, "output", "outputTokens", "output_tokens", "completion_tokens", "candidatesTokenCount"),
+		CacheTokens: number(u, "cacheRead", "cache_read_input_tokens", "cachedContentTokenCount") +
+			number(u, "cacheWrite", "cache_creation_input_tokens"),
+		TotalTokens: number(u, "totalTokens", "total_tokens", "totalTokenCount"),
+	}
+	if ev.Time.IsZero() {
+		return Event{}, false, false
+	}
+	if model != "" {
+		ev.Model = provider + "/" + model
+	}
+	if ev.Agent == "" {
+		ev.Agent = agent
+	}
+	if ev.TotalTokens == 0 {
+		ev.TotalTokens = ev.InputTokens + ev.OutputTokens + ev.CacheTokens
+	}
+	account := firstString(msg, obj, "profileId", "authProfile", "profile", "account", "email")
+	ev.Account = strings.TrimPrefix(account, provider+":")
+	stop, _ := msg["stopReason"].(string)
+	ev.Error = stop == "error" || obj["error"] != nil || msg["errorMessage"] != nil
+	return ev, false, true
+}
+
+func firstMap(a, b map[string]interface{}, keys ...string) map[string]interface{} {
+	for _, m := range []map[string]interface{}{a, b} {
+		for _, k := range keys {
+			if v, ok := m[k].(map[string]interface{}); ok {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+func firstString(a, b map[string]interface{}, keys ...string) string {
+	for _, m := range []map[string]interface{}{a, b} {
+		for _, k := range keys {
+			if v, ok := m[k].(string); ok && v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// firstTime accepts RFC 3339 strings and Unix times in seconds or
+// milliseconds.
+func firstTime(a, b map[string]interface{}, keys ...string) time.Time {
+	for _, m := range []map[string]interface{}{a, b} {
+		for _, k := range keys {
+			switch v := m[k].(type) {
+			case string:
+				if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+					return t
+				}
+			case float64:
+				if v > 1e12 {
+					return time.UnixMilli(int64(v))
+				}
+				if v > 0 {
+					return time.Unix(int64(v), 0)
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
+
+func number(m map[string]interface{}, keys ...string) int64 {
+	for _, k := range keys {
+		if v, ok := m[k].(float64); ok {
+			return int64(v)
+		}
+	}
+	return 0
+}
+
+// Read returns the stored events, oldest first. A missing store is empty.
+func Read(path string) ([]Event, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return []Event{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	events := []Event{}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for sc.Scan() {
+		var ev Event
+		if json.Unmarshal(sc.Bytes(), &ev) == nil {
+			events = append(events, ev)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	return events, sc.Err()
+}
+
+// Merge adds the events the store does not have yet and returns how many
+// were new.
+func Merge(path string, events []Event) (int, error) {
+	added := 0
+	err := fsutil.WithLock(path, func() error {
+		stored, err := Read(path)
+		if err != nil {
+			return err
+		}
+		seen := make(map[string]bool, len(stored))
+		for _, ev := range stored {
+			seen[ev.ID] = true
+		}
+		var buf bytes.Buffer
+		for _, ev := range events {
+			if seen[ev.ID] {
+				continue
+			}
+			seen[ev.ID] = true
+			line, _ := json.Marshal(ev)
+			buf.Write(line)
+			buf.WriteByte('\n')
+			added++
+		}
+		if added == 0 {
+			return nil
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(buf.Bytes())
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+		if fi, err := os.Stat(path); err == nil && fi.Size() > maxSize {
+			return trim(path)
+		}
+		return nil
+	})
+	return added, err
+}
+
+// trim keeps the newer half of the store. Callers hold the lock.
+func trim(path string) error {
+	events, err := Read(path)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, ev := range events[len(events)/2:] {
+		line, _ := json.Marshal(ev)
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return fsutil.WriteFileAtomic(path, buf.Bytes(), 0600)
+}
+
+// Attribute fills in the account and model of events that do not name
+// them with what the rotation history says was active at the time. Events
+// before the first recorded switch stay unattributed.
+func Attribute(events []Event, entries []history.Entry) {
+	var accounts, models []history.Entry
+	for _, e := range entries {
+		if e.Account != "" {
+			accounts = append(accounts, e)
+		}
+		if e.Model != "" {
+			models = append(models, e)
+		}
+	}
+	byTime := func(list []history.Entry) {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Time.Before(list[j].Time) })
+	}
+	byTime(accounts)
+	byTime(models)
+	activeAt := func(list []history.Entry, t time.Time) *history.Entry {
+		i := sort.Search(len(list), func(i int) bool { return list[i].Time.After(t) })
+		if i == 0 {
+			return nil
+		}
+		return &list[i-1]
+	}
+
+	for i := range events {
+		ev := &events[i]
+		if ev.Account == "" {
+			if e := activeAt(accounts, ev.Time); e != nil {
+				ev.Account = e.Account
+			}
+		}
+		if ev.Model == "" {
+			if e := activeAt(models, ev.Time); e != nil {
+				ev.Model = e.Model
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package usage
+
+import (
+	"antigravity-rotator-v2/internal/history"
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const transcript = `{"type":"session","id":"s1","timestamp":"2026-01-05T08:00:00Z"}
+{"type":"message","message":{"role":"user","content":"hi","timestamp":1767600000000}}
+{"type":"message","message":{"role":"assistant","provider":"google-antigravity","model":"gemini-3-pro-high","usage":{"input":100,"output":20,"cacheRead":5,"cacheWrite":0,"totalTokens":125},"stopReason":"stop","timestamp":1767600060000}}
+{"type":"message","message":{"role":"assistant","provider":"openai","model":"gpt-5","usage":{"input":1,"output":1},"timestamp":1767600120000}}
+{"type":"message","message":{"role":"assistant","provider":"google-antigravity","model":"gemini-3-flash","usage":{"input":10,"output":0},"stopReason":"error","errorMessage":"429","timestamp":1767600180000}}
+`
+
+const gatewayLog = `2026-01-05T08:05:00Z info gateway started
+{"time":"2026-01-05T08:06:00Z","agentId":"ops","model":"google-antigravity/gemini-3-flash","profileId":"google-antigravity:b@example.com","usage":{"prompt_tokens":7,"completion_tokens":3}}
+{"ts":1767600420,"usageMetadata":{"promptTokenCount":4,"candidatesTokenCount":6,"totalTokenCount":10}}
+`
+
+func TestParseFormats(t *testing.T) {
+	res := Parse([]byte(transcript), "main")
+	if res.Foreign != 1 || len(res.Events) != 2 {
+		t.Fatalf("events %+v, foreign %d", res.Events, res.Foreign)
+	}
+	ev := res.Events[0]
+	if ev.Agent != "main" || ev.Model != "google-antigravity/gemini-3-pro-high" || ev.Account != "" ||
+		ev.InputTokens != 100 || ev.OutputTokens != 20 || ev.CacheTokens != 5 || ev.TotalTokens != 125 || ev.Error {
+		t.Errorf("assistant message = %+v", ev)
+	}
+	if !res.Events[1].Error || res.Events[1].TotalTokens != 10 {
+		t.Errorf("failed request = %+v", res.Events[1])
+	}
+
+	res = Parse([]byte(gatewayLog), "")
+	if len(res.Events) != 2 {
+		t.Fatalf("gateway events = %+v", res.Events)
+	}
+	if ev := res.Events[0]; ev.Agent != "ops" || ev.Account != "b@example.com" || ev.Model != "google-antigravity/gemini-3-flash" || ev.TotalTokens != 10 {
+		t.Errorf("gateway request = %+v", ev)
+	}
+	if ev := res.Events[1]; ev.Time.Unix() != 1767600420 || ev.Model != "" || ev.TotalTokens != 10 {
+		t.Errorf("usageMetadata line = %+v", ev)
+	}
+	if res.Events[0].ID == res.Events[1].ID || res.Events[0].ID != Parse([]byte(gatewayLog), "").Events[0].ID {
+		t.Errorf("IDs are not stable per line")
+	}
+}
+
+func TestMergeSkipsKnownEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), fileName)
+	events := Parse([]byte(transcript), "main").Events
+	if added, err := Merge(path, events); err != nil || added != 2 {
+		t.Fatalf("first merge added %d, %v", added, err)
+	}
+	if added, err := Merge(path, events); err != nil || added != 0 {
+		t.Fatalf("second merge added %d, %v", added, err)
+	}
+	stored, err := Read(path)
+	if err != nil || len(stored) != 2 {
+		t.Fatalf("stored %+v, %v", stored, err)
+	}
+}
+
+func TestAttribute(t *testing.T) {
+	at := func(min int) time.Time { return time.Date(2026, 1, 5, 8, min, 0, 0, time.UTC) }
+	entries := []history.Entry{
+		{Time: at(10), Account: "b"},
+		{Time: at(0), Account: "a", Model: "m1"},
+		{Time: at(20), Model: "m2"},
+	}
+	events := []Event{
+		{Time: at(0).Add(-time.Minute)},
+		{Time: at(5)},
+		{Time: at(15)},
+		{Time: at(25), Account: "c"},
+	}
+	Attribute(events, entries)
+	want := [][2]string{{"", ""}, {"a", "m1"}, {"b", "m1"}, {"c", "m2"}}
+	for i, w := range want {
+		if events[i].Account != w[0] || events[i].Model != w[1] {
+			t.Errorf("event %d attributed to %s/%s, want %s/%s", i, events[i].Account, events[i].Model, w[0], w[1])
+		}
+	}
+}
+
+func TestBuildWeeks(t *testing.T) {
+	// 2026-01-04 is a Sunday, 2026-01-05 a Monday.
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 12, 0, 0, 0, time.UTC) }
+	events := []Event{
+		{Time: day(4), Agent: "main", TotalTokens: 10},
+		{Time: day(5), Agent: "main", TotalTokens: 20},
+		{Time: day(6), Agent: "ops", TotalTokens: 50, Error: true},
+		{Time: day(7), TotalTokens: 1},
+		{Time: day(20), Agent: "main", TotalTokens: 99}, // outside the range
+	}
+	r, err := Build(events, ByAgent, Week, day(1), day(12), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, row := range r.Rows {
+		got = append(got, row.Period.Format("01-02")+" "+row.Key)
+	}
+	if strings.Join(got, ", ") != "12-29 main, 01-05 ops, 01-05 main, 01-05 (unknown)" {
+		t.Errorf("rows = %s", strings.Join(got, ", "))
+	}
+	if r.Total.Requests != 4 || r.Total.Errors != 1 || r.Total.TotalTokens != 81 {
+		t.Errorf("total = %+v", r.Total)
+	}
+	if len(r.Totals) != 3 || r.Totals[0].Key != "ops" || r.Totals[1].TotalTokens != 30 {
+		t.Errorf("totals = %+v", r.Totals)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 || lines[0] != "period,agent,requests,errors,input_tokens,output_tokens,cache_tokens,total_tokens" ||
+		lines[1] != "2025-12-29,main,1,0,0,0,0,10" {
+		t.Errorf("CSV = %q", buf.String())
+	}
+
+	if _, err := Build(events, "team", Day, day(1), day(12), time.UTC); err == nil {
+		t.Errorf("unknown dimension accepted")
+	}
+}
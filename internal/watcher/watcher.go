@@ -0,0 +1,175 @@
+// Package watcher keeps track of file changes inside an OpenClaw home: new
+// or removed agent workspaces, and external edits of credential and config
+// files. Bursts of events are debounced into a single Change.
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Change is a debounced batch of file system events.
+type Change struct {
+	Workspaces  bool     `json:"workspaces"`  // agent directories added or removed
+	Credentials []string `json:"credentials"` // auth-profiles.json files written
+	Configs     []string `json:"configs"`     // openclaw.json / rotator config written
+}
+
+func (c *Change) empty() bool {
+	return !c.Workspaces && len(c.Credentials) == 0 && len(c.Configs) == 0
+}
+
+type Watcher struct {
+	home     string
+	debounce time.Duration
+	onChange func(Change)
+
+	fsw     *fsnotify.Watcher
+	mu      sync.Mutex
+	pending Change
+	timer   *time.Timer
+	done    chan struct{}
+}
+
+// New watches home, its agent directories and the directories inside them.
+// onChange is called from the watcher goroutine once events have been quiet
+// for debounce.
+func New(home string, debounce time.Duration, onChange func(Change)) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		home:     home,
+		debounce: debounce,
+		onChange: onChange,
+		fsw:      fsw,
+		done:     make(chan struct{}),
+	}
+
+	fsw.Add(home)
+	w.watchAgents()
+	go w.loop()
+	return w, nil
+}
+
+// watchAgents adds watches for the agents directory and every workspace in
+// it. fsnotify is not recursive, so this is repeated whenever a directory
+// appears.
+func (w *Watcher) watchAgents() {
+	agentsPath := filepath.Join(w.home, "agents")
+	if err := w.fsw.Add(agentsPath); err != nil {
+		return
+	}
+	entries, _ := os.ReadDir(agentsPath)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		agentPath := filepath.Join(agentsPath, entry.Name())
+		w.fsw.Add(agentPath)
+		subs, _ := os.ReadDir(agentPath)
+		for _, sub := range subs {
+			if sub.IsDir() {
+				w.fsw.Add(filepath.Join(agentPath, sub.Name()))
+			}
+		}
+	}
+}
+
+func (w *Watcher) loop() {
+	for {
+		select {
+		case <-w.done:
+			return
+		case ev, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			w.handle(ev)
+		case _, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+func ignored(name string) bool {
+	base := filepath.Base(name)
+	return strings.HasSuffix(base, ".lock") ||
+		strings.Contains(base, ".tmp-") ||
+		strings.Contains(name, ".rotator-backups")
+}
+
+func (w *Watcher) handle(ev fsnotify.Event) {
+	if ignored(ev.Name) {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	switch base := filepath.Base(ev.Name); {
+	case base == "auth-profiles.json":
+		if !ev.Has(fsnotify.Chmod) {
+			w.pending.Credentials = appendUnique(w.pending.Credentials, ev.Name)
+		}
+	case base == "openclaw.json" || base == "antigravity-rotator-v2.json":
+		if !ev.Has(fsnotify.Chmod) {
+			w.pending.Configs = appendUnique(w.pending.Configs, ev.Name)
+		}
+	case ev.Has(fsnotify.Create) || ev.Has(fsnotify.Remove) || ev.Has(fsnotify.Rename):
+		// Directory layout under agents/ changed
+		if strings.HasPrefix(ev.Name, filepath.Join(w.home, "agents")) || base == "agents" {
+			w.pending.Workspaces = true
+			if ev.Has(fsnotify.Create) {
+				w.watchAgents()
+			}
+		}
+	}
+
+	if w.pending.empty() {
+		return
+	}
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.timer = time.AfterFunc(w.debounce, w.flush)
+}
+
+func (w *Watcher) flush() {
+	w.mu.Lock()
+	change := w.pending
+	w.pending = Change{}
+	w.mu.Unlock()
+
+	if !change.empty() {
+		w.onChange(change)
+	}
+}
+
+// Close stops watching. Pending changes are discarded.
+func (w *Watcher) Close() error {
+	w.mu.Lock()
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.mu.Unlock()
+	close(w.done)
+	return w.fsw.Close()
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
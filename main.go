@@ -1,7 +1,11 @@
 package main
 
 import (
+	"antigravity-rotator-v2/internal/cli"
+	"antigravity-rotator-v2/internal/config"
 	"embed"
+	"flag"
+	"os"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -12,6 +16,16 @@ import (
 var assets embed.FS
 
 func main() {
+	flags := flag.NewFlagSet("antigravity-rotator-v2", flag.ContinueOnError)
+	openclawHome := flags.String("openclaw-home", "", "OpenClaw home directory (default $OPENCLAW_STATE_DIR or ~/.openclaw)")
+	flags.Parse(os.Args[1:])
+	if *openclawHome != "" {
+		config.SetOpenClawHome(*openclawHome)
+	}
+	if flags.NArg() > 0 && cli.IsCommand(flags.Arg(0)) {
+		os.Exit(cli.Run(flags.Args(), os.Stdout, os.Stderr))
+	}
+
 	// Create an instance of the app structure
 	app := NewApp()
 
@@ -25,6 +39,7 @@ func main() {
 		},
 		BackgroundColour: &options.RGBA{R: 27, G: 38, B: 54, A: 1},
 		OnStartup:        app.startup,
+		OnShutdown:       app.shutdown,
 		Bind: []interface{}{
 			app,
 		},